@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/locale"
+)
+
+// ansiReset ends a colored span of terminal output.
+const ansiReset = "\033[0m"
+
+// ansiColors maps the AQI category colors to ANSI terminal escape sequences.
+var ansiColors = map[string]string{
+	"green":  "\033[32m",
+	"yellow": "\033[33m",
+	"orange": "\033[38;5;208m",
+	"red":    "\033[31m",
+	"purple": "\033[35m",
+	"maroon": "\033[38;5;88m",
+}
+
+// airQualityRecord is the air quality of a batch record, with the category of each index.
+type airQualityRecord struct {
+	api.AirQuality
+	// USAQICategory is the category of the US AQI.
+	USAQICategory api.AQICategory `json:"us_aqi_category"`
+	// EuropeanAQICategory is the category of the European AQI.
+	EuropeanAQICategory api.AQICategory `json:"european_aqi_category"`
+}
+
+// newAirQualityRecord returns the batch record of the given air quality.
+func newAirQualityRecord(airQuality api.AirQuality) *airQualityRecord {
+	return &airQualityRecord{
+		AirQuality:          airQuality,
+		USAQICategory:       api.USAQICategory(airQuality.USAQI),
+		EuropeanAQICategory: api.EuropeanAQICategory(airQuality.EuropeanAQI),
+	}
+}
+
+// colorize wraps text in the ANSI escape sequence of the given color if color output is enabled.
+func colorize(text string, color string, enabled bool) string {
+	if code, ok := ansiColors[color]; ok && enabled {
+		return code + text + ansiReset
+	}
+	return text
+}
+
+// displayAirQuality writes the air quality section of the current conditions: both indexes with their category, the
+// pollutant concentrations and, where available, the pollen. Categories are colored if color is set.
+func displayAirQuality(w io.Writer, airQuality api.AirQuality, lc locale.Locale, color bool) {
+	usCategory := api.USAQICategory(airQuality.USAQI)
+	europeanCategory := api.EuropeanAQICategory(airQuality.EuropeanAQI)
+
+	fmt.Fprintln(w, "Air Quality: ")
+	fmt.Fprintln(w, "---------------------------")
+	fmt.Fprintf(w, "US AQI: %.0f (%s)\n", airQuality.USAQI, colorize(usCategory.Label, usCategory.Color, color))
+	fmt.Fprintf(w, "European AQI: %.0f (%s)\n", airQuality.EuropeanAQI, colorize(europeanCategory.Label, europeanCategory.Color, color))
+	fmt.Fprintf(w, "PM2.5: %s μg/m³  PM10: %s μg/m³  Ozone: %s μg/m³\n",
+		lc.Number(airQuality.PM25, 1), lc.Number(airQuality.PM10, 1), lc.Number(airQuality.Ozone, 1))
+
+	if p := airQuality.Pollen; p != nil {
+		var pollen []string
+		for _, plant := range []struct {
+			name  string
+			value *float64
+		}{
+			{"Alder", p.Alder}, {"Birch", p.Birch}, {"Grass", p.Grass},
+			{"Mugwort", p.Mugwort}, {"Olive", p.Olive}, {"Ragweed", p.Ragweed},
+		} {
+			if plant.value != nil {
+				pollen = append(pollen, fmt.Sprintf("%s %s", plant.name, lc.Number(*plant.value, 1)))
+			}
+		}
+		fmt.Fprintf(w, "Pollen (grains/m³): %s\n", strings.Join(pollen, ", "))
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/locale"
+)
+
+func TestMain_displayAirQuality(t *testing.T) {
+	lc, _ := locale.Lookup(locale.Default)
+	grass := 23.5
+	airQuality := api.AirQuality{PM25: 8.4, PM10: 12.1, Ozone: 61, USAQI: 112, EuropeanAQI: 18, Pollen: &api.Pollen{Grass: &grass}}
+
+	var b strings.Builder
+	displayAirQuality(&b, airQuality, lc, false)
+	expected := `Air Quality: 
+---------------------------
+US AQI: 112 (Unhealthy for Sensitive Groups)
+European AQI: 18 (Good)
+PM2.5: 8.4 μg/m³  PM10: 12.1 μg/m³  Ozone: 61.0 μg/m³
+Pollen (grains/m³): Grass 23.5
+
+`
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	displayAirQuality(&b, airQuality, lc, true)
+	if !strings.Contains(b.String(), "\033[38;5;208mUnhealthy for Sensitive Groups\033[0m") {
+		t.Errorf("Expected the US AQI category in orange, got %q", b.String())
+	}
+}
+
+func TestMain_forecastBatch_AirQuality(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/maps/api/geocode/json":
+			w.Write([]byte(`{"results": [{"formatted_address": "Unter den Linden 1, 10117 Berlin, Germany",
+				"geometry": {"location": {"lat": 52.5170365, "lng": 13.3888599}}}]}`))
+		case "/v1/forecast":
+			w.Write([]byte(`{"current": {"temperature_2m": 18.2},
+				"daily": {"time": ["2024-09-19"], "temperature_2m_max": [21.3], "temperature_2m_min": [12.8]}}`))
+		case "/v1/air-quality":
+			w.Write([]byte(`{"current": {"pm2_5": 8.4, "pm10": 12.1, "ozone": 61.0, "us_aqi": 42, "european_aqi": 45}}`))
+		}
+	}))
+	defer server.Close()
+
+	records := forecastBatch([]batchInput{{ID: "1", Address: "Unter den Linden 1, Berlin"}}, 1, cache.GetCacheInstance(),
+		server.URL, server.URL, server.URL, "testApiKey", api.ForecastOptions{Units: api.Celsius})
+	if records[0].Error != "" {
+		t.Fatalf("Expected no error, got %s", records[0].Error)
+	}
+	airQuality := records[0].AirQuality
+	if airQuality == nil || airQuality.USAQI != 42 || airQuality.EuropeanAQICategory.Label != "Moderate" {
+		t.Errorf("Expected US AQI 42 and a Moderate European AQI, got %+v", airQuality)
+	}
+
+	var b strings.Builder
+	if err := writeBatchJSON(&b, records); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(b.String(), `"air_quality":{"pm2_5":8.4,"pm10":12.1,"ozone":61,"us_aqi":42,"european_aqi":45,"us_aqi_category":{"label":"Good","color":"green"}`) {
+		t.Errorf("Expected the air quality in the JSON record, got %s", b.String())
+	}
+}
@@ -0,0 +1,243 @@
+// Package alert evaluates threshold rules, such as "tomorrow min < 32F at hq", against daily forecasts and delivers
+// the rules that fire to notifiers such as stdout, a local command or an HTTP webhook.
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+)
+
+// Day selects the forecast days a rule applies to.
+type Day string
+
+const (
+	// AnyDay applies a rule to every day from today on.
+	AnyDay Day = "any"
+	// Today applies a rule to the current day at the location.
+	Today Day = "today"
+	// Tomorrow applies a rule to the day after the current day at the location.
+	Tomorrow Day = "tomorrow"
+)
+
+// fields maps the names of the daily values a rule can test to a function that returns the value of a day, if the
+// forecast has it.
+var fields = map[string]func(wf api.WeeklyForecast, day int) (float64, bool){
+	"min":           dailyValue(func(wf api.WeeklyForecast) []float64 { return wf.Temperature2MMin }),
+	"max":           dailyValue(func(wf api.WeeklyForecast) []float64 { return wf.Temperature2MMax }),
+	"precipitation": dailyValue(func(wf api.WeeklyForecast) []float64 { return wf.PrecipitationSum }),
+	"chance":        dailyValue(func(wf api.WeeklyForecast) []float64 { return wf.PrecipitationProbabilityMax }),
+	"snowfall":      dailyValue(func(wf api.WeeklyForecast) []float64 { return wf.SnowfallSum }),
+	"uv":            dailyValue(func(wf api.WeeklyForecast) []float64 { return wf.UVIndexMax }),
+}
+
+// temperatureFields are the fields whose threshold may carry a temperature unit.
+var temperatureFields = map[string]bool{"min": true, "max": true}
+
+// operators maps the comparison operators of a rule to their implementation.
+var operators = map[string]func(value, threshold float64) bool{
+	"<":  func(value, threshold float64) bool { return value < threshold },
+	"<=": func(value, threshold float64) bool { return value <= threshold },
+	">":  func(value, threshold float64) bool { return value > threshold },
+	">=": func(value, threshold float64) bool { return value >= threshold },
+}
+
+// dailyValue returns a field function that reads the day from the slice selected by values.
+func dailyValue(values func(wf api.WeeklyForecast) []float64) func(wf api.WeeklyForecast, day int) (float64, bool) {
+	return func(wf api.WeeklyForecast, day int) (float64, bool) {
+		v := values(wf)
+		if day < 0 || day >= len(v) {
+			return 0, false
+		}
+		return v[day], true
+	}
+}
+
+// Rule is a threshold on a daily forecast value at a location.
+type Rule struct {
+	// Day selects the days the rule applies to.
+	Day Day
+	// Field is the daily value tested: min, max, precipitation, chance, snowfall or uv.
+	Field string
+	// Operator compares the value to the threshold: <, <=, > or >=.
+	Operator string
+	// Threshold is the value the daily value is compared to.
+	Threshold float64
+	// Units is the temperature unit of Threshold. If empty, the threshold is in the units of the forecast.
+	Units api.Units
+	// Location is the address or favorite name the rule applies to.
+	Location string
+}
+
+// ParseRule parses a rule of the form "[today|tomorrow|any] <field> <operator> <threshold>[F|C] at <location>", such
+// as "tomorrow min < 32F at hq" or "max > 100F at 3001 Esperanza Crossing, Austin". A leading "notify if" is ignored.
+// Rules without a day apply to any day. The location is optional if defaultLocation is set.
+func ParseRule(s string, defaultLocation string) (Rule, error) {
+	condition, location, _ := strings.Cut(s, " at ")
+	if prefix := "notify if "; strings.HasPrefix(strings.ToLower(condition), prefix) {
+		condition = condition[len(prefix):]
+	}
+	rule := Rule{Day: AnyDay, Location: strings.TrimSpace(location)}
+	if rule.Location == "" {
+		rule.Location = defaultLocation
+	}
+	if rule.Location == "" {
+		return Rule{}, fmt.Errorf("invalid rule %q: missing location, as in \"at hq\"", s)
+	}
+
+	words := strings.Fields(strings.ToLower(condition))
+	if len(words) > 0 {
+		switch day := Day(strings.TrimSuffix(words[0], "'s")); day {
+		case AnyDay, Today, Tomorrow:
+			rule.Day = day
+			words = words[1:]
+		}
+	}
+	if len(words) != 3 {
+		return Rule{}, fmt.Errorf("invalid rule %q: must be [today|tomorrow|any] <field> <operator> <threshold> at <location>", s)
+	}
+
+	rule.Field, rule.Operator = words[0], words[1]
+	if _, ok := fields[rule.Field]; !ok {
+		return Rule{}, fmt.Errorf("invalid rule %q: unknown field %q, must be one of min, max, precipitation, chance, snowfall or uv", s, rule.Field)
+	}
+	if _, ok := operators[rule.Operator]; !ok {
+		return Rule{}, fmt.Errorf("invalid rule %q: unknown operator %q, must be <, <=, > or >=", s, rule.Operator)
+	}
+
+	threshold := strings.TrimSuffix(words[2], "%")
+	if temperatureFields[rule.Field] {
+		threshold = strings.TrimSuffix(threshold, "°")
+		switch {
+		case strings.HasSuffix(threshold, "f"):
+			threshold, rule.Units = strings.TrimSuffix(threshold, "f"), api.Fahrenheit
+		case strings.HasSuffix(threshold, "c"):
+			threshold, rule.Units = strings.TrimSuffix(threshold, "c"), api.Celsius
+		}
+		threshold = strings.TrimSuffix(threshold, "°")
+	}
+	v, err := strconv.ParseFloat(threshold, 64)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid rule %q: invalid threshold %q", s, words[2])
+	}
+	rule.Threshold = v
+	return rule, nil
+}
+
+// String returns the rule in the form accepted by ParseRule.
+func (r Rule) String() string {
+	return fmt.Sprintf("%s %s %s %s%s at %s", r.Day, r.Field, r.Operator,
+		strconv.FormatFloat(r.Threshold, 'f', -1, 64), unitSuffix(r.Units), r.Location)
+}
+
+// threshold returns the threshold of the rule converted to the given forecast units.
+func (r Rule) threshold(units api.Units) float64 {
+	if units == "" {
+		units = api.Fahrenheit
+	}
+	switch {
+	case r.Units == api.Fahrenheit && units == api.Celsius:
+		return (r.Threshold - 32) * 5 / 9
+	case r.Units == api.Celsius && units == api.Fahrenheit:
+		return r.Threshold*9/5 + 32
+	default:
+		return r.Threshold
+	}
+}
+
+// Firing is a rule that fired for a day of a forecast.
+type Firing struct {
+	// Rule is the rule that fired, in the form accepted by ParseRule.
+	Rule string `json:"rule"`
+	// Location is the address or favorite name of the rule.
+	Location string `json:"location"`
+	// Date is the forecast day the rule fired for, such as 2024-09-20.
+	Date string `json:"date"`
+	// Field is the daily value tested.
+	Field string `json:"field"`
+	// Value is the forecast value that crossed the threshold.
+	Value float64 `json:"value"`
+	// Threshold is the threshold in the units of the forecast.
+	Threshold float64 `json:"threshold"`
+	// Units is the unit system of the forecast.
+	Units api.Units `json:"units"`
+	// Message describes the firing for people.
+	Message string `json:"message"`
+}
+
+// key identifies the firing for deduplication: a rule fires at most once per day it applies to.
+func (f Firing) key() string {
+	return f.Rule + "|" + f.Date
+}
+
+// Evaluate returns a firing for every day of the forecast the rule applies to whose value crosses the threshold. The
+// days are counted relative to now in the forecast's time zone, and units is the unit system of the forecast.
+func (r Rule) Evaluate(wf api.WeeklyForecast, units api.Units, now time.Time) []Firing {
+	today := wf.DayIndex(now)
+	if today < 0 {
+		return nil
+	}
+	if units == "" {
+		units = api.Fahrenheit
+	}
+
+	var days []int
+	switch r.Day {
+	case Today:
+		days = []int{today}
+	case Tomorrow:
+		days = []int{today + 1}
+	default:
+		for day := today; day < len(wf.Time); day++ {
+			days = append(days, day)
+		}
+	}
+
+	threshold := r.threshold(units)
+	var firings []Firing
+	for _, day := range days {
+		value, ok := fields[r.Field](wf, day)
+		if !ok || day >= len(wf.Time) || !operators[r.Operator](value, threshold) {
+			continue
+		}
+		firings = append(firings, Firing{
+			Rule:      r.String(),
+			Location:  r.Location,
+			Date:      wf.Time[day],
+			Field:     r.Field,
+			Value:     value,
+			Threshold: threshold,
+			Units:     units,
+			Message: fmt.Sprintf("%s: %s %s %s %s %s (rule: %s)", r.Location, wf.Time[day], r.Field,
+				formatValue(r.Field, value, units), r.Operator, formatValue(r.Field, threshold, units), r.String()),
+		})
+	}
+	return firings
+}
+
+// formatValue formats the value of a field with its unit.
+func formatValue(field string, v float64, units api.Units) string {
+	switch field {
+	case "min", "max":
+		return fmt.Sprintf("%.1f %s", v, units.Symbol())
+	case "precipitation":
+		return fmt.Sprintf("%.2f %s", v, units.PrecipitationSymbol())
+	case "snowfall":
+		return fmt.Sprintf("%.2f %s", v, units.SnowfallSymbol())
+	case "chance":
+		return fmt.Sprintf("%.0f%%", v)
+	default:
+		return strconv.FormatFloat(v, 'f', 1, 64)
+	}
+}
+
+// unitSuffix returns the temperature symbol of a threshold unit, or an empty string if the unit is not set.
+func unitSuffix(u api.Units) string {
+	if u == "" {
+		return ""
+	}
+	return u.Symbol()
+}
@@ -0,0 +1,115 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+)
+
+func TestParseRule(t *testing.T) {
+	tc := []struct {
+		name     string
+		rule     string
+		expected Rule
+		err      string
+	}{
+		{
+			name:     "Tomorrow Min",
+			rule:     "notify if tomorrow's min < 32F at hq",
+			expected: Rule{Day: Tomorrow, Field: "min", Operator: "<", Threshold: 32, Units: api.Fahrenheit, Location: "hq"},
+		},
+		{
+			name:     "Any Day With Default Location",
+			rule:     "max > 37.5°C",
+			expected: Rule{Day: AnyDay, Field: "max", Operator: ">", Threshold: 37.5, Units: api.Celsius, Location: "home"},
+		},
+		{
+			name:     "Chance Without Units",
+			rule:     "today chance >= 80% at 3001 Esperanza Crossing, Austin",
+			expected: Rule{Day: Today, Field: "chance", Operator: ">=", Threshold: 80, Location: "3001 Esperanza Crossing, Austin"},
+		},
+		{name: "Unknown Field", rule: "tomorrow wind > 20 at hq", err: `invalid rule "tomorrow wind > 20 at hq": unknown field "wind", must be one of min, max, precipitation, chance, snowfall or uv`},
+		{name: "Invalid Threshold", rule: "min < cold at hq", err: `invalid rule "min < cold at hq": invalid threshold "cold"`},
+		{name: "Too Many Words", rule: "tomorrow min is < 32 at hq", err: `invalid rule "tomorrow min is < 32 at hq": must be [today|tomorrow|any] <field> <operator> <threshold> at <location>`},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := ParseRule(tc.rule, "home")
+			// Check for error cases
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("Expected error '%s', got %v", tc.err, err)
+				}
+				return
+			}
+
+			// Check for success cases
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if rule != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, rule)
+			}
+		})
+	}
+
+	if _, err := ParseRule("max > 100F", ""); err == nil {
+		t.Error("Expected an error for a rule without a location, got nil")
+	}
+}
+
+func TestRule_String(t *testing.T) {
+	rule, _ := ParseRule("tomorrow's min < 32F at hq", "")
+	if rule.String() != "tomorrow min < 32F at hq" {
+		t.Errorf("Expected 'tomorrow min < 32F at hq', got %s", rule.String())
+	}
+	if reparsed, _ := ParseRule(rule.String(), ""); reparsed != rule {
+		t.Errorf("Expected %+v after a round trip, got %+v", rule, reparsed)
+	}
+}
+
+func TestRule_Evaluate(t *testing.T) {
+	wf := api.WeeklyForecast{
+		Time:             []string{"2024-09-18", "2024-09-19", "2024-09-20", "2024-09-21"},
+		Temperature2MMax: []float64{104.0, 101.2, 97.6, 102.5},
+		Temperature2MMin: []float64{28.0, 75.8, 31.1, 74.0},
+	}
+	now := time.Date(2024, time.September, 19, 15, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		name  string
+		rule  string
+		units api.Units
+		dates []string
+	}{
+		{name: "Tomorrow", rule: "tomorrow min < 32F at hq", dates: []string{"2024-09-20"}},
+		{name: "Today Not Crossed", rule: "today min < 32F at hq"},
+		{name: "Any Day From Today", rule: "max > 100F at hq", dates: []string{"2024-09-19", "2024-09-21"}},
+		{name: "Celsius Threshold In Fahrenheit", rule: "max >= 39C at hq", dates: []string{"2024-09-21"}},
+		{name: "Fahrenheit Threshold In Celsius", rule: "tomorrow min < 90F at hq", units: api.Celsius, dates: []string{"2024-09-20"}},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := ParseRule(tc.rule, "")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			firings := rule.Evaluate(wf, tc.units, now)
+			if len(firings) != len(tc.dates) {
+				t.Fatalf("Expected %d firings, got %v", len(tc.dates), firings)
+			}
+			for i, date := range tc.dates {
+				if firings[i].Date != date {
+					t.Errorf("Expected a firing on %s, got %s", date, firings[i].Date)
+				}
+			}
+		})
+	}
+
+	if firings := (Rule{Day: AnyDay, Field: "max", Operator: ">", Threshold: 0, Location: "hq"}).Evaluate(wf, "", now.AddDate(0, 0, 7)); firings != nil {
+		t.Errorf("Expected no firings for a stale forecast, got %v", firings)
+	}
+}
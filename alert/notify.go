@@ -0,0 +1,125 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// defaultWebhookTimeout bounds how long a webhook delivery may take when WebhookNotifier.Timeout is not set, so that
+// a receiver that never answers cannot hold up the other rules.
+const defaultWebhookTimeout = 10 * time.Second
+
+// Notifier delivers firings.
+type Notifier interface {
+	Notify(ctx context.Context, firing Firing) error
+}
+
+// marshalFiring returns the JSON payload of a firing. Operators such as < are not escaped, so that commands can use
+// the rule as is.
+func marshalFiring(firing Firing) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(firing); err != nil {
+		return nil, fmt.Errorf("error marshalling alert: %v", err)
+	}
+	return b.Bytes(), nil
+}
+
+// WriterNotifier writes one line per firing to a writer, such as stdout.
+type WriterNotifier struct {
+	W io.Writer
+}
+
+// Notify writes the message of the firing.
+func (n WriterNotifier) Notify(ctx context.Context, firing Firing) error {
+	_, err := fmt.Fprintf(n.W, "ALERT %s\n", firing.Message)
+	return err
+}
+
+// CommandNotifier runs a local command through the shell for each firing. The command receives the firing as JSON on
+// stdin and its fields in the WEATHER_ALERT_RULE, WEATHER_ALERT_LOCATION, WEATHER_ALERT_DATE, WEATHER_ALERT_VALUE and
+// WEATHER_ALERT_MESSAGE environment variables.
+type CommandNotifier struct {
+	Command string
+}
+
+// Notify runs the command and returns an error if it fails.
+func (n CommandNotifier) Notify(ctx context.Context, firing Firing) error {
+	payload, err := marshalFiring(firing)
+	if err != nil {
+		return err
+	}
+
+	shell, flag := "sh", "-c"
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+	cmd := exec.CommandContext(ctx, shell, flag, n.Command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"WEATHER_ALERT_RULE="+firing.Rule,
+		"WEATHER_ALERT_LOCATION="+firing.Location,
+		"WEATHER_ALERT_DATE="+firing.Date,
+		fmt.Sprintf("WEATHER_ALERT_VALUE=%g", firing.Value),
+		"WEATHER_ALERT_MESSAGE="+firing.Message,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running alert command: %v: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// WebhookNotifier POSTs each firing as JSON to a URL.
+type WebhookNotifier struct {
+	URL string
+	// Client sends the requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+	// Timeout bounds each delivery, including reading the response. If zero, defaultWebhookTimeout is used.
+	Timeout time.Duration
+}
+
+// Notify posts the firing and returns an error unless the receiver answers with a 2xx status.
+func (n WebhookNotifier) Notify(ctx context.Context, firing Firing) error {
+	payload, err := marshalFiring(firing)
+	if err != nil {
+		return err
+	}
+
+	timeout := n.Timeout
+	if timeout == 0 {
+		timeout = defaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error posting webhook: %v", err)
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("received non-2xx webhook status: %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+)
+
+// Source retrieves the forecast of a rule's location.
+type Source func(ctx context.Context, location string) (api.WeeklyForecast, error)
+
+// Watcher periodically evaluates rules against the forecast of their location and notifies each firing once.
+type Watcher struct {
+	// Rules are the rules to evaluate.
+	Rules []Rule
+	// Source retrieves the forecast of a location.
+	Source Source
+	// Units is the unit system of the forecasts returned by Source.
+	Units api.Units
+	// Notifiers deliver the firings. A firing is delivered to every notifier.
+	Notifiers []Notifier
+	// Now returns the current time. If nil, time.Now is used.
+	Now func() time.Time
+
+	mu sync.Mutex
+	// delivered maps each firing delivered to a notifier, keyed by the notifier's index and the firing, to its date.
+	delivered map[string]string
+}
+
+// Check evaluates every rule once and notifies the firings that have not been notified before. A notifier that fails
+// to deliver a firing is retried on the next check, while the notifiers that delivered it are not notified again. It
+// returns the firings that have now been delivered to every notifier, and an error joining the failed deliveries.
+func (w *Watcher) Check(ctx context.Context) ([]Firing, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.delivered == nil {
+		w.delivered = map[string]string{}
+	}
+	now := time.Now()
+	if w.Now != nil {
+		now = w.Now()
+	}
+
+	// Fetch each location once, even if several rules share it
+	forecasts := map[string]api.WeeklyForecast{}
+	var notified []Firing
+	var errs []error
+	for _, rule := range w.Rules {
+		wf, ok := forecasts[rule.Location]
+		if !ok {
+			var err error
+			wf, err = w.Source(ctx, rule.Location)
+			if err != nil {
+				slog.WarnContext(ctx, "alert forecast unavailable", "location", rule.Location, "error", err)
+				continue
+			}
+			forecasts[rule.Location] = wf
+		}
+
+		for _, firing := range rule.Evaluate(wf, w.Units, now) {
+			complete, err := w.notify(ctx, firing)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			if complete {
+				notified = append(notified, firing)
+			}
+		}
+	}
+
+	// Forget the deliveries of past days, so the map does not grow forever. Dates are local to each rule's location,
+	// which can be up to a day ahead of or behind now, so two days are kept: a day that is yesterday here may still be
+	// today at the location, and forgetting it would notify its firings again.
+	cutoff := now.AddDate(0, 0, -2).Format("2006-01-02")
+	for key, date := range w.delivered {
+		if date < cutoff {
+			delete(w.delivered, key)
+		}
+	}
+	return notified, errors.Join(errs...)
+}
+
+// notify delivers the firing to every notifier that has not delivered it yet. It reports whether this completed the
+// delivery to every notifier, and returns an error if any notifier failed.
+func (w *Watcher) notify(ctx context.Context, firing Firing) (bool, error) {
+	delivered := false
+	var errs []error
+	for i, notifier := range w.Notifiers {
+		key := fmt.Sprintf("%d/%s", i, firing.key())
+		if _, ok := w.delivered[key]; ok {
+			continue
+		}
+		if err := notifier.Notify(ctx, firing); err != nil {
+			slog.ErrorContext(ctx, "alert notification failed", "rule", firing.Rule, "date", firing.Date, "error", err)
+			errs = append(errs, fmt.Errorf("error notifying %q on %s: %v", firing.Rule, firing.Date, err))
+			continue
+		}
+		w.delivered[key] = firing.Date
+		delivered = true
+	}
+	return delivered && len(errs) == 0, errors.Join(errs...)
+}
+
+// Run checks the rules immediately and then on every interval until ctx is done.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		// Failed deliveries are logged and retried on the next check
+		w.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+)
+
+// failingNotifier fails the first failures notifications and records every firing it is asked to deliver.
+type failingNotifier struct {
+	failures int
+	firings  []Firing
+}
+
+func (n *failingNotifier) Notify(ctx context.Context, firing Firing) error {
+	n.firings = append(n.firings, firing)
+	if n.failures > 0 {
+		n.failures--
+		return errors.New("unavailable")
+	}
+	return nil
+}
+
+func TestWatcher_Check(t *testing.T) {
+	wf := api.WeeklyForecast{
+		Time:             []string{"2024-09-19", "2024-09-20", "2024-09-21"},
+		Temperature2MMin: []float64{75.8, 28.4, 30.1},
+	}
+	now := time.Date(2024, time.September, 19, 15, 0, 0, 0, time.UTC)
+	sourceCalls := 0
+	notifier := &failingNotifier{failures: 1}
+	rule, _ := ParseRule("min < 32F at hq", "")
+	watcher := &Watcher{
+		Rules: []Rule{rule},
+		Source: func(ctx context.Context, location string) (api.WeeklyForecast, error) {
+			sourceCalls++
+			return wf, nil
+		},
+		Notifiers: []Notifier{notifier},
+		Now:       func() time.Time { return now },
+	}
+
+	// The first delivery fails, so only the 2024-09-21 firing is notified
+	notified, err := watcher.Check(context.Background())
+	if len(notified) != 1 || notified[0].Date != "2024-09-21" {
+		t.Errorf("Expected the 2024-09-21 firing, got %v", notified)
+	}
+	if expected := `error notifying "any min < 32F at hq" on 2024-09-20: unavailable`; err == nil || err.Error() != expected {
+		t.Errorf("Expected error '%s', got %v", expected, err)
+	}
+	if sourceCalls != 1 {
+		t.Errorf("Expected one forecast per location and check, got %d", sourceCalls)
+	}
+
+	// The failed firing is retried and the delivered one is not repeated
+	if notified, err := watcher.Check(context.Background()); err != nil || len(notified) != 1 || notified[0].Date != "2024-09-20" {
+		t.Errorf("Expected the retried 2024-09-20 firing and no error, got %v and %v", notified, err)
+	}
+	if notified, _ := watcher.Check(context.Background()); len(notified) != 0 {
+		t.Errorf("Expected no firings once all were delivered, got %v", notified)
+	}
+}
+
+func TestWatcher_CheckPartialFailure(t *testing.T) {
+	wf := api.WeeklyForecast{Time: []string{"2024-09-19", "2024-09-20"}, Temperature2MMin: []float64{75.8, 28.4}}
+	rule, _ := ParseRule("min < 32F at hq", "")
+	stdout, webhook := &failingNotifier{}, &failingNotifier{failures: 1}
+	watcher := &Watcher{
+		Rules:     []Rule{rule},
+		Source:    func(ctx context.Context, location string) (api.WeeklyForecast, error) { return wf, nil },
+		Notifiers: []Notifier{stdout, webhook},
+		Now:       func() time.Time { return time.Date(2024, time.September, 19, 15, 0, 0, 0, time.UTC) },
+	}
+
+	// Only the notifier that failed is retried
+	if notified, err := watcher.Check(context.Background()); len(notified) != 0 || err == nil {
+		t.Errorf("Expected no firing delivered to every notifier and an error, got %v and %v", notified, err)
+	}
+	if notified, _ := watcher.Check(context.Background()); len(notified) != 1 {
+		t.Errorf("Expected the retried firing, got %v", notified)
+	}
+	watcher.Check(context.Background())
+	if len(stdout.firings) != 1 || len(webhook.firings) != 2 {
+		t.Errorf("Expected 1 delivery to stdout and 2 attempts at the webhook, got %d and %d", len(stdout.firings), len(webhook.firings))
+	}
+}
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	tc := []struct {
+		name   string
+		status int
+		err    string
+	}{
+		{name: "Success Case", status: http.StatusNoContent},
+		{name: "Status Not OK", status: http.StatusInternalServerError, err: "received non-2xx webhook status: 500 Internal Server Error"},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("Expected a JSON POST, got %s %s", r.Method, r.Header.Get("Content-Type"))
+				}
+				var firing Firing
+				if err := json.NewDecoder(r.Body).Decode(&firing); err != nil || firing.Rule != "tomorrow min < 32F at hq" {
+					t.Errorf("Expected the firing as JSON, got %+v, %v", firing, err)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			err := WebhookNotifier{URL: server.URL}.Notify(context.Background(), Firing{Rule: "tomorrow min < 32F at hq"})
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("Expected error '%s', got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifier_NotifyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	err := WebhookNotifier{URL: server.URL, Timeout: 50 * time.Millisecond}.Notify(context.Background(), Firing{Rule: "max > 100F at hq"})
+	if err == nil || !strings.Contains(err.Error(), "context deadline exceeded") {
+		t.Errorf("Expected a deadline error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the delivery to give up after the timeout, took %s", elapsed)
+	}
+}
+
+func TestCommandNotifier_Notify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the command uses a POSIX shell")
+	}
+	out := filepath.Join(t.TempDir(), "alert.txt")
+	notifier := CommandNotifier{Command: `cat > "` + out + `"; echo "$WEATHER_ALERT_DATE" >> "` + out + `"`}
+	if err := notifier.Notify(context.Background(), Firing{Rule: "max > 100F at hq", Date: "2024-09-20"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	b, _ := os.ReadFile(out)
+	if !strings.Contains(string(b), `"rule":"max > 100F at hq"`) || !strings.HasSuffix(string(b), "}\n2024-09-20\n") {
+		t.Errorf("Expected the firing on stdin and its date in the environment, got %s", b)
+	}
+
+	if err := (CommandNotifier{Command: "exit 3"}).Notify(context.Background(), Firing{}); err == nil {
+		t.Error("Expected an error for a failing command, got nil")
+	}
+}
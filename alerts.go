@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfryhover/weather/alert"
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/logging"
+)
+
+// stringList is a flag that may be given several times, collecting every value.
+type stringList []string
+
+// String returns the values joined by commas.
+func (s *stringList) String() string {
+	return strings.Join(*s, ", ")
+}
+
+// Set appends a value.
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// readRules parses the rules of a rules file, one per line. Blank lines and lines starting with # are ignored.
+func readRules(r io.Reader, defaultLocation string) ([]alert.Rule, error) {
+	var rules []alert.Rule
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := alert.ParseRule(text, defaultLocation)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading rules: %v", err)
+	}
+	return rules, nil
+}
+
+// alertSource returns an alert source that resolves each location once and retrieves its forecast through the cache,
+// so that checks more frequent than the cache TTL do not call the forecast API.
+func alertSource(favs *favorites.Store, c *cache.Cache, cfg config.Config, opts api.ForecastOptions) alert.Source {
+	type coordinates struct {
+		address  string
+		lat, lng float64
+	}
+	var mu sync.Mutex
+	resolved := map[string]coordinates{}
+
+	return func(ctx context.Context, location string) (api.WeeklyForecast, error) {
+		ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+
+		mu.Lock()
+		coords, ok := resolved[location]
+		mu.Unlock()
+		if !ok {
+			addressFull, lat, lng, err := lookupCoordinates(ctx, location, favs, cfg)
+			if err != nil {
+				return api.WeeklyForecast{}, err
+			}
+			coords = coordinates{addressFull, lat, lng}
+			mu.Lock()
+			resolved[location] = coords
+			mu.Unlock()
+		}
+
+		_, weeklyForecast, _, err := getForecastForCoordinates(ctx, coords.address, coords.lat, coords.lng, c, cfg.Providers.ForecastURL, opts)
+		return weeklyForecast, err
+	}
+}
+
+// runAlerts runs the alerts command, which evaluates threshold rules against the forecast of their location every
+// interval until ctx is done, and notifies each firing once on stdout and optionally through a command or a webhook.
+func runAlerts(ctx context.Context, args []string, cfg config.Config, c *cache.Cache, favs *favorites.Store, stdout io.Writer) error {
+	var ruleFlags stringList
+	flags := flag.NewFlagSet("alerts", flag.ContinueOnError)
+	flags.Var(&ruleFlags, "rule", "rule to evaluate, such as \"tomorrow min < 32F at hq\" (repeatable)")
+	rulesPath := flags.String("rules", "", "file with one rule per line")
+	location := flags.String("location", "", "location of rules without \"at <location>\"")
+	interval := flags.Duration("interval", 10*time.Minute, "how often to evaluate the rules")
+	webhook := flags.String("webhook", "", "URL to POST each alert to as JSON")
+	command := flags.String("command", "", "shell command to run for each alert, with the alert as JSON on stdin")
+	once := flags.Bool("once", false, "evaluate the rules once and exit")
+	if _, err := parseInterleaved(flags, args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval %s: must be positive", *interval)
+	}
+
+	var rules []alert.Rule
+	if *rulesPath != "" {
+		f, err := os.Open(*rulesPath)
+		if err != nil {
+			return fmt.Errorf("error opening rules: %v", err)
+		}
+		fileRules, err := readRules(f, *location)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("invalid rules file %s: %v", *rulesPath, err)
+		}
+		rules = append(rules, fileRules...)
+	}
+	for _, s := range ruleFlags {
+		rule, err := alert.ParseRule(s, *location)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		return errors.New("usage: weather alerts --rule \"tomorrow min < 32F at hq\" [--rules FILE] [--interval 10m] [--webhook URL] [--command CMD] [--once]")
+	}
+
+	opts := forecastOptions(cfg)
+	notifiers := []alert.Notifier{alert.WriterNotifier{W: stdout}}
+	if *command != "" {
+		notifiers = append(notifiers, alert.CommandNotifier{Command: *command})
+	}
+	if *webhook != "" {
+		notifiers = append(notifiers, alert.WebhookNotifier{URL: *webhook})
+	}
+	watcher := &alert.Watcher{
+		Rules:     rules,
+		Source:    alertSource(favs, c, cfg, opts),
+		Units:     opts.Units,
+		Notifiers: notifiers,
+	}
+
+	if *once {
+		// Failed deliveries are reported, so that a scheduler running the command sees them
+		_, err := watcher.Check(ctx)
+		return err
+	}
+	fmt.Fprintf(stdout, "Watching %d rule(s) every %s, press Ctrl+C to stop\n", len(rules), *interval)
+	// Run only stops once ctx is done, on an interrupt, which is a clean exit
+	watcher.Run(ctx, *interval)
+	return nil
+}
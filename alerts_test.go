@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/alert"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+)
+
+func TestMain_readRules(t *testing.T) {
+	rules, err := readRules(strings.NewReader("# freeze warning\ntomorrow min < 32F at hq\n\nmax > 100F\n"), "home")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rules) != 2 || rules[0].Location != "hq" || rules[1].Location != "home" {
+		t.Errorf("Expected 2 rules at hq and home, got %v", rules)
+	}
+
+	if _, err := readRules(strings.NewReader("tomorrow min < 32F at hq\nmin is low\n"), "home"); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error on line 2, got %v", err)
+	}
+}
+
+func TestMain_runAlerts(t *testing.T) {
+	today := time.Now().UTC()
+	forecastRequests := 0
+	forecastServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forecastRequests++
+		fmt.Fprintf(w, `{"current": {"temperature_2m": 40.2},
+			"daily": {"time": ["%s", "%s"], "temperature_2m_max": [50.1, 45.3], "temperature_2m_min": [35.2, 28.4]}}`,
+			today.Format("2006-01-02"), today.AddDate(0, 0, 1).Format("2006-01-02"))
+	}))
+	defer forecastServer.Close()
+
+	var mu sync.Mutex
+	var received []alert.Firing
+	webhookServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var firing alert.Firing
+		if err := json.NewDecoder(r.Body).Decode(&firing); err != nil {
+			t.Errorf("Expected a JSON alert, got %v", err)
+		}
+		mu.Lock()
+		received = append(received, firing)
+		mu.Unlock()
+	}))
+	defer webhookServer.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ForecastURL = forecastServer.URL
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	favs.Save(favorites.Place{Name: "hq", Address: "1 Alert Way, Austin, TX 78701, USA", Latitude: 30.2711286, Longitude: -97.7436995})
+
+	// Evaluate for a while at a short interval: the firing must be delivered once and the forecast fetched once
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var b strings.Builder
+	args := []string{"--rule", "tomorrow min < 32F at hq", "--rule", "max > 100F at hq", "--interval", "20ms", "--webhook", webhookServer.URL}
+	if err := runAlerts(ctx, args, cfg, cache.GetCacheInstance(), favs, &b); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("Expected 1 webhook alert, got %d: %v", len(received), received)
+	}
+	if firing := received[0]; firing.Location != "hq" || firing.Date != today.AddDate(0, 0, 1).Format("2006-01-02") || firing.Value != 28.4 {
+		t.Errorf("Expected tomorrow's min of 28.4 at hq, got %+v", firing)
+	}
+	if strings.Count(b.String(), "ALERT ") != 1 || !strings.Contains(b.String(), "min 28.4 F < 32.0 F") {
+		t.Errorf("Expected one alert on stdout, got:\n%s", b.String())
+	}
+	if forecastRequests != 1 {
+		t.Errorf("Expected the forecast to be fetched once and then served from the cache, got %d requests", forecastRequests)
+	}
+
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failingServer.Close()
+
+	tc := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "No Rules", args: []string{"--once"}, err: "usage: weather alerts"},
+		{name: "Failed Delivery", args: []string{"--rule", "tomorrow min < 32F at hq", "--once", "--webhook", failingServer.URL}, err: "received non-2xx webhook status: 500"},
+		{name: "Invalid Rule", args: []string{"--rule", "tomorrow min ~ 32F at hq"}, err: `unknown operator "~"`},
+		{name: "Invalid Interval", args: []string{"--rule", "max > 100F at hq", "--interval", "0s"}, err: "invalid interval"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			err := runAlerts(context.Background(), tc.args, cfg, cache.GetCacheInstance(), favs, &b)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected an error containing '%s', got %v", tc.err, err)
+			}
+		})
+	}
+}
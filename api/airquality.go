@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// airQualityPathTemplate defines the URL path template for fetching current air quality and pollen from the
+	// Open-Meteo air quality API.
+	airQualityPathTemplate = "/v1/air-quality?latitude=%f&longitude=%f&current=pm2_5,pm10,ozone,us_aqi,european_aqi,alder_pollen,birch_pollen,grass_pollen,mugwort_pollen,olive_pollen,ragweed_pollen&timezone=auto"
+)
+
+// AirQuality holds the current air quality at a location. Concentrations are in μg/m³ and pollen in grains/m³.
+type AirQuality struct {
+	// PM25 is the concentration of particulate matter smaller than 2.5 μm.
+	PM25 float64 `json:"pm2_5"`
+	// PM10 is the concentration of particulate matter smaller than 10 μm.
+	PM10 float64 `json:"pm10"`
+	// Ozone is the concentration of ozone.
+	Ozone float64 `json:"ozone"`
+	// USAQI is the United States Air Quality Index, from 0 upwards.
+	USAQI float64 `json:"us_aqi"`
+	// EuropeanAQI is the European Air Quality Index, from 0 upwards.
+	EuropeanAQI float64 `json:"european_aqi"`
+	// Pollen holds the pollen of each plant, such as grass. It is only available in Europe during pollen season and
+	// is nil elsewhere.
+	Pollen *Pollen `json:"pollen,omitempty"`
+}
+
+// Pollen holds the pollen of each plant. Plants without data are nil.
+type Pollen struct {
+	// Alder is the alder pollen.
+	Alder *float64 `json:"alder,omitempty"`
+	// Birch is the birch pollen.
+	Birch *float64 `json:"birch,omitempty"`
+	// Grass is the grass pollen.
+	Grass *float64 `json:"grass,omitempty"`
+	// Mugwort is the mugwort pollen.
+	Mugwort *float64 `json:"mugwort,omitempty"`
+	// Olive is the olive pollen.
+	Olive *float64 `json:"olive,omitempty"`
+	// Ragweed is the ragweed pollen.
+	Ragweed *float64 `json:"ragweed,omitempty"`
+}
+
+// airQualityResponse holds the air quality response from the API
+type airQualityResponse struct {
+	Current struct {
+		PM25          float64  `json:"pm2_5"`
+		PM10          float64  `json:"pm10"`
+		Ozone         float64  `json:"ozone"`
+		USAQI         float64  `json:"us_aqi"`
+		EuropeanAQI   float64  `json:"european_aqi"`
+		AlderPollen   *float64 `json:"alder_pollen"`
+		BirchPollen   *float64 `json:"birch_pollen"`
+		GrassPollen   *float64 `json:"grass_pollen"`
+		MugwortPollen *float64 `json:"mugwort_pollen"`
+		OlivePollen   *float64 `json:"olive_pollen"`
+		RagweedPollen *float64 `json:"ragweed_pollen"`
+	} `json:"current"`
+}
+
+// AQICategory is the band an air quality index value falls in, with the color the index publishes for it.
+type AQICategory struct {
+	// Label names the category, such as Moderate.
+	Label string `json:"label"`
+	// Color is the color of the category, such as yellow.
+	Color string `json:"color"`
+}
+
+// usAQICategories holds the upper bound of each US AQI category, as published by the EPA.
+var usAQICategories = []struct {
+	max      float64
+	category AQICategory
+}{
+	{50, AQICategory{"Good", "green"}},
+	{100, AQICategory{"Moderate", "yellow"}},
+	{150, AQICategory{"Unhealthy for Sensitive Groups", "orange"}},
+	{200, AQICategory{"Unhealthy", "red"}},
+	{300, AQICategory{"Very Unhealthy", "purple"}},
+}
+
+// europeanAQICategories holds the upper bound of each European AQI category, as published by the EEA.
+var europeanAQICategories = []struct {
+	max      float64
+	category AQICategory
+}{
+	{20, AQICategory{"Good", "green"}},
+	{40, AQICategory{"Fair", "yellow"}},
+	{60, AQICategory{"Moderate", "orange"}},
+	{80, AQICategory{"Poor", "red"}},
+	{100, AQICategory{"Very Poor", "purple"}},
+}
+
+// USAQICategory returns the category of a US AQI value, from Good to Hazardous.
+func USAQICategory(aqi float64) AQICategory {
+	for _, c := range usAQICategories {
+		if aqi <= c.max {
+			return c.category
+		}
+	}
+	return AQICategory{"Hazardous", "maroon"}
+}
+
+// EuropeanAQICategory returns the category of a European AQI value, from Good to Extremely Poor.
+func EuropeanAQICategory(aqi float64) AQICategory {
+	for _, c := range europeanAQICategories {
+		if aqi <= c.max {
+			return c.category
+		}
+	}
+	return AQICategory{"Extremely Poor", "maroon"}
+}
+
+// GetAirQualityContext retrieves the current air quality and pollen for the given latitude and longitude from the air
+// quality API at baseURL.
+func GetAirQualityContext(ctx context.Context, latitude float64, longitude float64, baseURL string) (AirQuality, error) {
+	// Build the full API request URL
+	fullURL := baseURL + fmt.Sprintf(airQualityPathTemplate, latitude, longitude)
+
+	// Make the request and unmarshal the JSON data into the air quality struct
+	res := airQualityResponse{}
+	if err := getJSON(ctx, providerAirQuality, fullURL, &res); err != nil {
+		return AirQuality{}, err
+	}
+
+	current := res.Current
+	airQuality := AirQuality{
+		PM25:        current.PM25,
+		PM10:        current.PM10,
+		Ozone:       current.Ozone,
+		USAQI:       current.USAQI,
+		EuropeanAQI: current.EuropeanAQI,
+	}
+	pollen := Pollen{
+		Alder:   current.AlderPollen,
+		Birch:   current.BirchPollen,
+		Grass:   current.GrassPollen,
+		Mugwort: current.MugwortPollen,
+		Olive:   current.OlivePollen,
+		Ragweed: current.RagweedPollen,
+	}
+	if pollen != (Pollen{}) {
+		airQuality.Pollen = &pollen
+	}
+	return airQuality, nil
+}
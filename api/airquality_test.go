@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_GetAirQualityContext(t *testing.T) {
+	tc := []struct {
+		name         string
+		status       int
+		mockResponse string
+		usAQI        float64
+		grassPollen  float64
+		error        string
+	}{
+		{
+			name:   "Success Case Without Pollen",
+			status: http.StatusOK,
+			mockResponse: `{"current": {"pm2_5": 8.4, "pm10": 12.1, "ozone": 61.0, "us_aqi": 42, "european_aqi": 18,
+				"alder_pollen": null, "birch_pollen": null, "grass_pollen": null, "mugwort_pollen": null, "olive_pollen": null, "ragweed_pollen": null}}`,
+			usAQI: 42,
+		},
+		{
+			name:   "Success Case With Pollen",
+			status: http.StatusOK,
+			mockResponse: `{"current": {"pm2_5": 8.4, "pm10": 12.1, "ozone": 61.0, "us_aqi": 42, "european_aqi": 18,
+				"grass_pollen": 23.5}}`,
+			usAQI:       42,
+			grassPollen: 23.5,
+		},
+		{
+			name:         "Error - Non-OK Status",
+			status:       http.StatusInternalServerError,
+			mockResponse: `{}`,
+			error:        "received non-OK HTTP status: 500 Internal Server Error",
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/air-quality" {
+					t.Errorf("Expected path /v1/air-quality, got %s", r.URL.Path)
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.mockResponse))
+			}))
+			defer server.Close()
+
+			airQuality, err := GetAirQualityContext(context.Background(), 30.3985991, -97.7220666, server.URL)
+			// Check for error cases
+			if tc.error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.error) {
+					t.Errorf("Expected error '%s', got %v", tc.error, err)
+				}
+				return
+			}
+
+			// Check for success cases
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if airQuality.USAQI != tc.usAQI || airQuality.PM25 != 8.4 {
+				t.Errorf("Expected US AQI %.0f and PM2.5 8.4, got %+v", tc.usAQI, airQuality)
+			}
+			if tc.grassPollen == 0 {
+				if airQuality.Pollen != nil {
+					t.Errorf("Expected no pollen, got %+v", airQuality.Pollen)
+				}
+				return
+			}
+			if airQuality.Pollen == nil || airQuality.Pollen.Grass == nil || *airQuality.Pollen.Grass != tc.grassPollen {
+				t.Errorf("Expected grass pollen %.1f, got %+v", tc.grassPollen, airQuality.Pollen)
+			}
+		})
+	}
+}
+
+func Test_AQICategory(t *testing.T) {
+	tc := []struct {
+		name     string
+		category AQICategory
+		expected AQICategory
+	}{
+		{name: "US Good", category: USAQICategory(42), expected: AQICategory{"Good", "green"}},
+		{name: "US Boundary", category: USAQICategory(100), expected: AQICategory{"Moderate", "yellow"}},
+		{name: "US Sensitive Groups", category: USAQICategory(101), expected: AQICategory{"Unhealthy for Sensitive Groups", "orange"}},
+		{name: "US Hazardous", category: USAQICategory(350), expected: AQICategory{"Hazardous", "maroon"}},
+		{name: "European Fair", category: EuropeanAQICategory(35), expected: AQICategory{"Fair", "yellow"}},
+		{name: "European Extremely Poor", category: EuropeanAQICategory(120), expected: AQICategory{"Extremely Poor", "maroon"}},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.category != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, tc.category)
+			}
+		})
+	}
+}
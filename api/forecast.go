@@ -3,25 +3,128 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
+	"sync"
+	"time"
 )
 
 const (
-	// forecastPathTemplate defines the URL path template for fetching forecast data from the Open-Meteo API.
-	forecastPathTemplate = "/v1/forecast?latitude=%f&longitude=%f&current=temperature_2m&daily=temperature_2m_max,temperature_2m_min&temperature_unit=fahrenheit&wind_speed_unit=mph&precipitation_unit=inch"
+	// dateLayout is the layout of the dates in WeeklyForecast.Time.
+	dateLayout = "2006-01-02"
+	// timeLayout is the layout of the local times in WeeklyForecast.Sunrise and WeeklyForecast.Sunset.
+	timeLayout = "2006-01-02T15:04"
+	// forecastPathTemplate defines the URL path template for fetching forecast data from the Open-Meteo API. Days and
+	// hours are requested in the local time zone of the coordinates.
+	forecastPathTemplate = "/v1/forecast?latitude=%f&longitude=%f&current=temperature_2m&hourly=temperature_2m,precipitation_probability&daily=temperature_2m_max,temperature_2m_min,sunrise,sunset,daylight_duration,uv_index_max,precipitation_sum,precipitation_probability_max,rain_sum,snowfall_sum,weather_code&temperature_unit=%s&wind_speed_unit=%s&precipitation_unit=%s&timezone=auto&forecast_days=%d&past_days=%d"
+
+	// DefaultForecastDays is the number of days forecast when ForecastOptions.ForecastDays is not set.
+	DefaultForecastDays = 7
+	// MaxForecastDays is the most days the Open-Meteo API forecasts.
+	MaxForecastDays = 16
+	// MaxPastDays is the most past days the Open-Meteo API returns with a forecast.
+	MaxPastDays = 92
+)
+
+// Units is the unit system forecasts are requested in.
+type Units string
+
+const (
+	// Fahrenheit requests temperatures in Fahrenheit, wind speeds in mph and precipitation in inches.
+	Fahrenheit Units = "fahrenheit"
+	// Celsius requests temperatures in Celsius, wind speeds in km/h and precipitation in millimeters.
+	Celsius Units = "celsius"
 )
 
+// Symbol returns the temperature unit symbol, F or C.
+func (u Units) Symbol() string {
+	if u == Celsius {
+		return "C"
+	}
+	return "F"
+}
+
+// PrecipitationSymbol returns the precipitation unit symbol, in or mm.
+func (u Units) PrecipitationSymbol() string {
+	if u == Celsius {
+		return "mm"
+	}
+	return "in"
+}
+
+// SnowfallSymbol returns the snowfall unit symbol, in or cm.
+func (u Units) SnowfallSymbol() string {
+	if u == Celsius {
+		return "cm"
+	}
+	return "in"
+}
+
+// queryParams returns the temperature, wind speed and precipitation unit parameters for the Open-Meteo API.
+func (u Units) queryParams() (temperature, windSpeed, precipitation string) {
+	if u == Celsius {
+		return "celsius", "kmh", "mm"
+	}
+	return "fahrenheit", "mph", "inch"
+}
+
+// ForecastOptions holds the optional parameters of a forecast request. The zero value requests the defaults.
+type ForecastOptions struct {
+	// Units is the unit system of the forecast. It defaults to Fahrenheit.
+	Units Units
+	// ForecastDays is the number of days forecast, starting today, from 1 to MaxForecastDays. It defaults to
+	// DefaultForecastDays.
+	ForecastDays int
+	// PastDays is the number of days before today included in the forecast, from 0 to MaxPastDays. Their values are
+	// observations and model analysis rather than forecasts.
+	PastDays int
+}
+
+// Days returns the number of forecast days and past days requested, with the defaults applied.
+func (o ForecastOptions) Days() (forecastDays int, pastDays int) {
+	forecastDays = o.ForecastDays
+	if forecastDays == 0 {
+		forecastDays = DefaultForecastDays
+	}
+	return forecastDays, o.PastDays
+}
+
+// Validate reports whether the options are out of the ranges accepted by the Open-Meteo API.
+func (o ForecastOptions) Validate() error {
+	forecastDays, pastDays := o.Days()
+	if forecastDays < 1 || forecastDays > MaxForecastDays {
+		return fmt.Errorf("invalid forecast days %d: must be between 1 and %d", forecastDays, MaxForecastDays)
+	}
+	if pastDays < 0 || pastDays > MaxPastDays {
+		return fmt.Errorf("invalid past days %d: must be between 0 and %d", pastDays, MaxPastDays)
+	}
+	return nil
+}
+
 // forecastResponse holds the forecast response from the API
 type forecastResponse struct {
 	Current struct {
-		// Temperature2M represents the current temp in Fahrenheit
+		// Temperature2M represents the current temp in the requested units
 		Temperature2M float64 `json:"temperature_2m"`
 	} `json:"current"`
+	// Timezone is the IANA name of the time zone of the coordinates, such as America/Chicago
+	Timezone string `json:"timezone"`
+	// UTCOffsetSeconds is the offset of the time zone from UTC at the time of the request
+	UTCOffsetSeconds int `json:"utc_offset_seconds"`
 	// WeeklyForecast contains the daily forecast data for a week
 	WeeklyForecast `json:"daily"`
+	// Hourly contains the hourly forecast data over the same days
+	Hourly *HourlyForecast `json:"hourly"`
+}
+
+// HourlyForecast holds the hourly forecast from the Open-Meteo API, where each index corresponds to the same hour.
+type HourlyForecast struct {
+	// Time holds the local time at the start of each hour, such as 2024-09-19T07:00.
+	Time []string `json:"time"`
+	// Temperature2M holds the temperature of each hour.
+	Temperature2M []float64 `json:"temperature_2m"`
+	// PrecipitationProbability holds the chance of precipitation of each hour, as a percentage.
+	PrecipitationProbability []float64 `json:"precipitation_probability,omitempty"`
 }
 
 // WeeklyForecast holds the daily forecast from the Open-Meteo API.
@@ -33,40 +136,162 @@ type WeeklyForecast struct {
 	Temperature2MMax []float64 `json:"temperature_2m_max"`
 	// Temperature2MMin holds the min temperatures for each day.
 	Temperature2MMin []float64 `json:"temperature_2m_min"`
+	// Sunrise holds the local time of sunrise for each day, such as 2024-09-19T07:15.
+	Sunrise []string `json:"sunrise,omitempty"`
+	// Sunset holds the local time of sunset for each day.
+	Sunset []string `json:"sunset,omitempty"`
+	// DaylightDuration holds the seconds between sunrise and sunset for each day.
+	DaylightDuration []float64 `json:"daylight_duration,omitempty"`
+	// UVIndexMax holds the max UV index for each day.
+	UVIndexMax []float64 `json:"uv_index_max,omitempty"`
+	// PrecipitationSum holds the total rain, showers and snowfall for each day, in inches or millimeters.
+	PrecipitationSum []float64 `json:"precipitation_sum,omitempty"`
+	// PrecipitationProbabilityMax holds the highest chance of precipitation for each day, as a percentage.
+	PrecipitationProbabilityMax []float64 `json:"precipitation_probability_max,omitempty"`
+	// RainSum holds the rain and showers for each day, in inches or millimeters.
+	RainSum []float64 `json:"rain_sum,omitempty"`
+	// SnowfallSum holds the snowfall for each day, in inches or centimeters.
+	SnowfallSum []float64 `json:"snowfall_sum,omitempty"`
+	// WeatherCode holds the WMO weather code of the most severe weather of each day. See WeatherDescription.
+	WeatherCode []int `json:"weather_code,omitempty"`
+	// Timezone is the IANA name of the time zone the days are counted in, such as America/Chicago.
+	Timezone string `json:"timezone,omitempty"`
+	// UTCOffsetSeconds is the offset of Timezone from UTC when the forecast was retrieved.
+	UTCOffsetSeconds int `json:"utc_offset_seconds,omitempty"`
+	// Hourly holds the hourly forecast over the same days, or nil if it was not retrieved, as for history.
+	Hourly *HourlyForecast `json:"hourly,omitempty"`
 }
 
-// GetForecast retrieves the current temperature and weekly forecast for the given latitude and longitude.
-// It requires the base URL of the API server and returns the current temperature, weekly forecast, and an error if any.
-func GetForecast(latitude float64, longitude float64, baseURL string) (float64, WeeklyForecast, error) {
-	// Build the full API request URL
-	path := fmt.Sprintf(forecastPathTemplate, latitude, longitude)
-	fullURL := fmt.Sprintf(baseURL + path)
+// locations caches the time zones loaded by Location, mapped by name, since loading one reads the system time zone
+// database and the days of every forecast are counted in one. Only zones found in the database are cached, so it
+// holds at most one entry per zone there.
+var locations sync.Map
 
-	// Make the HTTP GET request to the API
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return 0, WeeklyForecast{}, fmt.Errorf("error making GET request: %v", err)
+// Location returns the time zone the forecast days are counted in. If Timezone is not in the system time zone
+// database, the fixed UTC offset is used instead. Forecasts without a time zone, which Open-Meteo counts in GMT, are
+// in UTC.
+func (wf WeeklyForecast) Location() *time.Location {
+	if wf.Timezone == "" {
+		return time.FixedZone("GMT", wf.UTCOffsetSeconds)
+	}
+	if loc, ok := locations.Load(wf.Timezone); ok {
+		return loc.(*time.Location)
 	}
-	defer resp.Body.Close()
+	if loc, err := time.LoadLocation(wf.Timezone); err == nil {
+		locations.Store(wf.Timezone, loc)
+		return loc
+	}
+	return time.FixedZone(wf.Timezone, wf.UTCOffsetSeconds)
+}
 
-	// Check the HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		return 0, WeeklyForecast{}, fmt.Errorf("received non-OK HTTP status: %s", resp.Status)
+// DayIndex returns the index of the day that contains t in the forecast's time zone, or -1 if the forecast does not
+// cover that day.
+func (wf WeeklyForecast) DayIndex(t time.Time) int {
+	day := t.In(wf.Location()).Format(dateLayout)
+	for i, d := range wf.Time {
+		if d == day {
+			return i
+		}
 	}
+	return -1
+}
+
+// Dates parses Time into the midnight that starts each day in the forecast's time zone.
+func (wf WeeklyForecast) Dates() ([]time.Time, error) {
+	dates := make([]time.Time, len(wf.Time))
+	for i, day := range wf.Time {
+		date, err := time.ParseInLocation(dateLayout, day, wf.Location())
+		if err != nil {
+			return nil, fmt.Errorf("error parsing forecast date: %v", err)
+		}
+		dates[i] = date
+	}
+	return dates, nil
+}
 
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
+// SunriseAt returns the time of sunrise on the given day in the forecast's time zone. It returns false if the forecast
+// has no sunrise for that day, as in the polar night, or it cannot be parsed.
+func (wf WeeklyForecast) SunriseAt(day int) (time.Time, bool) {
+	return localTime(wf.Sunrise, day, wf.Location())
+}
+
+// SunsetAt returns the time of sunset on the given day in the forecast's time zone. It returns false if the forecast
+// has no sunset for that day, as in the midnight sun, or it cannot be parsed.
+func (wf WeeklyForecast) SunsetAt(day int) (time.Time, bool) {
+	return localTime(wf.Sunset, day, wf.Location())
+}
+
+// Daylight returns the time between sunrise and sunset on the given day. It returns false if the forecast has no
+// daylight duration for that day.
+func (wf WeeklyForecast) Daylight(day int) (time.Duration, bool) {
+	if day < 0 || day >= len(wf.DaylightDuration) {
+		return 0, false
+	}
+	return time.Duration(wf.DaylightDuration[day] * float64(time.Second)).Round(time.Minute), true
+}
+
+// HoursFrom returns up to n hours of the hourly forecast starting with the hour that contains t, with the local time
+// at the start of each hour and its temperature. It returns nothing if the forecast has no hourly data covering t.
+func (wf WeeklyForecast) HoursFrom(t time.Time, n int) ([]time.Time, []float64) {
+	if wf.Hourly == nil {
+		return nil, nil
+	}
+	// The location is loaded once, rather than for every hour
+	hourly, loc := wf.Hourly, wf.Location()
+	var times []time.Time
+	var temperatures []float64
+	for i := 0; i < len(hourly.Time) && i < len(hourly.Temperature2M) && len(times) < n; i++ {
+		start, ok := localTime(hourly.Time, i, loc)
+		if !ok || !start.Add(time.Hour).After(t) {
+			continue
+		}
+		times = append(times, start)
+		temperatures = append(temperatures, hourly.Temperature2M[i])
+	}
+	return times, temperatures
+}
+
+// localTime parses the value at index i of times, as sent by the forecast API, as a local time in loc.
+func localTime(times []string, i int, loc *time.Location) (time.Time, bool) {
+	if i < 0 || i >= len(times) || times[i] == "" {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(timeLayout, times[i], loc)
 	if err != nil {
-		return 0, WeeklyForecast{}, fmt.Errorf("error reading response body: %v", err)
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// GetForecast retrieves the current temperature and weekly forecast for the given latitude and longitude.
+// It requires the base URL of the API server and returns the current temperature, weekly forecast, and an error if any.
+// Temperatures are in Fahrenheit.
+func GetForecast(latitude float64, longitude float64, baseURL string) (float64, WeeklyForecast, error) {
+	return GetForecastContext(context.Background(), latitude, longitude, baseURL, ForecastOptions{})
+}
+
+// GetForecastContext is like GetForecast but carries ctx, which cancels the request and supplies the request ID logged
+// with it, and accepts options such as the unit system.
+func GetForecastContext(ctx context.Context, latitude float64, longitude float64, baseURL string, opts ForecastOptions) (float64, WeeklyForecast, error) {
+	if err := opts.Validate(); err != nil {
+		return 0, WeeklyForecast{}, err
 	}
 
-	// Unmarshal the JSON data into the forecast struct
+	// Build the full API request URL
+	temperatureUnit, windSpeedUnit, precipitationUnit := opts.Units.queryParams()
+	forecastDays, pastDays := opts.Days()
+	path := fmt.Sprintf(forecastPathTemplate, latitude, longitude, temperatureUnit, windSpeedUnit, precipitationUnit, forecastDays, pastDays)
+	fullURL := fmt.Sprintf(baseURL + path)
+
+	// Make the request and unmarshal the JSON data into the forecast struct
 	forecast := forecastResponse{}
-	err = json.Unmarshal(body, &forecast)
-	if err != nil {
-		return 0, WeeklyForecast{}, fmt.Errorf("error unmarshalling response body: %v", err)
+	if err := getJSON(ctx, providerForecast, fullURL, &forecast); err != nil {
+		return 0, WeeklyForecast{}, err
 	}
 
-	// Return the current temperature and weekly forecast
+	// Return the current temperature and weekly forecast, whose days are counted in the time zone of the coordinates
+	forecast.WeeklyForecast.Timezone = forecast.Timezone
+	forecast.WeeklyForecast.UTCOffsetSeconds = forecast.UTCOffsetSeconds
+	forecast.WeeklyForecast.Hourly = forecast.Hourly
 	return forecast.Current.Temperature2M, forecast.WeeklyForecast, nil
 }
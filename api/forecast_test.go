@@ -1,9 +1,11 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func Test_GetForecast(t *testing.T) {
@@ -112,3 +114,266 @@ func Test_GetForecast(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetForecastContext_Units(t *testing.T) {
+	tc := []struct {
+		name              string
+		units             Units
+		temperatureUnit   string
+		windSpeedUnit     string
+		precipitationUnit string
+		symbol            string
+	}{
+		{
+			name:              "Default Fahrenheit",
+			temperatureUnit:   "fahrenheit",
+			windSpeedUnit:     "mph",
+			precipitationUnit: "inch",
+			symbol:            "F",
+		},
+		{
+			name:              "Celsius",
+			units:             Celsius,
+			temperatureUnit:   "celsius",
+			windSpeedUnit:     "kmh",
+			precipitationUnit: "mm",
+			symbol:            "C",
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				if query.Get("temperature_unit") != tc.temperatureUnit {
+					t.Errorf("Expected temperature_unit '%s', got %s", tc.temperatureUnit, query.Get("temperature_unit"))
+				}
+				if query.Get("wind_speed_unit") != tc.windSpeedUnit {
+					t.Errorf("Expected wind_speed_unit '%s', got %s", tc.windSpeedUnit, query.Get("wind_speed_unit"))
+				}
+				if query.Get("precipitation_unit") != tc.precipitationUnit {
+					t.Errorf("Expected precipitation_unit '%s', got %s", tc.precipitationUnit, query.Get("precipitation_unit"))
+				}
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			if _, _, err := GetForecastContext(context.Background(), 0, 0, server.URL, ForecastOptions{Units: tc.units}); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tc.units.Symbol() != tc.symbol {
+				t.Errorf("Expected symbol '%s', got %s", tc.symbol, tc.units.Symbol())
+			}
+		})
+	}
+}
+
+func Test_WeeklyForecast_Dates(t *testing.T) {
+	dates, err := WeeklyForecast{Time: []string{"2024-09-19", "2024-09-20"}}.Dates()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(dates) != 2 || !dates[1].Equal(time.Date(2024, time.September, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 2024-09-19 and 2024-09-20 at midnight UTC, got %v", dates)
+	}
+
+	if _, err := (WeeklyForecast{Time: []string{"19/09/2024"}}).Dates(); err == nil {
+		t.Error("Expected an error parsing an invalid date, got nil")
+	}
+}
+
+func Test_GetForecastContext_Timezone(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tz := r.URL.Query().Get("timezone"); tz != "auto" {
+			t.Errorf("Expected timezone 'auto', got %s", tz)
+		}
+		w.Write([]byte(`{"timezone": "America/Chicago", "utc_offset_seconds": -18000,
+			"daily": {"time": ["2024-09-19", "2024-09-20"], "temperature_2m_max": [97.6, 96.1], "temperature_2m_min": [75.8, 74.2]}}`))
+	}))
+	defer server.Close()
+
+	_, weeklyForecast, err := GetForecastContext(context.Background(), 30.3985991, -97.7220666, server.URL, ForecastOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if weeklyForecast.Timezone != "America/Chicago" || weeklyForecast.UTCOffsetSeconds != -18000 {
+		t.Errorf("Expected America/Chicago at -18000 seconds, got %s at %d", weeklyForecast.Timezone, weeklyForecast.UTCOffsetSeconds)
+	}
+}
+
+func Test_WeeklyForecast_Location(t *testing.T) {
+	tc := []struct {
+		name     string
+		forecast WeeklyForecast
+		expected string
+		offset   int
+		cached   bool
+	}{
+		{name: "GMT", forecast: WeeklyForecast{}, expected: "GMT"},
+		{name: "Known Zone", forecast: WeeklyForecast{Timezone: "America/Chicago"}, expected: "America/Chicago", cached: true},
+		{name: "Unknown Zone", forecast: WeeklyForecast{Timezone: "Unknown/Zone", UTCOffsetSeconds: -18000}, expected: "Unknown/Zone", offset: -18000},
+	}
+
+	at := time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC)
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			loc := tc.forecast.Location()
+			if loc.String() != tc.expected {
+				t.Errorf("Expected location %s, got %s", tc.expected, loc)
+			}
+			if _, offset := at.In(loc).Zone(); !tc.cached && offset != tc.offset {
+				t.Errorf("Expected offset %d, got %d", tc.offset, offset)
+			}
+			// A zone from the database is loaded once and shared by every forecast in it
+			if again := tc.forecast.Location(); (again == loc) != tc.cached {
+				t.Errorf("Expected the location to be cached: %t", tc.cached)
+			}
+		})
+	}
+}
+
+func Test_WeeklyForecast_DayIndex(t *testing.T) {
+	tc := []struct {
+		name     string
+		forecast WeeklyForecast
+		t        time.Time
+		expected int
+	}{
+		{
+			name:     "GMT",
+			forecast: WeeklyForecast{Time: []string{"2024-09-19", "2024-09-20"}},
+			t:        time.Date(2024, time.September, 20, 3, 0, 0, 0, time.UTC),
+			expected: 1,
+		},
+		{
+			name:     "Local Day Behind UTC",
+			forecast: WeeklyForecast{Time: []string{"2024-09-19", "2024-09-20"}, Timezone: "Unknown/Zone", UTCOffsetSeconds: -5 * 60 * 60},
+			t:        time.Date(2024, time.September, 20, 3, 0, 0, 0, time.UTC),
+			expected: 0,
+		},
+		{
+			name:     "Not Covered",
+			forecast: WeeklyForecast{Time: []string{"2024-09-19", "2024-09-20"}},
+			t:        time.Date(2024, time.September, 22, 12, 0, 0, 0, time.UTC),
+			expected: -1,
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.forecast.DayIndex(tc.t); got != tc.expected {
+				t.Errorf("Expected day %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func Test_WeeklyForecast_Daylight(t *testing.T) {
+	weeklyForecast := WeeklyForecast{
+		Time:             []string{"2024-09-19", "2024-09-20"},
+		Sunrise:          []string{"2024-09-19T07:15", "bad"},
+		Sunset:           []string{"2024-09-19T19:32"},
+		DaylightDuration: []float64{44220.5},
+		Timezone:         "Unknown/Zone",
+		UTCOffsetSeconds: -5 * 60 * 60,
+	}
+
+	sunrise, ok := weeklyForecast.SunriseAt(0)
+	if !ok || !sunrise.Equal(time.Date(2024, time.September, 19, 12, 15, 0, 0, time.UTC)) {
+		t.Errorf("Expected sunrise at 07:15 local time, got %v, %t", sunrise, ok)
+	}
+	if sunset, ok := weeklyForecast.SunsetAt(0); !ok || sunset.Hour() != 19 || sunset.Minute() != 32 {
+		t.Errorf("Expected sunset at 19:32 local time, got %v, %t", sunset, ok)
+	}
+	if daylight, ok := weeklyForecast.Daylight(0); !ok || daylight != 12*time.Hour+17*time.Minute {
+		t.Errorf("Expected 12h17m of daylight, got %v, %t", daylight, ok)
+	}
+
+	if _, ok := weeklyForecast.SunriseAt(1); ok {
+		t.Error("Expected no sunrise for an unparseable time")
+	}
+	if _, ok := weeklyForecast.SunsetAt(1); ok {
+		t.Error("Expected no sunset for a missing day")
+	}
+	if _, ok := weeklyForecast.Daylight(1); ok {
+		t.Error("Expected no daylight for a missing day")
+	}
+}
+
+func Test_GetForecastContext_Days(t *testing.T) {
+	tc := []struct {
+		name         string
+		opts         ForecastOptions
+		forecastDays string
+		pastDays     string
+		err          string
+	}{
+		{name: "Defaults", forecastDays: "7", pastDays: "0"},
+		{name: "Horizon And Past Days", opts: ForecastOptions{ForecastDays: 16, PastDays: 3}, forecastDays: "16", pastDays: "3"},
+		{name: "Too Many Forecast Days", opts: ForecastOptions{ForecastDays: 17}, err: "invalid forecast days 17: must be between 1 and 16"},
+		{name: "Negative Past Days", opts: ForecastOptions{PastDays: -1}, err: "invalid past days -1: must be between 0 and 92"},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				query := r.URL.Query()
+				if query.Get("forecast_days") != tc.forecastDays {
+					t.Errorf("Expected forecast_days '%s', got %s", tc.forecastDays, query.Get("forecast_days"))
+				}
+				if query.Get("past_days") != tc.pastDays {
+					t.Errorf("Expected past_days '%s', got %s", tc.pastDays, query.Get("past_days"))
+				}
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			_, _, err := GetForecastContext(context.Background(), 0, 0, server.URL, tc.opts)
+			// Check for error cases
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("Expected error '%s', got %v", tc.err, err)
+				}
+				if requests != 0 {
+					t.Errorf("Expected no request for invalid options, got %d", requests)
+				}
+				return
+			}
+
+			// Check for success cases
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_WeeklyForecast_HoursFrom(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hourly := r.URL.Query().Get("hourly"); hourly != "temperature_2m,precipitation_probability" {
+			t.Errorf("Expected hourly 'temperature_2m,precipitation_probability', got %s", hourly)
+		}
+		w.Write([]byte(`{"timezone": "America/Chicago", "utc_offset_seconds": -18000,
+			"hourly": {"time": ["2024-09-19T13:00", "2024-09-19T14:00", "2024-09-19T15:00", "2024-09-19T16:00"],
+				"temperature_2m": [91.2, 93.5, 95.0, 96.1], "precipitation_probability": [0, 5, 10, 10]},
+			"daily": {"time": ["2024-09-19"], "temperature_2m_max": [97.6], "temperature_2m_min": [75.8]}}`))
+	}))
+	defer server.Close()
+
+	_, weeklyForecast, err := GetForecastContext(context.Background(), 30.3985991, -97.7220666, server.URL, ForecastOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 19:30 UTC is 14:30 in Austin, so the hours start at 14:00
+	times, temperatures := weeklyForecast.HoursFrom(time.Date(2024, time.September, 19, 19, 30, 0, 0, time.UTC), 2)
+	if len(times) != 2 || times[0].Hour() != 14 || temperatures[0] != 93.5 || temperatures[1] != 95.0 {
+		t.Errorf("Expected 2 hours from 14:00 at 93.5 and 95.0, got %v, %v", times, temperatures)
+	}
+
+	if times, _ := (WeeklyForecast{}).HoursFrom(time.Now(), 24); times != nil {
+		t.Errorf("Expected no hours without hourly data, got %v", times)
+	}
+}
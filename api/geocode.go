@@ -2,10 +2,8 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 )
 
@@ -46,37 +44,25 @@ type geocodeLocation struct {
 // It returns the full formatted address, latitude, longitude, and an error if any.
 // If the address is not found or an error occurs, it returns zero values and the error.
 func AddressToCoordinates(address string, baseURL string, apiKey string) (fullAddress string, latitude, longitude float64, err error) {
+	return AddressToCoordinatesContext(context.Background(), address, baseURL, apiKey)
+}
+
+// AddressToCoordinatesContext is like AddressToCoordinates but carries ctx, which cancels the request and supplies
+// the request ID logged with it.
+func AddressToCoordinatesContext(ctx context.Context, address string, baseURL string, apiKey string) (fullAddress string, latitude, longitude float64, err error) {
 	// Build the full API request URL
 	path := fmt.Sprintf(geocodePathTemplate, url.QueryEscape(address), apiKey)
 	fullURL := fmt.Sprintf(baseURL + path)
 
-	// Make the HTTP GET request to the API
-	resp, err := http.Get(fullURL)
-	if err != nil {
-		return "", 0.0, 0.0, fmt.Errorf("error making GET request: %v", err)
-	}
-	defer resp.Body.Close()
-
-	// Check the HTTP status code
-	if resp.StatusCode != http.StatusOK {
-		return "", 0.0, 0.0, fmt.Errorf("received non-OK HTTP status: %s", resp.Status)
-	}
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", 0.0, 0.0, fmt.Errorf("error reading response body: %v", err)
-	}
-
-	// Unmarshal the JSON data into the geocodeResponse struct
+	// Make the request and unmarshal the JSON data into the geocodeResponse struct
 	var googleRes geocodeResponse
-	err = json.Unmarshal(body, &googleRes)
-	if err != nil {
-		return "", 0.0, 0.0, fmt.Errorf("error unmarshalling response body: %v", err)
+	if err := getJSON(ctx, providerGeocode, fullURL, &googleRes); err != nil {
+		return "", 0.0, 0.0, err
 	}
 
 	// Check if any results were returned
 	if len(googleRes.Results) == 0 {
+		upstreamErrors.Inc(providerGeocode, "no_results")
 		return "", 0.0, 0.0, fmt.Errorf("no results found for address: %s", address)
 	}
 
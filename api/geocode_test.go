@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 )
 
@@ -186,3 +187,20 @@ func Test_AddressToCoordinates(t *testing.T) {
 		})
 	}
 }
+
+func Test_AddressToCoordinates_RedactsAPIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	// Close the server so the request fails with an error that embeds the request URL
+	server.Close()
+
+	_, _, _, err := AddressToCoordinates("test", server.URL, "TestAPIKey")
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+	if strings.Contains(err.Error(), "TestAPIKey") {
+		t.Errorf("Expected the API key to be redacted, got %s", err.Error())
+	}
+	if !strings.Contains(err.Error(), "key=REDACTED") {
+		t.Errorf("Expected key=REDACTED in the error, got %s", err.Error())
+	}
+}
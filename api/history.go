@@ -0,0 +1,39 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// historyPathTemplate defines the URL path template for fetching historical daily weather from the Open-Meteo
+	// archive API. Days are requested in the local time zone of the coordinates.
+	historyPathTemplate = "/v1/archive?latitude=%f&longitude=%f&start_date=%s&end_date=%s&daily=temperature_2m_max,temperature_2m_min,sunrise,sunset,daylight_duration,precipitation_sum,rain_sum,snowfall_sum,weather_code&temperature_unit=%s&wind_speed_unit=%s&precipitation_unit=%s&timezone=auto"
+)
+
+// GetHistoryContext retrieves the observed daily weather for the given latitude and longitude from the from date to
+// the to date, both inclusive, from the archive API at baseURL. The days are decoded like a forecast, without the
+// current temperature, precipitation probability and UV index. Only the unit system of opts is used.
+func GetHistoryContext(ctx context.Context, latitude float64, longitude float64, baseURL string, from time.Time, to time.Time, opts ForecastOptions) (WeeklyForecast, error) {
+	if to.Before(from) {
+		return WeeklyForecast{}, errors.New("invalid date range: the end date is before the start date")
+	}
+
+	// Build the full API request URL
+	temperatureUnit, windSpeedUnit, precipitationUnit := opts.Units.queryParams()
+	path := fmt.Sprintf(historyPathTemplate, latitude, longitude, from.Format(dateLayout), to.Format(dateLayout),
+		temperatureUnit, windSpeedUnit, precipitationUnit)
+	fullURL := baseURL + path
+
+	// Make the request and unmarshal the JSON data into the same struct as a forecast
+	history := forecastResponse{}
+	if err := getJSON(ctx, providerArchive, fullURL, &history); err != nil {
+		return WeeklyForecast{}, err
+	}
+
+	history.WeeklyForecast.Timezone = history.Timezone
+	history.WeeklyForecast.UTCOffsetSeconds = history.UTCOffsetSeconds
+	return history.WeeklyForecast, nil
+}
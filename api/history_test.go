@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_GetHistoryContext(t *testing.T) {
+	from := time.Date(2024, time.September, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, time.September, 2, 0, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		name         string
+		status       int
+		mockResponse string
+		from         time.Time
+		to           time.Time
+		maxTemps     []float64
+		error        string
+	}{
+		{
+			name:   "Success Case",
+			status: http.StatusOK,
+			mockResponse: `{"timezone": "America/Chicago", "utc_offset_seconds": -18000,
+				"daily": {"time": ["2024-09-01", "2024-09-02"], "temperature_2m_max": [99.1, 97.3], "temperature_2m_min": [76.3, 75.0],
+				"precipitation_sum": [0, 0.42], "weather_code": [1, 63]}}`,
+			from:     from,
+			to:       to,
+			maxTemps: []float64{99.1, 97.3},
+		},
+		{
+			name:  "Error - End Before Start",
+			from:  to,
+			to:    from,
+			error: "invalid date range: the end date is before the start date",
+		},
+		{
+			name:         "Error - Non-OK Status",
+			status:       http.StatusBadRequest,
+			mockResponse: `{"error": true, "reason": "Parameter 'start_date' is out of allowed range"}`,
+			from:         from,
+			to:           to,
+			error:        "received non-OK HTTP status: 400 Bad Request",
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/archive" {
+					t.Errorf("Expected path /v1/archive, got %s", r.URL.Path)
+				}
+				query := r.URL.Query()
+				if query.Get("start_date") != "2024-09-01" || query.Get("end_date") != "2024-09-02" {
+					t.Errorf("Expected 2024-09-01 to 2024-09-02, got %s to %s", query.Get("start_date"), query.Get("end_date"))
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.mockResponse))
+			}))
+			defer server.Close()
+
+			history, err := GetHistoryContext(context.Background(), 30.3985991, -97.7220666, server.URL, tc.from, tc.to, ForecastOptions{})
+			// Check for error cases
+			if tc.error != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.error) {
+					t.Errorf("Expected error '%s', got %v", tc.error, err)
+				}
+				return
+			}
+
+			// Check for success cases
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(history.Temperature2MMax) != len(tc.maxTemps) || history.Temperature2MMax[1] != tc.maxTemps[1] {
+				t.Errorf("Expected max temps %v, got %v", tc.maxTemps, history.Temperature2MMax)
+			}
+			if history.Timezone != "America/Chicago" || history.WeatherCode[1] != 63 {
+				t.Errorf("Expected the time zone and weather codes to be decoded, got %+v", history)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mfryhover/weather/logging"
+	"github.com/mfryhover/weather/metrics"
+)
+
+const (
+	// providerGeocode labels metrics for requests made to the Google Geocode API.
+	providerGeocode = "geocode"
+	// providerForecast labels metrics for requests made to the Open-Meteo forecast API.
+	providerForecast = "forecast"
+	// providerArchive labels metrics for requests made to the Open-Meteo historical weather API.
+	providerArchive = "archive"
+	// providerAirQuality labels metrics for requests made to the Open-Meteo air quality API.
+	providerAirQuality = "air_quality"
+)
+
+var (
+	// upstreamRequests counts completed upstream requests by provider and HTTP status code.
+	upstreamRequests = metrics.Default.NewCounterVec("weather_upstream_requests_total",
+		"Upstream API requests that received a response, by provider and HTTP status code.", "provider", "code")
+	// upstreamDuration observes upstream request latency by provider.
+	upstreamDuration = metrics.Default.NewHistogramVec("weather_upstream_request_duration_seconds",
+		"Upstream API request latency in seconds, by provider.", metrics.DefaultBuckets, "provider")
+	// upstreamErrors counts failed upstream requests by provider and error type.
+	upstreamErrors = metrics.Default.NewCounterVec("weather_upstream_errors_total",
+		"Upstream API errors, by provider and error type.", "provider", "type")
+	// upstreamInFlight tracks upstream requests that have not completed yet, by provider.
+	upstreamInFlight = metrics.Default.NewGaugeVec("weather_upstream_requests_in_flight",
+		"Upstream API requests currently in flight, by provider.", "provider")
+)
+
+// getJSON makes an HTTP GET request to fullURL and unmarshals the JSON response body into v.
+// It records request count, latency, errors and in-flight requests for the given provider and logs the request
+// with the request ID carried by ctx. Credentials in fullURL never appear in the returned error.
+func getJSON(ctx context.Context, provider string, fullURL string, v any) error {
+	upstreamInFlight.Inc(provider)
+	defer upstreamInFlight.Dec(provider)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		upstreamErrors.Inc(provider, "request")
+		return fmt.Errorf("error creating GET request: %v", logging.RedactSecrets(err.Error()))
+	}
+
+	// Make the HTTP GET request to the API
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	latency := time.Since(start)
+	upstreamDuration.Observe(latency.Seconds(), provider)
+	if err != nil {
+		// url.Error embeds the request URL, which includes the API key for the geocode provider
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = logging.RedactSecrets(urlErr.URL)
+		}
+		upstreamErrors.Inc(provider, "request")
+		slog.WarnContext(ctx, "upstream request failed", "provider", provider, "url", fullURL, "latency", latency, "error", err)
+		return fmt.Errorf("error making GET request: %v", err)
+	}
+	defer resp.Body.Close()
+	upstreamRequests.Inc(provider, fmt.Sprint(resp.StatusCode))
+	slog.InfoContext(ctx, "upstream request", "provider", provider, "url", fullURL, "status", resp.StatusCode, "latency", latency)
+
+	// Check the HTTP status code
+	if resp.StatusCode != http.StatusOK {
+		upstreamErrors.Inc(provider, "status")
+		slog.WarnContext(ctx, "upstream returned non-OK status", "provider", provider, "status", resp.StatusCode)
+		return fmt.Errorf("received non-OK HTTP status: %s", resp.Status)
+	}
+
+	// Read the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		upstreamErrors.Inc(provider, "read")
+		slog.WarnContext(ctx, "error reading upstream response", "provider", provider, "error", err)
+		return fmt.Errorf("error reading response body: %v", err)
+	}
+
+	// Unmarshal the JSON data into v
+	if err := json.Unmarshal(body, v); err != nil {
+		upstreamErrors.Inc(provider, "decode")
+		slog.WarnContext(ctx, "error decoding upstream response", "provider", provider, "error", err)
+		return fmt.Errorf("error unmarshalling response body: %v", err)
+	}
+
+	return nil
+}
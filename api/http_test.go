@@ -0,0 +1,44 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_getJSON_Metrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.Write([]byte(`}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	requests := upstreamRequests.Value("test", "200")
+	decodeErrors := upstreamErrors.Value("test", "decode")
+	observations := upstreamDuration.Count("test")
+
+	var v struct{}
+	if err := getJSON(context.Background(), "test", server.URL+"/ok", &v); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := getJSON(context.Background(), "test", server.URL+"/bad", &v); err == nil {
+		t.Error("Expected an error, got nil")
+	}
+
+	if got := upstreamRequests.Value("test", "200") - requests; got != 2 {
+		t.Errorf("Expected 2 requests to be counted, got %f", got)
+	}
+	if got := upstreamErrors.Value("test", "decode") - decodeErrors; got != 1 {
+		t.Errorf("Expected 1 decode error to be counted, got %f", got)
+	}
+	if got := upstreamDuration.Count("test") - observations; got != 2 {
+		t.Errorf("Expected 2 latency observations, got %d", got)
+	}
+	if got := upstreamInFlight.Value("test"); got != 0 {
+		t.Errorf("Expected no requests in flight, got %f", got)
+	}
+}
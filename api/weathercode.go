@@ -0,0 +1,42 @@
+package api
+
+// weatherCodes maps the WMO weather interpretation codes returned by Open-Meteo to short descriptions.
+var weatherCodes = map[int]string{
+	0:  "Clear sky",
+	1:  "Mainly clear",
+	2:  "Partly cloudy",
+	3:  "Overcast",
+	45: "Fog",
+	48: "Freezing fog",
+	51: "Light drizzle",
+	53: "Drizzle",
+	55: "Heavy drizzle",
+	56: "Light freezing drizzle",
+	57: "Freezing drizzle",
+	61: "Light rain",
+	63: "Rain",
+	65: "Heavy rain",
+	66: "Light freezing rain",
+	67: "Freezing rain",
+	71: "Light snow",
+	73: "Snow",
+	75: "Heavy snow",
+	77: "Snow grains",
+	80: "Light rain showers",
+	81: "Rain showers",
+	82: "Violent rain showers",
+	85: "Light snow showers",
+	86: "Snow showers",
+	95: "Thunderstorm",
+	96: "Thunderstorm with hail",
+	99: "Thunderstorm with heavy hail",
+}
+
+// WeatherDescription returns a short description of a WMO weather code, such as "Rain showers", or "Unknown" for
+// codes outside the table.
+func WeatherDescription(code int) string {
+	if description, ok := weatherCodes[code]; ok {
+		return description
+	}
+	return "Unknown"
+}
@@ -0,0 +1,22 @@
+package api
+
+import "testing"
+
+func Test_WeatherDescription(t *testing.T) {
+	tc := []struct {
+		code     int
+		expected string
+	}{
+		{code: 0, expected: "Clear sky"},
+		{code: 63, expected: "Rain"},
+		{code: 81, expected: "Rain showers"},
+		{code: 96, expected: "Thunderstorm with hail"},
+		{code: 42, expected: "Unknown"},
+	}
+
+	for _, tc := range tc {
+		if got := WeatherDescription(tc.code); got != tc.expected {
+			t.Errorf("Expected %q for code %d, got %q", tc.expected, tc.code, got)
+		}
+	}
+}
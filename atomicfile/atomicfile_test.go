@@ -0,0 +1,58 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAtomicFile_WriteFile(t *testing.T) {
+	tc := []struct {
+		name     string
+		existing string
+		perm     os.FileMode
+	}{
+		{name: "New File In New Directory", perm: 0o644},
+		{name: "Replaced File", existing: "previous", perm: 0o600},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "weather")
+			path := filepath.Join(dir, "feed.ics")
+			if tc.existing != "" {
+				os.MkdirAll(dir, 0o755)
+				os.WriteFile(path, []byte(tc.existing), 0o644)
+			}
+
+			if err := WriteFile(path, []byte("current"), tc.perm); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil || string(data) != "current" {
+				t.Errorf("Expected the file to hold %q, got %q (%v)", "current", data, err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if info.Mode().Perm() != tc.perm {
+				t.Errorf("Expected permissions %v, got %v", tc.perm, info.Mode().Perm())
+			}
+			// The temporary file is renamed, so only the file itself is left in the directory
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("Expected 1 file in the directory, got %d", len(entries))
+			}
+		})
+	}
+}
+
+func TestAtomicFile_WriteFileError(t *testing.T) {
+	// A path under a regular file cannot be created
+	parent := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(parent, nil, 0o644)
+
+	if err := WriteFile(filepath.Join(parent, "feed.ics"), []byte("current"), 0o644); err == nil {
+		t.Error("Expected an error writing under a file, got nil")
+	}
+}
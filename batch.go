@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/logging"
+)
+
+// batchInput is a single row read from a batch input.
+type batchInput struct {
+	// ID identifies the row: the id column of a CSV input, or the line number otherwise.
+	ID string
+	// Address is the address to forecast.
+	Address string
+}
+
+// batchDay is the forecast for a single day of a batch record.
+type batchDay struct {
+	// Date is the day the forecast applies to.
+	Date string `json:"date"`
+	// Max is the high temperature.
+	Max float64 `json:"max"`
+	// Min is the low temperature.
+	Min float64 `json:"min"`
+	// Observed marks the days before today, requested with past days, which hold observations rather than forecasts.
+	Observed bool `json:"observed,omitempty"`
+}
+
+// newBatchDays returns the days of a forecast, with the days before the day that contains now marked as observed.
+func newBatchDays(weeklyForecast api.WeeklyForecast, now time.Time) []batchDay {
+	days := []batchDay{}
+	today := weeklyForecast.DayIndex(now)
+	for dayIndex := range weeklyForecast.Time {
+		if dayIndex >= len(weeklyForecast.Temperature2MMax) || dayIndex >= len(weeklyForecast.Temperature2MMin) {
+			break
+		}
+		days = append(days, batchDay{
+			Date:     weeklyForecast.Time[dayIndex],
+			Max:      weeklyForecast.Temperature2MMax[dayIndex],
+			Min:      weeklyForecast.Temperature2MMin[dayIndex],
+			Observed: dayIndex < today,
+		})
+	}
+	return days
+}
+
+// batchRecord is the result for a single batch input row. Error is set instead of the forecast fields if the
+// row failed.
+type batchRecord struct {
+	// ID identifies the input row.
+	ID string `json:"id"`
+	// Input is the address as it was read.
+	Input string `json:"input"`
+	// Address is the full formatted address.
+	Address string `json:"address,omitempty"`
+	// CurrentTemp is the current temperature.
+	CurrentTemp float64 `json:"current_temp"`
+	// Units is the unit system of the temperatures.
+	Units api.Units `json:"units"`
+	// Daily holds the forecast for each day.
+	Daily []batchDay `json:"daily,omitempty"`
+	// FromCache indicates if the forecast was retrieved from the cache.
+	FromCache bool `json:"from_cache"`
+	// AirQuality is the current air quality, if requested and available.
+	AirQuality *airQualityRecord `json:"air_quality,omitempty"`
+	// Error describes why the row failed.
+	Error string `json:"error,omitempty"`
+
+	// today is the index of the current day at the location in Daily, or -1 if the forecast does not cover it.
+	today int
+}
+
+// readBatchInput reads batch rows from r. If the first line is a CSV header, naming an id or an address column, the
+// input is read as CSV, and the header must name an address column; rows are identified by the id column, or by their
+// line number without one. Otherwise every non-blank line is an address, identified by its line number.
+func readBatchInput(r io.Reader) ([]batchInput, error) {
+	br := bufio.NewReader(r)
+	firstLine, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error reading input: %v", err)
+	}
+
+	// Detect a CSV header from the columns it names
+	header, headerErr := csv.NewReader(strings.NewReader(firstLine)).Read()
+	idColumn, addressColumn := -1, -1
+	if headerErr == nil {
+		for i, name := range header {
+			switch strings.ToLower(strings.TrimSpace(name)) {
+			case "id":
+				idColumn = i
+			case "address":
+				addressColumn = i
+			}
+		}
+	}
+	if idColumn >= 0 || addressColumn >= 0 {
+		if addressColumn < 0 {
+			return nil, errors.New("invalid CSV header: missing address column")
+		}
+		return readBatchCSV(br, idColumn, addressColumn)
+	}
+
+	var inputs []batchInput
+	scanner := bufio.NewScanner(io.MultiReader(strings.NewReader(firstLine), br))
+	for line := 1; scanner.Scan(); line++ {
+		if address := strings.TrimSpace(scanner.Text()); address != "" {
+			inputs = append(inputs, batchInput{ID: strconv.Itoa(line), Address: address})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading input: %v", err)
+	}
+	return inputs, nil
+}
+
+// readBatchCSV reads the CSV records following the header, taking the id and address from the given columns. If
+// idColumn is -1, records are identified by their line number, counting the header as line 1.
+func readBatchCSV(r io.Reader, idColumn int, addressColumn int) ([]batchInput, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var inputs []batchInput
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return inputs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading CSV input: %v", err)
+		}
+
+		var input batchInput
+		if idColumn < 0 {
+			line, _ := cr.FieldPos(0)
+			input.ID = strconv.Itoa(line + 1)
+		} else if idColumn < len(record) {
+			input.ID = strings.TrimSpace(record[idColumn])
+		}
+		if addressColumn < len(record) {
+			input.Address = strings.TrimSpace(record[addressColumn])
+		}
+		if input.ID == "" && input.Address == "" {
+			continue
+		}
+		inputs = append(inputs, input)
+	}
+}
+
+// forecastBatch resolves every input through the geocode API and the shared forecast cache, running at most parallel
+// lookups at a time. If airQualityURL is set, the current air quality of every input is retrieved too. It returns one
+// record per input, in input order; failed rows carry an error instead of a forecast.
+func forecastBatch(inputs []batchInput, parallel int, c *cache.Cache, geocodeURL string, forecastURL string, airQualityURL string, apiKey string, opts api.ForecastOptions) []batchRecord {
+	records := make([]batchRecord, len(inputs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, input := range inputs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, input batchInput) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			record := batchRecord{ID: input.ID, Input: input.Address, Units: opts.Units}
+			if record.Units == "" {
+				record.Units = api.Fahrenheit
+			}
+			if input.Address == "" {
+				record.Error = "address is empty"
+				records[i] = record
+				return
+			}
+
+			ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+			addressFull, lat, lng, err := api.AddressToCoordinatesContext(ctx, input.Address, geocodeURL, apiKey)
+			if err != nil || addressFull == "" || lat == 0 || lng == 0 {
+				record.Error = fmt.Sprintf("error retrieving coordinates: %v", err)
+				records[i] = record
+				return
+			}
+			currentTemp, weeklyForecast, isFromCache, err := getForecastForCoordinates(ctx, addressFull, lat, lng, c, forecastURL, opts)
+			if err != nil {
+				record.Error = err.Error()
+				records[i] = record
+				return
+			}
+			// Air quality is optional, so a row without it is still a success
+			if airQualityURL != "" {
+				if airQuality, err := api.GetAirQualityContext(ctx, lat, lng, airQualityURL); err != nil {
+					slog.WarnContext(ctx, "air quality unavailable", "error", err)
+				} else {
+					record.AirQuality = newAirQualityRecord(airQuality)
+				}
+			}
+
+			record.Address = addressFull
+			record.CurrentTemp = currentTemp
+			record.FromCache = isFromCache
+			now := time.Now()
+			record.Daily = newBatchDays(weeklyForecast, now)
+			record.today = weeklyForecast.DayIndex(now)
+			records[i] = record
+		}(i, input)
+	}
+	wg.Wait()
+
+	return records
+}
+
+// writeBatchJSON writes one JSON object per record, one per line.
+func writeBatchJSON(w io.Writer, records []batchRecord) error {
+	enc := json.NewEncoder(w)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeBatchCSV writes a header followed by one CSV row per record, with today's high and low at the location. They
+// are left empty if the forecast does not cover today.
+func writeBatchCSV(w io.Writer, records []batchRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "input", "address", "current_temp", "today_max", "today_min", "units", "from_cache", "error"}); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		row := []string{record.ID, record.Input, record.Address, "", "", "", string(record.Units), strconv.FormatBool(record.FromCache), record.Error}
+		if record.Error == "" {
+			row[3] = strconv.FormatFloat(record.CurrentTemp, 'f', 1, 64)
+			if record.today >= 0 && record.today < len(record.Daily) {
+				row[4] = strconv.FormatFloat(record.Daily[record.today].Max, 'f', 1, 64)
+				row[5] = strconv.FormatFloat(record.Daily[record.today].Min, 'f', 1, 64)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// runBatch runs the batch command, which forecasts every address read from a file, or stdin if the file is omitted
+// or "-", and writes one JSON or CSV record per input. Failed rows are reported in their record and do not stop the
+// batch; an error is returned at the end if any row failed.
+func runBatch(args []string, cfg config.Config, c *cache.Cache, stdin io.Reader, stdout io.Writer) error {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	format := flags.String("format", "json", "output format: json (one object per line) or csv")
+	parallel := flags.Int("parallel", 4, "maximum number of addresses resolved at the same time")
+	output := flags.String("o", "", "file to write the records to instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("invalid format %q: must be json or csv", *format)
+	}
+	if *parallel < 1 {
+		return fmt.Errorf("invalid parallel %d: must be at least 1", *parallel)
+	}
+	if flags.NArg() > 1 {
+		return errors.New("usage: weather batch [-format json|csv] [-parallel n] [-o file] [file]")
+	}
+	if cfg.Providers.GeocodeAPIKey == "" {
+		return errors.New("GEOCODE_API_KEY environment variable is not set")
+	}
+
+	// Read the input
+	in := stdin
+	if name := flags.Arg(0); name != "" && name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return fmt.Errorf("error opening input: %v", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	inputs, err := readBatchInput(in)
+	if err != nil {
+		return err
+	}
+
+	airQualityURL := ""
+	if cfg.AirQuality {
+		airQualityURL = cfg.Providers.AirQualityURL
+	}
+	records := forecastBatch(inputs, *parallel, c, cfg.Providers.GeocodeURL, cfg.Providers.ForecastURL, airQualityURL, cfg.Providers.GeocodeAPIKey, forecastOptions(cfg))
+
+	// Write the output
+	out := stdout
+	var outFile *os.File
+	if *output != "" {
+		outFile, err = os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("error creating output: %v", err)
+		}
+		out = outFile
+	}
+	if *format == "csv" {
+		err = writeBatchCSV(out, records)
+	} else {
+		err = writeBatchJSON(out, records)
+	}
+	if outFile != nil {
+		// Closing reports the writes the file system could not complete, which would otherwise be lost
+		if closeErr := outFile.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("error writing output: %v", err)
+	}
+
+	failed := 0
+	for _, record := range records {
+		if record.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", failed, len(records))
+	}
+	return nil
+}
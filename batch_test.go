@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+)
+
+func TestMain_readBatchInput(t *testing.T) {
+	tc := []struct {
+		name     string
+		input    string
+		expected []batchInput
+		err      string
+	}{
+		{
+			name:  "One Address Per Line",
+			input: "3001 Esperanza Crossing, Austin, TX 78758, USA\n\n350 5th Ave, New York, NY 10118\n",
+			expected: []batchInput{
+				{ID: "1", Address: "3001 Esperanza Crossing, Austin, TX 78758, USA"},
+				{ID: "3", Address: "350 5th Ave, New York, NY 10118"},
+			},
+		},
+		{
+			name:  "CSV With ID Column",
+			input: "name,id,address\nHQ,austin,\"3001 Esperanza Crossing, Austin, TX 78758, USA\"\nNYC,nyc,\"350 5th Ave, New York, NY 10118\"\n",
+			expected: []batchInput{
+				{ID: "austin", Address: "3001 Esperanza Crossing, Austin, TX 78758, USA"},
+				{ID: "nyc", Address: "350 5th Ave, New York, NY 10118"},
+			},
+		},
+		{
+			name:  "CSV Without ID Column",
+			input: "address,name\n\"3001 Esperanza Crossing, Austin, TX 78758, USA\",HQ\n\n\"350 5th Ave, New York, NY 10118\",NYC\n",
+			expected: []batchInput{
+				{ID: "2", Address: "3001 Esperanza Crossing, Austin, TX 78758, USA"},
+				{ID: "4", Address: "350 5th Ave, New York, NY 10118"},
+			},
+		},
+		{
+			name:  "CSV Without Address Column",
+			input: "id,location\naustin,\"3001 Esperanza Crossing, Austin, TX 78758, USA\"\n",
+			err:   "invalid CSV header: missing address column",
+		},
+		{
+			name:     "Empty",
+			input:    "",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			inputs, err := readBatchInput(strings.NewReader(tc.input))
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("Expected error '%s', got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(inputs) != len(tc.expected) {
+				t.Fatalf("Expected %d inputs, got %d: %+v", len(tc.expected), len(inputs), inputs)
+			}
+			for i := range inputs {
+				if inputs[i] != tc.expected[i] {
+					t.Errorf("Expected %+v, got %+v", tc.expected[i], inputs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMain_runBatch(t *testing.T) {
+	// Cached forecasts are only served while they cover the current day
+	today := time.Now().UTC()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/maps/api/geocode/json" {
+			if strings.Contains(r.URL.Query().Get("address"), "Nowhere") {
+				w.Write([]byte(`{"results": []}`))
+				return
+			}
+			w.Write([]byte(`{"results": [{"formatted_address": "3001 Esperanza Crossing, Austin, TX 78757, USA",
+				"geometry": {"location": {"lat": 30.3985991, "lng": -97.72206659999999}}}]}`))
+		}
+		if r.URL.Path == "/v1/forecast" {
+			w.Write([]byte(`{"current": {"temperature_2m": 78.6},
+				"daily": {"time": ["` + today.Format("2006-01-02") + `", "` + today.AddDate(0, 0, 1).Format("2006-01-02") + `"], "temperature_2m_max": [97.6, 96.1], "temperature_2m_min": [75.8, 74.2]}}`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.GeocodeURL = server.URL
+	cfg.Providers.ForecastURL = server.URL
+	cfg.Providers.GeocodeAPIKey = "testApiKey"
+	input := "id,address\nhq,3001 Esperanza Crossing Austin TX\nbad,Nowhere\nhq2,3001 Esperanza Crossing Austin TX\n"
+
+	// JSON output, one record per input, in input order, with the failed row reported
+	var out bytes.Buffer
+	err := runBatch([]string{"-parallel", "2"}, cfg, cache.GetCacheInstance(), strings.NewReader(input), &out)
+	if err == nil || err.Error() != "1 of 3 rows failed" {
+		t.Errorf("Expected '1 of 3 rows failed', got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 records, got %d:\n%s", len(lines), out.String())
+	}
+	var records []batchRecord
+	for _, line := range lines {
+		var record batchRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Expected valid JSON, got %v", err)
+		}
+		records = append(records, record)
+	}
+	if records[0].ID != "hq" || records[0].CurrentTemp != 78.6 || len(records[0].Daily) != 2 || records[0].Error != "" {
+		t.Errorf("Expected a forecast for hq, got %+v", records[0])
+	}
+	if records[1].ID != "bad" || !strings.Contains(records[1].Error, "no results found") {
+		t.Errorf("Expected an error for bad, got %+v", records[1])
+	}
+	if records[2].ID != "hq2" || records[2].Error != "" {
+		t.Errorf("Expected a forecast for hq2, got %+v", records[2])
+	}
+
+	// CSV output
+	out.Reset()
+	runBatch([]string{"-format", "csv"}, cfg, cache.GetCacheInstance(), strings.NewReader(input), &out)
+	csvLines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(csvLines) != 4 {
+		t.Fatalf("Expected a header and 3 rows, got:\n%s", out.String())
+	}
+	if csvLines[0] != "id,input,address,current_temp,today_max,today_min,units,from_cache,error" {
+		t.Errorf("Expected the CSV header, got %s", csvLines[0])
+	}
+	if !strings.HasPrefix(csvLines[1], `hq,3001 Esperanza Crossing Austin TX,"3001 Esperanza Crossing, Austin, TX 78757, USA",78.6,97.6,75.8,fahrenheit,true,`) {
+		t.Errorf("Expected a cached CSV row for hq, got %s", csvLines[1])
+	}
+
+	if err := runBatch([]string{"-parallel", "0"}, cfg, cache.GetCacheInstance(), strings.NewReader(input), &out); err == nil {
+		t.Error("Expected an error for -parallel 0, got nil")
+	}
+}
+
+func TestMain_writeBatchCSV(t *testing.T) {
+	now := time.Now().UTC()
+	weeklyForecast := api.WeeklyForecast{
+		Time:             []string{now.AddDate(0, 0, -1).Format("2006-01-02"), now.Format("2006-01-02")},
+		Temperature2MMax: []float64{99.1, 97.6},
+		Temperature2MMin: []float64{76.3, 75.8},
+	}
+	daily := newBatchDays(weeklyForecast, now)
+	if len(daily) != 2 || !daily[0].Observed || daily[1].Observed {
+		t.Errorf("Expected yesterday to be observed, got %+v", daily)
+	}
+
+	tc := []struct {
+		name     string
+		today    int
+		expected string
+	}{
+		{name: "Today After Past Days", today: weeklyForecast.DayIndex(now), expected: "hq,78758,78758,78.6,97.6,75.8,fahrenheit,false,"},
+		{name: "Today Not Covered", today: -1, expected: "hq,78758,78758,78.6,,,fahrenheit,false,"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			record := batchRecord{ID: "hq", Input: "78758", Address: "78758", CurrentTemp: 78.6, Units: api.Fahrenheit, Daily: daily, today: tc.today}
+			if err := writeBatchCSV(&out, []batchRecord{record}); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); lines[1] != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, lines[1])
+			}
+		})
+	}
+}
@@ -1,11 +1,16 @@
-// Package cache provides a simple in-memory cache for storing the current temperature and weekly forecast for a given postalCode
+// Package cache provides a simple cache for storing the current temperature and weekly forecast for a given postalCode.
+// Entries are held in memory by default, or in any other Store such as a RESP server shared by several processes.
 package cache
 
 import (
+	"encoding/json"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/metrics"
 )
 
 var (
@@ -13,6 +18,9 @@ var (
 	once          sync.Once
 )
 
+// updateBuffer is the number of updates a subscriber can fall behind before further updates are dropped for it.
+const updateBuffer = 16
+
 // Value holds the timestamp when the data was cached, the weekly forecast, and the current temperature.
 type Value struct {
 	// timestamp is when the data was added to the cache.
@@ -21,33 +29,155 @@ type Value struct {
 	weeklyForecast api.WeeklyForecast
 	// currentTemp is the current temperature.
 	currentTemp float64
+	// permanent marks data that never changes, such as historical weather, which never expires.
+	permanent bool
+}
+
+// valueJSON is the serialized form of Value used by stores that keep entries outside the process.
+type valueJSON struct {
+	Timestamp      time.Time          `json:"timestamp"`
+	WeeklyForecast api.WeeklyForecast `json:"weekly_forecast"`
+	CurrentTemp    float64            `json:"current_temp"`
+	Permanent      bool               `json:"permanent,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler so that a Value can be written to an external Store.
+func (v Value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(valueJSON{
+		Timestamp:      v.timestamp,
+		WeeklyForecast: v.weeklyForecast,
+		CurrentTemp:    v.currentTemp,
+		Permanent:      v.permanent,
+	})
 }
 
-// Cache provides an in-memory store with thread-safe access and entry expiration.
+// UnmarshalJSON implements json.Unmarshaler so that a Value can be read back from an external Store.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	var vj valueJSON
+	if err := json.Unmarshal(data, &vj); err != nil {
+		return err
+	}
+
+	*v = Value{
+		timestamp:      vj.Timestamp,
+		weeklyForecast: vj.WeeklyForecast,
+		currentTemp:    vj.CurrentTemp,
+		permanent:      vj.Permanent,
+	}
+	return nil
+}
+
+// expired reports whether the value is older than entryTTL. Permanent values never expire.
+func (v Value) expired(entryTTL time.Duration) bool {
+	return !v.permanent && time.Since(v.timestamp) > entryTTL
+}
+
+// Cache provides thread-safe access to a Store with entry expiration.
 type Cache struct {
-	// data stores the cached values mapped by a string key.
-	data map[string]Value
-	// mu protects concurrent access to the cache.
+	// store holds the cached values mapped by a string key.
+	store Store
+	// mu protects concurrent access to the store and entryTTL fields. It is not held across calls to the store, see
+	// snapshot.
 	mu sync.RWMutex
 	// entryTTL defines the time-to-live for each cache entry.
 	entryTTL time.Duration
+
+	// hits counts lookups that returned a live entry.
+	hits atomic.Uint64
+	// misses counts lookups that returned nothing, including expired entries.
+	misses atomic.Uint64
+	// expirations counts entries removed by Get because their TTL had elapsed.
+	expirations atomic.Uint64
+	// purges counts the number of times PurgeCache has run.
+	purges atomic.Uint64
+	// evictions counts entries removed by PurgeCache.
+	evictions atomic.Uint64
+	// storeErrors counts failed calls to the underlying Store.
+	storeErrors atomic.Uint64
+
+	// subscribersMu protects subscribers.
+	subscribersMu sync.Mutex
+	// subscribers receive an Update for every entry added with Add under the key they are mapped to.
+	subscribers map[chan Update]string
+}
+
+// Stats is a point-in-time snapshot of the cache counters.
+type Stats struct {
+	// Hits is the number of lookups that returned a live entry.
+	Hits uint64
+	// Misses is the number of lookups that returned nothing, including expired entries.
+	Misses uint64
+	// Expirations is the number of entries removed on lookup because their TTL had elapsed.
+	Expirations uint64
+	// Purges is the number of times PurgeCache has run.
+	Purges uint64
+	// Evictions is the number of entries removed by PurgeCache.
+	Evictions uint64
+	// StoreErrors is the number of failed calls to the underlying Store.
+	StoreErrors uint64
+	// Size is the number of entries currently held, including expired entries that have not been purged yet.
+	Size int
+	// OldestEntryAge is the age of the oldest entry held, or zero if the cache is empty.
+	OldestEntryAge time.Duration
+}
+
+// Entry describes a single live cache entry.
+type Entry struct {
+	// Key is the key the entry is stored under.
+	Key string
+	// Timestamp is when the entry was added to the cache.
+	Timestamp time.Time
+	// TTL is the time remaining before the entry expires. It is zero for permanent entries.
+	TTL time.Duration
+	// Permanent is set for entries that never expire.
+	Permanent bool
+	// CurrentTemp is the cached current temperature.
+	CurrentTemp float64
+	// WeeklyForecast is the cached weekly weather forecast.
+	WeeklyForecast api.WeeklyForecast
+}
+
+// Update describes an entry added with Add, as delivered to subscribers.
+type Update struct {
+	// Key is the key the entry is stored under.
+	Key string
+	// Timestamp is when the entry was added to the cache.
+	Timestamp time.Time
+	// CurrentTemp is the current temperature.
+	CurrentTemp float64
+	// WeeklyForecast is the weekly weather forecast.
+	WeeklyForecast api.WeeklyForecast
 }
 
 // GetCacheInstance returns the singleton instance of the Cache.
 // If the cache has already been initialized, it returns the existing instance.
-// The cache is initialized with a default entryTTL of 30 minutes.
+// The cache is initialized with an in-memory store and a default entryTTL of 30 minutes.
 func GetCacheInstance() *Cache {
 	once.Do(
 		func() {
-			cacheInstance = &Cache{
-				data:     make(map[string]Value),
-				entryTTL: 30 * time.Minute,
-			}
+			cacheInstance = newCache(30 * time.Minute)
 		})
 
 	return cacheInstance
 }
 
+// newCache returns an empty Cache backed by an in-memory store with the given entry time-to-live.
+func newCache(entryTTL time.Duration) *Cache {
+	return &Cache{
+		store:    NewMemoryStore(),
+		entryTTL: entryTTL,
+	}
+}
+
+// SetStore replaces the store backing the cache. Entries held by the previous store are not copied over.
+// It is safe for concurrent use.
+func (c *Cache) SetStore(store Store) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store = store
+}
+
 // SetEntryTTL sets the time-to-live duration for cache entries.
 // It is safe for concurrent use. Note that changing the TTL affects all existing entries and may lead to
 // unexpected expiration times.
@@ -58,15 +188,39 @@ func (c *Cache) SetEntryTTL(entryTTL time.Duration) {
 	c.entryTTL = entryTTL
 }
 
+// snapshot returns the store and the entry time-to-live. Methods call the store through them rather than holding c.mu,
+// which would make every other Get and Add wait on the store, a network round trip for a RESPStore; stores are safe
+// for concurrent use on their own.
+func (c *Cache) snapshot() (Store, time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.store, c.entryTTL
+}
+
 // PurgeCache removes expired entries from the cache based on the entry time-to-live.
 // It is safe for concurrent use.
 func (c *Cache) PurgeCache() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	store, entryTTL := c.snapshot()
 
-	for k, v := range c.data {
-		if time.Now().After(v.timestamp.Add(c.entryTTL)) {
-			delete(c.data, k)
+	c.purges.Add(1)
+	keys, err := store.Keys()
+	if err != nil {
+		c.storeErrors.Add(1)
+		return
+	}
+	for _, k := range keys {
+		v, ok, err := store.Get(k)
+		if err != nil {
+			c.storeErrors.Add(1)
+			continue
+		}
+		if ok && v.expired(entryTTL) {
+			if err := store.Delete(k); err != nil {
+				c.storeErrors.Add(1)
+				continue
+			}
+			c.evictions.Add(1)
 		}
 	}
 }
@@ -83,44 +237,270 @@ func (c *Cache) StartAutoPurge(interval time.Duration) {
 	}()
 }
 
-// Add inserts a new entry into the cache with the specified key, current temperature, and weekly forecast.
+// Add inserts a new entry into the cache with the specified key, current temperature, and weekly forecast, and
+// notifies the subscribers once it is stored. It returns the entry as added, even if the store failed to keep it.
 // It is safe for concurrent use.
-func (c *Cache) Add(key string, currentTemp float64, weeklyForecast api.WeeklyForecast) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.data[key] = Value{
+func (c *Cache) Add(key string, currentTemp float64, weeklyForecast api.WeeklyForecast) Entry {
+	value := Value{
 		timestamp:      time.Now(),
 		weeklyForecast: weeklyForecast,
 		currentTemp:    currentTemp,
 	}
+	store, entryTTL := c.snapshot()
+	err := store.Set(key, value, entryTTL)
+	entry := newEntry(key, value, entryTTL)
+
+	if err != nil {
+		c.storeErrors.Add(1)
+		return entry
+	}
+	c.notify(Update{Key: key, Timestamp: value.timestamp, CurrentTemp: currentTemp, WeeklyForecast: weeklyForecast})
+	return entry
+}
+
+// Subscribe returns a channel that receives an Update every time an entry is added under key with Add from now on,
+// whichever caller added it, and a function that unsubscribes and closes the channel. Updates are dropped for a
+// subscriber that falls more than a few updates behind, so that a slow subscriber never blocks Add. Entries added by
+// other processes sharing the store are not seen. It is safe for concurrent use.
+func (c *Cache) Subscribe(key string) (<-chan Update, func()) {
+	updates := make(chan Update, updateBuffer)
+	c.subscribersMu.Lock()
+	if c.subscribers == nil {
+		c.subscribers = make(map[chan Update]string)
+	}
+	c.subscribers[updates] = key
+	c.subscribersMu.Unlock()
+
+	var unsubscribe sync.Once
+	return updates, func() {
+		unsubscribe.Do(func() {
+			c.subscribersMu.Lock()
+			defer c.subscribersMu.Unlock()
+
+			delete(c.subscribers, updates)
+			close(updates)
+		})
+	}
+}
+
+// notify delivers the update to every subscriber of its key that has room for it.
+func (c *Cache) notify(update Update) {
+	c.subscribersMu.Lock()
+	defer c.subscribersMu.Unlock()
+
+	for updates, key := range c.subscribers {
+		if key != update.Key {
+			continue
+		}
+		select {
+		case updates <- update:
+		default:
+		}
+	}
+}
+
+// AddPermanent inserts an entry that never expires, for data that never changes such as historical weather. It is
+// only removed by Delete. It is safe for concurrent use.
+func (c *Cache) AddPermanent(key string, weeklyForecast api.WeeklyForecast) {
+	store, _ := c.snapshot()
+	err := store.Set(key, Value{
+		timestamp:      time.Now(),
+		weeklyForecast: weeklyForecast,
+		permanent:      true,
+	}, 0)
+	if err != nil {
+		c.storeErrors.Add(1)
+	}
 }
 
 // Get retrieves the current temperature and weekly forecast for the given key.
-// It returns false if the key is not found or the entry has expired.
+// It returns false if the key is not found, the entry has expired or the store could not be reached.
 // It is safe for concurrent use.
 func (c *Cache) Get(key string) (float64, api.WeeklyForecast, bool) {
-	c.mu.RLock()
-	value, ok := c.data[key]
-	c.mu.RUnlock()
+	entry, ok := c.GetEntry(key)
+	return entry.CurrentTemp, entry.WeeklyForecast, ok
+}
 
+// GetEntry retrieves the entry for the given key, along with when it was added and the time remaining before it
+// expires. It returns false if the key is not found, the entry has expired or the store could not be reached.
+// It is safe for concurrent use.
+func (c *Cache) GetEntry(key string) (Entry, bool) {
+	store, entryTTL := c.snapshot()
+	value, ok, err := store.Get(key)
+	if err != nil {
+		c.storeErrors.Add(1)
+	}
 	if !ok {
-		return 0, api.WeeklyForecast{}, false
+		c.misses.Add(1)
+		return Entry{}, false
 	}
 
-	if time.Since(value.timestamp) > c.entryTTL {
-		c.Delete(key) // Safe to call; it acquires the write lock internally
-		return 0, api.WeeklyForecast{}, false
+	if value.expired(entryTTL) {
+		c.Delete(key)
+		c.expirations.Add(1)
+		c.misses.Add(1)
+		return Entry{}, false
 	}
 
-	return value.currentTemp, value.weeklyForecast, ok
+	c.hits.Add(1)
+	return newEntry(key, value, entryTTL), true
 }
 
 // Delete removes the entry associated with the key from the cache.
 // It is safe for concurrent use.
 func (c *Cache) Delete(key string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	store, _ := c.snapshot()
+	if err := store.Delete(key); err != nil {
+		c.storeErrors.Add(1)
+	}
+}
+
+// Clear removes every entry from the cache, including permanent entries, and returns the number removed.
+// It is safe for concurrent use.
+func (c *Cache) Clear() int {
+	store, _ := c.snapshot()
 
-	delete(c.data, key)
+	keys, err := store.Keys()
+	if err != nil {
+		c.storeErrors.Add(1)
+		return 0
+	}
+	removed := 0
+	for _, k := range keys {
+		if err := store.Delete(k); err != nil {
+			c.storeErrors.Add(1)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// Stats returns a snapshot of the cache counters, its current size and the age of its oldest entry.
+// It is safe for concurrent use.
+func (c *Cache) Stats() Stats {
+	store, _ := c.snapshot()
+
+	stats := Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Expirations: c.expirations.Load(),
+		Purges:      c.purges.Load(),
+		Evictions:   c.evictions.Load(),
+	}
+	for _, v := range c.values(store) {
+		stats.Size++
+		if age := time.Since(v.timestamp); age > stats.OldestEntryAge {
+			stats.OldestEntryAge = age
+		}
+	}
+	stats.StoreErrors = c.storeErrors.Load()
+
+	return stats
+}
+
+// Keys returns the keys of all live entries in sorted order.
+// It is safe for concurrent use.
+func (c *Cache) Keys() []string {
+	entries := c.Entries()
+	keys := make([]string, 0, len(entries))
+	for _, e := range entries {
+		keys = append(keys, e.Key)
+	}
+
+	return keys
+}
+
+// Entries returns all live entries sorted by key, along with the time remaining before each one expires.
+// Expired entries that have not been purged yet are skipped. It is safe for concurrent use.
+func (c *Cache) Entries() []Entry {
+	store, entryTTL := c.snapshot()
+
+	values := c.values(store)
+	entries := make([]Entry, 0, len(values))
+	for k, v := range values {
+		if v.expired(entryTTL) {
+			continue
+		}
+		entries = append(entries, newEntry(k, v, entryTTL))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	return entries
+}
+
+// newEntry returns the entry describing the value stored under key, given the entry time-to-live.
+func newEntry(key string, v Value, entryTTL time.Duration) Entry {
+	entry := Entry{
+		Key:            key,
+		Timestamp:      v.timestamp,
+		Permanent:      v.permanent,
+		CurrentTemp:    v.currentTemp,
+		WeeklyForecast: v.weeklyForecast,
+	}
+	if !v.permanent {
+		entry.TTL = entryTTL - time.Since(v.timestamp)
+	}
+	return entry
+}
+
+// values returns every value held by store mapped by key. Store errors are counted and the affected entries
+// skipped.
+func (c *Cache) values(store Store) map[string]Value {
+	values := make(map[string]Value)
+	keys, err := store.Keys()
+	if err != nil {
+		c.storeErrors.Add(1)
+		return values
+	}
+	for _, k := range keys {
+		v, ok, err := store.Get(k)
+		if err != nil {
+			c.storeErrors.Add(1)
+			continue
+		}
+		if ok {
+			values[k] = v
+		}
+	}
+
+	return values
+}
+
+// RegisterMetrics registers the cache counters, its size and its hit ratio with the given metrics registry.
+// It must be called at most once per registry.
+func (c *Cache) RegisterMetrics(r *metrics.Registry) {
+	r.NewCounterFunc("weather_cache_hits_total", "Cache lookups that returned a live entry.",
+		func() float64 { return float64(c.hits.Load()) })
+	r.NewCounterFunc("weather_cache_misses_total", "Cache lookups that returned nothing, including expired entries.",
+		func() float64 { return float64(c.misses.Load()) })
+	r.NewCounterFunc("weather_cache_expirations_total", "Cache entries removed on lookup because their TTL had elapsed.",
+		func() float64 { return float64(c.expirations.Load()) })
+	r.NewCounterFunc("weather_cache_evictions_total", "Cache entries removed by a purge.",
+		func() float64 { return float64(c.evictions.Load()) })
+	r.NewCounterFunc("weather_cache_store_errors_total", "Failed calls to the cache store.",
+		func() float64 { return float64(c.storeErrors.Load()) })
+	r.NewGaugeFunc("weather_cache_hit_ratio", "Ratio of cache lookups that returned a live entry.",
+		func() float64 {
+			hits, misses := c.hits.Load(), c.misses.Load()
+			if hits+misses == 0 {
+				return 0
+			}
+			return float64(hits) / float64(hits+misses)
+		})
+	r.NewGaugeFunc("weather_cache_entries", "Entries currently held by the cache store.",
+		func() float64 { return float64(c.size()) })
+}
+
+// size returns the number of keys held by the store without reading their values.
+// It is safe for concurrent use.
+func (c *Cache) size() int {
+	store, _ := c.snapshot()
+
+	keys, err := store.Keys()
+	if err != nil {
+		c.storeErrors.Add(1)
+		return 0
+	}
+	return len(keys)
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/metrics"
 )
 
 var c = GetCacheInstance()
@@ -131,3 +132,290 @@ func TestCache_PurgeCache(t *testing.T) {
 		t.Errorf("Expected key %s to remain in cacheInstance", key2)
 	}
 }
+
+// blockingStore is a MemoryStore whose Keys, and Get of the key "slow", block until release is closed, as a remote
+// store does while it waits on the network.
+type blockingStore struct {
+	*MemoryStore
+	scanning chan struct{}
+	release  chan struct{}
+}
+
+func (s *blockingStore) Keys() ([]string, error) {
+	s.scanning <- struct{}{}
+	<-s.release
+	return s.MemoryStore.Keys()
+}
+
+func (s *blockingStore) Get(key string) (Value, bool, error) {
+	if key == "slow" {
+		s.scanning <- struct{}{}
+		<-s.release
+	}
+	return s.MemoryStore.Get(key)
+}
+
+func TestCache_PurgeCacheConcurrent(t *testing.T) {
+	store := &blockingStore{MemoryStore: NewMemoryStore(), scanning: make(chan struct{}), release: make(chan struct{})}
+	c := newCache(time.Minute)
+	c.SetStore(store)
+
+	purged := make(chan struct{})
+	go func() {
+		c.PurgeCache()
+		close(purged)
+	}()
+	<-store.scanning
+
+	// Lookups and additions go ahead while the purge waits on the store
+	done := make(chan struct{})
+	go func() {
+		c.Add("TestCache_PurgeCacheConcurrent", 75.5, api.WeeklyForecast{})
+		c.GetEntry("TestCache_PurgeCacheConcurrent")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected Add and GetEntry not to wait for the purge")
+	}
+	close(store.release)
+	<-purged
+	<-done
+}
+
+func TestCache_GetEntryConcurrent(t *testing.T) {
+	store := &blockingStore{MemoryStore: NewMemoryStore(), scanning: make(chan struct{}), release: make(chan struct{})}
+	c := newCache(time.Minute)
+	c.SetStore(store)
+
+	found := make(chan struct{})
+	go func() {
+		c.GetEntry("slow")
+		close(found)
+	}()
+	<-store.scanning
+
+	// Other keys are read and written while the lookup waits on the store
+	done := make(chan struct{})
+	go func() {
+		c.Add("TestCache_GetEntryConcurrent", 75.5, api.WeeklyForecast{})
+		c.GetEntry("TestCache_GetEntryConcurrent")
+		c.Delete("TestCache_GetEntryConcurrent")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected Add, GetEntry and Delete not to wait for another lookup")
+	}
+	close(store.release)
+	<-found
+	<-done
+}
+
+func TestCache_Stats(t *testing.T) {
+	sc := newCache(1 * time.Second)
+	weeklyForecast := api.WeeklyForecast{
+		Time:             []string{"2024-09-25"},
+		Temperature2MMax: []float64{75.5},
+		Temperature2MMin: []float64{75.2},
+	}
+
+	sc.Add("TestCache_Stats", 75.5, weeklyForecast)
+	sc.Add("TestCache_Stats2", 75.5, weeklyForecast)
+	sc.Get("TestCache_Stats")
+	sc.Get("TestCache_StatsMissing")
+
+	stats := sc.Stats()
+	if stats.Hits != 1 {
+		t.Errorf("Expected 1 hit, got %d", stats.Hits)
+	}
+	if stats.Misses != 1 {
+		t.Errorf("Expected 1 miss, got %d", stats.Misses)
+	}
+	if stats.Size != 2 {
+		t.Errorf("Expected size 2, got %d", stats.Size)
+	}
+	if stats.OldestEntryAge <= 0 {
+		t.Errorf("Expected a positive oldest entry age, got %s", stats.OldestEntryAge)
+	}
+
+	// Let both entries expire, then trigger one expiration and one eviction
+	time.Sleep(2 * time.Second)
+	sc.Get("TestCache_Stats")
+	sc.PurgeCache()
+
+	stats = sc.Stats()
+	if stats.Expirations != 1 {
+		t.Errorf("Expected 1 expiration, got %d", stats.Expirations)
+	}
+	if stats.Misses != 2 {
+		t.Errorf("Expected 2 misses, got %d", stats.Misses)
+	}
+	if stats.Purges != 1 {
+		t.Errorf("Expected 1 purge, got %d", stats.Purges)
+	}
+	if stats.Evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", stats.Evictions)
+	}
+	if stats.Size != 0 {
+		t.Errorf("Expected size 0, got %d", stats.Size)
+	}
+	if stats.OldestEntryAge != 0 {
+		t.Errorf("Expected oldest entry age 0 for an empty cache, got %s", stats.OldestEntryAge)
+	}
+}
+
+func TestCache_Entries(t *testing.T) {
+	sc := newCache(30 * time.Minute)
+	weeklyForecast := api.WeeklyForecast{
+		Time:             []string{"2024-09-25"},
+		Temperature2MMax: []float64{75.5},
+		Temperature2MMin: []float64{75.2},
+	}
+
+	sc.Add("78758", 75.5, weeklyForecast)
+	sc.Add("10001", 60.1, weeklyForecast)
+
+	keys := sc.Keys()
+	if !reflect.DeepEqual(keys, []string{"10001", "78758"}) {
+		t.Errorf("Expected sorted keys [10001 78758], got %v", keys)
+	}
+
+	entries := sc.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].CurrentTemp != 60.1 {
+		t.Errorf("Expected temp 60.1, got %f", entries[0].CurrentTemp)
+	}
+	if entries[0].TTL <= 0 || entries[0].TTL > 30*time.Minute {
+		t.Errorf("Expected remaining TTL within (0, 30m], got %s", entries[0].TTL)
+	}
+	if entries[0].Timestamp.IsZero() {
+		t.Error("Expected a non-zero timestamp")
+	}
+}
+
+func TestCache_GetEntry(t *testing.T) {
+	sc := newCache(30 * time.Minute)
+	weeklyForecast := api.WeeklyForecast{Time: []string{"2024-09-25"}, Temperature2MMax: []float64{88.3}}
+
+	added := sc.Add("78705", 79.4, weeklyForecast)
+	entry, ok := sc.GetEntry("78705")
+	if !ok {
+		t.Fatal("Expected the entry to be found")
+	}
+	if !entry.Timestamp.Equal(added.Timestamp) || entry.Key != "78705" || entry.CurrentTemp != 79.4 ||
+		entry.WeeklyForecast.Temperature2MMax[0] != 88.3 {
+		t.Errorf("Expected the added entry %+v, got %+v", added, entry)
+	}
+	if entry.TTL <= 0 || entry.TTL > added.TTL {
+		t.Errorf("Expected remaining TTL within (0, %s], got %s", added.TTL, entry.TTL)
+	}
+
+	if _, ok := sc.GetEntry("missing"); ok {
+		t.Error("Expected a missing key not to be found")
+	}
+	if stats := sc.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected 1 hit and 1 miss, got %d and %d", stats.Hits, stats.Misses)
+	}
+}
+
+func TestCache_AddPermanent(t *testing.T) {
+	sc := newCache(time.Millisecond)
+	history := api.WeeklyForecast{
+		Time:             []string{"2024-09-01"},
+		Temperature2MMax: []float64{99.1},
+		Temperature2MMin: []float64{76.3},
+	}
+
+	sc.AddPermanent("history", history)
+	sc.Add("forecast", 75.5, history)
+	time.Sleep(5 * time.Millisecond)
+	sc.PurgeCache()
+
+	if _, weeklyForecast, ok := sc.Get("history"); !ok || weeklyForecast.Temperature2MMax[0] != 99.1 {
+		t.Errorf("Expected the permanent entry to outlive the TTL, got %v, %t", weeklyForecast, ok)
+	}
+	if _, _, ok := sc.Get("forecast"); ok {
+		t.Error("Expected the regular entry to expire")
+	}
+
+	entries := sc.Entries()
+	if len(entries) != 1 || !entries[0].Permanent || entries[0].TTL != 0 {
+		t.Errorf("Expected one permanent entry without a TTL, got %+v", entries)
+	}
+}
+
+func TestCache_Clear(t *testing.T) {
+	cc := newCache(30 * time.Minute)
+	cc.Add("78758", 78.6, api.WeeklyForecast{})
+	cc.AddPermanent("history", api.WeeklyForecast{})
+
+	if removed := cc.Clear(); removed != 2 {
+		t.Errorf("Expected 2 entries removed, got %d", removed)
+	}
+	if keys := cc.Keys(); len(keys) != 0 {
+		t.Errorf("Expected an empty cache, got %v", keys)
+	}
+}
+
+func TestCache_Subscribe(t *testing.T) {
+	sc := newCache(time.Minute)
+	updates, unsubscribe := sc.Subscribe("austin")
+	weeklyForecast := api.WeeklyForecast{Time: []string{"2024-09-25"}, Temperature2MMax: []float64{91.4}}
+
+	sc.Add("boston", 70.2, weeklyForecast)
+	sc.Add("austin", 80.1, weeklyForecast)
+	sc.AddPermanent("austin", weeklyForecast)
+	update := <-updates
+	if update.Key != "austin" || update.CurrentTemp != 80.1 || update.WeeklyForecast.Temperature2MMax[0] != 91.4 ||
+		update.Timestamp.IsZero() {
+		t.Errorf("Expected the update of austin, got %+v", update)
+	}
+	if len(updates) != 0 {
+		t.Errorf("Expected no update for other keys or a permanent entry, got %d", len(updates))
+	}
+
+	// A subscriber that falls behind misses updates rather than blocking Add
+	for i := 0; i < updateBuffer+5; i++ {
+		sc.Add("austin", float64(i), weeklyForecast)
+	}
+	if len(updates) != updateBuffer {
+		t.Errorf("Expected %d buffered updates, got %d", updateBuffer, len(updates))
+	}
+
+	unsubscribe()
+	unsubscribe()
+	for range updates {
+	}
+	sc.Add("austin", 80.1, weeklyForecast)
+	if len(sc.subscribers) != 0 {
+		t.Errorf("Expected no subscribers, got %d", len(sc.subscribers))
+	}
+}
+
+func TestCache_RegisterMetrics(t *testing.T) {
+	mc := newCache(30 * time.Minute)
+	r := metrics.NewRegistry()
+	mc.RegisterMetrics(r)
+
+	mc.Add("78758", 78.6, api.WeeklyForecast{})
+	mc.Get("78758")
+	mc.Get("TestCache_RegisterMetricsMissing")
+
+	var b strings.Builder
+	r.Write(&b)
+	for _, sample := range []string{
+		"weather_cache_hits_total 1\n",
+		"weather_cache_misses_total 1\n",
+		"weather_cache_hit_ratio 0.5\n",
+		"weather_cache_entries 1\n",
+	} {
+		if !strings.Contains(b.String(), sample) {
+			t.Errorf("Expected %q in metrics output, got:\n%s", sample, b.String())
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// respDialTimeout bounds how long connecting to the RESP server may take.
+	respDialTimeout = 5 * time.Second
+	// respIOTimeout bounds how long a single command round trip may take.
+	respIOTimeout = 5 * time.Second
+	// respScanCount is the COUNT hint sent with each SCAN call.
+	respScanCount = 100
+	// respMaxBulkLength is the longest bulk string accepted in a reply, far above any cached forecast, so that a
+	// corrupt or hostile reply cannot make the store allocate without bound.
+	respMaxBulkLength = 16 << 20
+	// respMaxArrayLength is the most elements accepted in a reply array, far above any SCAN page.
+	respMaxArrayLength = 1 << 20
+)
+
+// RESPStore is a Store backed by a server speaking the Redis serialization protocol (RESP), such as Redis or Valkey.
+// Values are serialized as JSON and written with SET EX so that the server expires them on its own, which lets
+// several weather servers share one cache.
+type RESPStore struct {
+	// addr is the host:port of the RESP server.
+	addr string
+	// prefix is prepended to every key so that the cache can share a server with other data.
+	prefix string
+
+	// mu serializes commands on the single connection.
+	mu sync.Mutex
+	// conn is the current connection, or nil if the store has not connected yet or the last command failed.
+	conn net.Conn
+	// rd buffers replies read from conn.
+	rd *bufio.Reader
+}
+
+// respError is an error reply sent by the RESP server.
+type respError string
+
+// Error implements the error interface.
+func (e respError) Error() string {
+	return "resp server error: " + string(e)
+}
+
+// NewRESPStore returns a RESPStore for the server at addr that stores keys under the given prefix.
+// The connection is established on first use and re-established after any network error.
+func NewRESPStore(addr string, prefix string) *RESPStore {
+	return &RESPStore{
+		addr:   addr,
+		prefix: prefix,
+	}
+}
+
+// Get returns the value stored under key and whether it was found.
+func (s *RESPStore) Get(key string) (Value, bool, error) {
+	reply, err := s.do("GET", s.prefix+key)
+	if err != nil {
+		return Value{}, false, err
+	}
+	if reply == nil {
+		return Value{}, false, nil
+	}
+
+	data, ok := reply.(string)
+	if !ok {
+		return Value{}, false, fmt.Errorf("unexpected reply to GET: %v", reply)
+	}
+	var value Value
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return Value{}, false, fmt.Errorf("error unmarshalling cached value: %v", err)
+	}
+	return value, true, nil
+}
+
+// Set stores value under key. A positive ttl is rounded up to whole seconds and sent as SET EX.
+func (s *RESPStore) Set(key string, value Value, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error marshalling cached value: %v", err)
+	}
+
+	args := []string{"SET", s.prefix + key, string(data)}
+	if ttl > 0 {
+		seconds := int64((ttl + time.Second - 1) / time.Second)
+		args = append(args, "EX", strconv.FormatInt(seconds, 10))
+	}
+	_, err = s.do(args...)
+	return err
+}
+
+// Delete removes the value stored under key.
+func (s *RESPStore) Delete(key string) error {
+	_, err := s.do("DEL", s.prefix+key)
+	return err
+}
+
+// Keys returns every key held under the store prefix, with the prefix removed.
+// It walks the keyspace with SCAN rather than KEYS so that large servers are not blocked.
+func (s *RESPStore) Keys() ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := s.do("SCAN", cursor, "MATCH", s.prefix+"*", "COUNT", strconv.Itoa(respScanCount))
+		if err != nil {
+			return nil, err
+		}
+
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return nil, fmt.Errorf("unexpected reply to SCAN: %v", reply)
+		}
+		cursor, ok = page[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected SCAN cursor: %v", page[0])
+		}
+		batch, ok := page[1].([]any)
+		if !ok {
+			return nil, fmt.Errorf("unexpected SCAN keys: %v", page[1])
+		}
+		for _, k := range batch {
+			if key, ok := k.(string); ok {
+				keys = append(keys, strings.TrimPrefix(key, s.prefix))
+			}
+		}
+
+		if cursor == "0" {
+			return keys, nil
+		}
+	}
+}
+
+// Close closes the connection to the RESP server, if any.
+func (s *RESPStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.rd = nil, nil
+	return err
+}
+
+// do sends a single command and returns its reply. Bulk and simple strings are returned as string, integers as
+// int64, arrays as []any and null replies as nil. Network and protocol errors drop the connection so that the
+// next command reconnects; error replies from the server are returned as respError and keep it open.
+func (s *RESPStore) do(args ...string) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		conn, err := net.DialTimeout("tcp", s.addr, respDialTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to resp server: %v", err)
+		}
+		s.conn, s.rd = conn, bufio.NewReader(conn)
+	}
+
+	reply, err := s.roundTrip(args)
+	var re respError
+	if err != nil && !errors.As(err, &re) {
+		s.conn.Close()
+		s.conn, s.rd = nil, nil
+	}
+	return reply, err
+}
+
+// roundTrip writes args as a RESP array of bulk strings and reads one reply. The caller must hold s.mu.
+func (s *RESPStore) roundTrip(args []string) (any, error) {
+	if err := s.conn.SetDeadline(time.Now().Add(respIOTimeout)); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(s.conn, b.String()); err != nil {
+		return nil, fmt.Errorf("error writing to resp server: %v", err)
+	}
+
+	return readRESP(s.rd)
+}
+
+// readRESP reads a single RESP reply from rd.
+func readRESP(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("error reading from resp server: %v", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty resp reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, respError(line[1:])
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid resp integer %q: %v", line[1:], err)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid resp bulk length %q: %v", line[1:], err)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		if n < 0 || n > respMaxBulkLength {
+			return nil, fmt.Errorf("invalid resp bulk length %d: must be between 0 and %d", n, respMaxBulkLength)
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, fmt.Errorf("error reading from resp server: %v", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid resp array length %q: %v", line[1:], err)
+		}
+		if n == -1 {
+			return nil, nil
+		}
+		if n < 0 || n > respMaxArrayLength {
+			return nil, fmt.Errorf("invalid resp array length %d: must be between 0 and %d", n, respMaxArrayLength)
+		}
+		elems := make([]any, 0, n)
+		for i := 0; i < n; i++ {
+			elem, err := readRESP(rd)
+			var re respError
+			if errors.As(err, &re) {
+				// Keep reading so the rest of the array does not desynchronize the connection
+				elems = append(elems, re)
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+		return elems, nil
+	default:
+		return nil, fmt.Errorf("unknown resp reply type %q", line[0])
+	}
+}
@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+)
+
+// respTestServer is a minimal in-process stand-in for a RESP server. It understands GET, SET (with EX), DEL and SCAN,
+// which is everything RESPStore sends.
+type respTestServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	data     map[string]string
+	expiry   map[string]time.Time
+	commands []string
+}
+
+func newRESPTestServer(t *testing.T) *respTestServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Expected no error listening, got %v", err)
+	}
+	s := &respTestServer{
+		listener: listener,
+		data:     make(map[string]string),
+		expiry:   make(map[string]time.Time),
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *respTestServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *respTestServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *respTestServer) handle(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		reply, err := readRESP(rd)
+		if err != nil {
+			return
+		}
+		elems, _ := reply.([]any)
+		args := make([]string, 0, len(elems))
+		for _, e := range elems {
+			arg, _ := e.(string)
+			args = append(args, arg)
+		}
+		if len(args) == 0 {
+			fmt.Fprint(conn, "-ERR empty command\r\n")
+			continue
+		}
+		fmt.Fprint(conn, s.exec(args))
+	}
+}
+
+func (s *respTestServer) exec(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands = append(s.commands, strings.Join(args, " "))
+	for k, exp := range s.expiry {
+		if time.Now().After(exp) {
+			delete(s.data, k)
+			delete(s.expiry, k)
+		}
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		v, ok := s.data[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "SET":
+		s.data[args[1]] = args[2]
+		delete(s.expiry, args[1])
+		if len(args) == 5 && strings.EqualFold(args[3], "EX") {
+			seconds, _ := strconv.Atoi(args[4])
+			s.expiry[args[1]] = time.Now().Add(time.Duration(seconds) * time.Second)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, ok := s.data[args[1]]
+		delete(s.data, args[1])
+		delete(s.expiry, args[1])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "SCAN":
+		prefix := strings.TrimSuffix(args[3], "*")
+		var b strings.Builder
+		n := 0
+		for k := range s.data {
+			if strings.HasPrefix(k, prefix) {
+				fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(k), k)
+				n++
+			}
+		}
+		return fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n%s", n, b.String())
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func (s *respTestServer) lastCommand(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.commands) - 1; i >= 0; i-- {
+		if strings.HasPrefix(s.commands[i], prefix) {
+			return s.commands[i]
+		}
+	}
+	return ""
+}
+
+func TestRESPStore_SharedCache(t *testing.T) {
+	server := newRESPTestServer(t)
+	weeklyForecast := api.WeeklyForecast{
+		Time:             []string{"2024-09-25"},
+		Temperature2MMax: []float64{75.5},
+		Temperature2MMin: []float64{75.2},
+	}
+
+	// Two caches sharing one server behave like two weather servers sharing one cache
+	first := newCache(30 * time.Minute)
+	first.SetStore(NewRESPStore(server.addr(), "weather:"))
+	second := newCache(30 * time.Minute)
+	second.SetStore(NewRESPStore(server.addr(), "weather:"))
+
+	first.Add("78758", 78.6, weeklyForecast)
+	if cmd := server.lastCommand("SET"); !strings.HasPrefix(cmd, "SET weather:78758 ") || !strings.HasSuffix(cmd, " EX 1800") {
+		t.Errorf("Expected SET weather:78758 ... EX 1800, got %s", cmd)
+	}
+
+	temp, forecast, ok := second.Get("78758")
+	if !ok {
+		t.Fatal("Expected key 78758 to be found through the second cache")
+	}
+	if temp != 78.6 {
+		t.Errorf("Expected temp 78.6, got %f", temp)
+	}
+	if forecast.Time[0] != weeklyForecast.Time[0] || forecast.Temperature2MMax[0] != weeklyForecast.Temperature2MMax[0] {
+		t.Errorf("Expected forecast %+v, got %+v", weeklyForecast, forecast)
+	}
+
+	if keys := second.Keys(); len(keys) != 1 || keys[0] != "78758" {
+		t.Errorf("Expected keys [78758], got %v", keys)
+	}
+
+	second.Delete("78758")
+	if _, _, ok := first.Get("78758"); ok {
+		t.Error("Expected key 78758 to be deleted for both caches")
+	}
+	if stats := first.Stats(); stats.StoreErrors != 0 {
+		t.Errorf("Expected no store errors, got %d", stats.StoreErrors)
+	}
+}
+
+func TestRESPStore_Expiry(t *testing.T) {
+	server := newRESPTestServer(t)
+	store := NewRESPStore(server.addr(), "weather:")
+	defer store.Close()
+
+	if err := store.Set("TestRESPStore_Expiry", Value{timestamp: time.Now(), currentTemp: 75.5}, 1*time.Second); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok, err := store.Get("TestRESPStore_Expiry"); !ok || err != nil {
+		t.Errorf("Expected key to be found, got ok=%t err=%v", ok, err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	if _, ok, err := store.Get("TestRESPStore_Expiry"); ok || err != nil {
+		t.Errorf("Expected key to be expired by the server, got ok=%t err=%v", ok, err)
+	}
+}
+
+func TestRESPStore_Unreachable(t *testing.T) {
+	server := newRESPTestServer(t)
+	addr := server.addr()
+	server.listener.Close()
+
+	uc := newCache(30 * time.Minute)
+	uc.SetStore(NewRESPStore(addr, "weather:"))
+	uc.Add("78758", 78.6, api.WeeklyForecast{})
+
+	if _, _, ok := uc.Get("78758"); ok {
+		t.Error("Expected a miss when the store is unreachable")
+	}
+	if stats := uc.Stats(); stats.StoreErrors < 2 {
+		t.Errorf("Expected store errors to be counted, got %d", stats.StoreErrors)
+	}
+}
+
+func TestRESPStore_readRESP(t *testing.T) {
+	tc := []struct {
+		name     string
+		reply    string
+		expected any
+		err      string
+	}{
+		{name: "Bulk String", reply: "$5\r\nhello\r\n", expected: "hello"},
+		{name: "Null Bulk String", reply: "$-1\r\n", expected: nil},
+		{name: "Null Array", reply: "*-1\r\n", expected: nil},
+		{name: "Negative Bulk Length", reply: "$-2\r\n", err: "invalid resp bulk length -2: must be between 0 and 16777216"},
+		{name: "Oversized Bulk Length", reply: "$9999999999\r\n", err: "invalid resp bulk length 9999999999: must be between 0 and 16777216"},
+		{name: "Negative Array Length", reply: "*-5\r\n", err: "invalid resp array length -5: must be between 0 and 1048576"},
+		{name: "Oversized Array Length", reply: "*9999999999\r\n", err: "invalid resp array length 9999999999: must be between 0 and 1048576"},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			reply, err := readRESP(bufio.NewReader(strings.NewReader(tc.reply)))
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Errorf("Expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil || reply != tc.expected {
+				t.Errorf("Expected %v, got %v (%v)", tc.expected, reply, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Store is the storage backend behind a Cache.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(key string) (Value, bool, error)
+	// Set stores value under key. A positive ttl allows the store to expire the value on its own;
+	// the Cache still checks the entry timestamp on every lookup.
+	Set(key string, value Value, ttl time.Duration) error
+	// Delete removes the value stored under key. Deleting a missing key is not an error.
+	Delete(key string) error
+	// Keys returns every key currently held by the store.
+	Keys() ([]string, error)
+}
+
+// MemoryStore is a process-local Store backed by a map. It is the default store of a Cache.
+type MemoryStore struct {
+	// data stores the cached values mapped by a string key.
+	data map[string]Value
+	// mu protects concurrent access to data.
+	mu sync.RWMutex
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		data: make(map[string]Value),
+	}
+}
+
+// Get returns the value stored under key and whether it was found. It never returns an error.
+func (s *MemoryStore) Get(key string) (Value, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.data[key]
+	return value, ok, nil
+}
+
+// Set stores value under key. The ttl is ignored; expired entries are removed by the Cache.
+func (s *MemoryStore) Set(key string, value Value, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.data[key] = value
+	return nil
+}
+
+// Delete removes the value stored under key.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data, key)
+	return nil
+}
+
+// Keys returns every key currently held by the store.
+func (s *MemoryStore) Keys() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
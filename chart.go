@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/locale"
+)
+
+const (
+	// chartHeight is the number of rows of the temperature chart.
+	chartHeight = 10
+	// chartMinColumnWidth is the narrowest column a day is drawn in.
+	chartMinColumnWidth = 3
+	// chartMaxColumnWidth is the widest column a day is drawn in.
+	chartMaxColumnWidth = 8
+	// chartHourlyRows is the number of rows of the hourly temperature chart.
+	chartHourlyRows = 4
+	// chartHourlyHours is the number of hours the hourly temperature chart shows when the width allows.
+	chartHourlyHours = 24
+)
+
+// sparkBlocks are the characters used to draw sparklines, from lowest to highest.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as a single line of block characters scaled between their min and max.
+func sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+
+	var b strings.Builder
+	for _, v := range values {
+		level := len(sparkBlocks) - 1
+		if high > low {
+			level = int(math.Round((v - low) / (high - low) * float64(len(sparkBlocks)-1)))
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
+
+// renderTemperatureChart draws an ASCII chart of labelled high (H) and low (L) values that fits in width columns,
+// followed by a sparkline of each series. Values that do not fit are dropped from the right, and labels wider than
+// their column keep their last characters. Points where the high and low fall on the same row are drawn as *. The y
+// axis is labelled with the decimal separator of lc.
+func renderTemperatureChart(w io.Writer, title string, labels []string, highs, lows []float64, lc locale.Locale, width int) {
+	n := min(len(labels), len(highs), len(lows))
+	if n == 0 {
+		return
+	}
+
+	// Scale the y axis to the values
+	low, high := lows[0], highs[0]
+	for i := 0; i < n; i++ {
+		low, high = math.Min(low, math.Min(lows[i], highs[i])), math.Max(high, math.Max(lows[i], highs[i]))
+	}
+	axisLabels := []string{lc.Number(high, 1), lc.Number((high+low)/2, 1), lc.Number(low, 1)}
+	axisWidth := 0
+	for _, l := range axisLabels {
+		axisWidth = max(axisWidth, len(l))
+	}
+
+	// Fit the days into the width, dropping the ones that do not fit
+	columnWidth := min(max((width-axisWidth-2)/n, chartMinColumnWidth), chartMaxColumnWidth)
+	if fit := (width - axisWidth - 2) / columnWidth; fit < n {
+		n = max(fit, 1)
+	}
+
+	row := func(v float64) int {
+		if high == low {
+			return chartHeight / 2
+		}
+		return int(math.Round((high - v) / (high - low) * float64(chartHeight-1)))
+	}
+	grid := make([][]rune, chartHeight)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", n*columnWidth))
+	}
+	for i := 0; i < n; i++ {
+		col := i*columnWidth + columnWidth/2
+		highRow, lowRow := row(highs[i]), row(lows[i])
+		if highRow == lowRow {
+			grid[highRow][col] = '*'
+			continue
+		}
+		grid[highRow][col] = 'H'
+		grid[lowRow][col] = 'L'
+	}
+
+	fmt.Fprintln(w, title)
+	for r := range grid {
+		label := ""
+		switch r {
+		case 0:
+			label = axisLabels[0]
+		case chartHeight / 2:
+			label = axisLabels[1]
+		case chartHeight - 1:
+			label = axisLabels[2]
+		}
+		fmt.Fprintf(w, "%*s |%s\n", axisWidth, label, strings.TrimRight(string(grid[r]), " "))
+	}
+	fmt.Fprintf(w, "%*s +%s\n", axisWidth, "", strings.Repeat("-", n*columnWidth))
+
+	// Label the x axis, shortening labels to the column width, measured in runes since fmt pads in runes
+	var xAxis strings.Builder
+	for i := 0; i < n; i++ {
+		label := []rune(labels[i])
+		if len(label) > columnWidth-1 {
+			label = label[len(label)-(columnWidth-1):]
+		}
+		fmt.Fprintf(&xAxis, "%-*s", columnWidth, fmt.Sprintf("%*s", columnWidth/2+(len(label)+1)/2, string(label)))
+	}
+	fmt.Fprintf(w, "%*s  %s\n", axisWidth, "", strings.TrimRight(xAxis.String(), " "))
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Highs %s  Lows %s\n", sparkline(highs[:n]), sparkline(lows[:n]))
+}
+
+// renderHourlyChart draws the temperature of each hour as a vertical bar that fits in width columns, one column per
+// hour, with the range of the values on the y axis and every sixth hour below. Hours that do not fit are dropped from
+// the right.
+func renderHourlyChart(w io.Writer, title string, times []time.Time, temperatures []float64, lc locale.Locale, width int) {
+	n := min(len(times), len(temperatures))
+	if n == 0 {
+		return
+	}
+
+	// rangeLabels labels the y axis with the range of the first n values
+	rangeLabels := func(n int) []string {
+		low, high := temperatures[0], temperatures[0]
+		for _, v := range temperatures[:n] {
+			low, high = math.Min(low, v), math.Max(high, v)
+		}
+		return []string{lc.Number(high, 1), lc.Number(low, 1)}
+	}
+
+	// Fit the hours into the width, then scale the y axis to the ones that fit, whose labels are no wider
+	axisLabels := rangeLabels(n)
+	axisWidth := max(len(axisLabels[0]), len(axisLabels[1]))
+	n = min(n, max(width-axisWidth-2, 1))
+	axisLabels = rangeLabels(n)
+
+	fmt.Fprintln(w, title)
+	for r, line := range hourlyBars(temperatures[:n], chartHourlyRows) {
+		label := ""
+		switch r {
+		case 0:
+			label = axisLabels[0]
+		case chartHourlyRows - 1:
+			label = axisLabels[1]
+		}
+		fmt.Fprintf(w, "%*s |%s\n", axisWidth, label, strings.TrimRight(line, " "))
+	}
+	fmt.Fprintf(w, "%*s +%s\n", axisWidth, "", strings.Repeat("-", n))
+
+	// Label every sixth hour, letting the last label run past the bars
+	xAxis := []rune(strings.Repeat(" ", n+2))
+	for i, hour := range times[:n] {
+		if hour.Hour()%6 == 0 {
+			copy(xAxis[i:], []rune(hour.Format("15h")))
+		}
+	}
+	fmt.Fprintf(w, "%*s  %s\n", axisWidth, "", strings.TrimRight(string(xAxis), " "))
+}
+
+// displayForecastChart displays the extended forecast as a chart of daily highs and lows, followed by a chart of the
+// temperature over the next hours when the forecast has hourly data, that fit in width columns.
+func displayForecastChart(weeklyForecast api.WeeklyForecast, units api.Units, lc locale.Locale, width int) {
+	// Label days by weekday and day of the month, which narrow columns shorten to the day
+	labels := dayLabels(weeklyForecast, time.Now(), func(day time.Time, _ time.Time) string { return lc.ShortDate(day) })
+
+	fmt.Println("Extended Forecast: ")
+	fmt.Println("---------------------------")
+	renderTemperatureChart(os.Stdout, fmt.Sprintf("Daily High (H) and Low (L) in %s", units.Symbol()),
+		labels, weeklyForecast.Temperature2MMax, weeklyForecast.Temperature2MMin, lc, width)
+	fmt.Println()
+	if times, temperatures := weeklyForecast.HoursFrom(time.Now(), chartHourlyHours); len(times) > 0 {
+		renderHourlyChart(os.Stdout, fmt.Sprintf("Next %d Hours in %s", len(times), units.Symbol()), times, temperatures, lc, width)
+		fmt.Println()
+	}
+}
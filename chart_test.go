@@ -0,0 +1,171 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mfryhover/weather/locale"
+)
+
+func TestMain_sparkline(t *testing.T) {
+	tc := []struct {
+		name     string
+		values   []float64
+		expected string
+	}{
+		{name: "Empty", values: nil, expected: ""},
+		{name: "Rising", values: []float64{0, 1, 2, 3, 4, 5, 6, 7}, expected: "▁▂▃▄▅▆▇█"},
+		{name: "Flat", values: []float64{72, 72, 72}, expected: "███"},
+		{name: "Scaled", values: []float64{60, 95, 60}, expected: "▁█▁"},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := sparkline(tc.values); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMain_renderTemperatureChart(t *testing.T) {
+	labels := []string{"Thu 19", "Fri 20", "Sat 21"}
+	highs := []float64{90, 95, 80}
+	lc, _ := locale.Lookup(locale.Default)
+
+	t.Run("Fits Width", func(t *testing.T) {
+		var b strings.Builder
+		renderTemperatureChart(&b, "Daily High (H) and Low (L) in F", labels, highs, []float64{70, 75, 80}, lc, 40)
+
+		expected := `Daily High (H) and Low (L) in F
+95.0 |            H
+     |
+     |    H
+     |
+     |
+82.5 |                    *
+     |
+     |            L
+     |
+70.0 |    L
+     +------------------------
+       Thu 19  Fri 20  Sat 21
+
+Highs ▆█▁  Lows ▁▅█
+`
+		if b.String() != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+		}
+	})
+
+	t.Run("Narrow Width", func(t *testing.T) {
+		var b strings.Builder
+		renderTemperatureChart(&b, "Daily High (H) and Low (L) in F", labels, highs, []float64{70, 75, 60}, lc, 12)
+
+		lines := strings.Split(b.String(), "\n")
+		for _, line := range lines {
+			if n := len([]rune(line)); n > 12 && !strings.HasPrefix(line, "Daily") && !strings.HasPrefix(line, "Highs") {
+				t.Errorf("Expected chart lines to fit in 12 columns, got %d in %q", n, line)
+			}
+		}
+		if !strings.Contains(b.String(), "      19 20\n") {
+			t.Errorf("Expected labels shortened to the day and the last day dropped, got:\n%s", b.String())
+		}
+	})
+
+	t.Run("Decimal Separator", func(t *testing.T) {
+		de, _ := locale.Lookup("de-DE")
+		var b strings.Builder
+		renderTemperatureChart(&b, "Daily High (H) and Low (L) in F", labels, highs, []float64{70.5, 75, 80}, de, 40)
+		if !strings.Contains(b.String(), "70,5 |") {
+			t.Errorf("Expected the y axis labelled with a decimal comma, got:\n%s", b.String())
+		}
+	})
+
+	t.Run("Non-ASCII Labels", func(t *testing.T) {
+		es, _ := locale.Lookup("es-ES")
+		var b strings.Builder
+		renderTemperatureChart(&b, "Daily High (H) and Low (L) in F", []string{"vie 20", "sáb 21"}, []float64{95, 80}, []float64{75, 60}, es, 18)
+		if !utf8.ValidString(b.String()) {
+			t.Errorf("Expected valid UTF-8, got %q", b.String())
+		}
+		if !strings.Contains(b.String(), "ie 20 áb 21\n") {
+			t.Errorf("Expected labels shortened by runes, got:\n%s", b.String())
+		}
+
+		b.Reset()
+		renderTemperatureChart(&b, "Daily High (H) and Low (L) in F", []string{"vie 20", "sáb 21"}, []float64{95, 80}, []float64{75, 60}, es, 40)
+		if !strings.Contains(b.String(), "  vie 20  sáb 21\n") {
+			t.Errorf("Expected whole labels centered in their columns, got:\n%s", b.String())
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		var b strings.Builder
+		renderTemperatureChart(&b, "Daily High (H) and Low (L) in F", nil, nil, nil, lc, 80)
+		if b.String() != "" {
+			t.Errorf("Expected no output for an empty forecast, got %q", b.String())
+		}
+	})
+}
+
+func TestMain_renderHourlyChart(t *testing.T) {
+	start := time.Date(2026, 10, 18, 4, 0, 0, 0, time.UTC)
+	temperatures := []float64{60, 58, 57, 59, 63, 68, 72, 75, 77, 76, 72, 68}
+	times := make([]time.Time, len(temperatures))
+	for i := range times {
+		times[i] = start.Add(time.Duration(i) * time.Hour)
+	}
+	lc, _ := locale.Lookup(locale.Default)
+
+	tc := []struct {
+		name     string
+		width    int
+		expected string
+	}{
+		{
+			name:  "Fits Width",
+			width: 40,
+			expected: `Next 12 Hours in F
+77.0 |       ▅█▆
+     |     ▂█████▂
+     |    ▂███████
+57.0 |▆▃▁▄████████
+     +------------
+        06h   12h
+`,
+		},
+		{
+			name:  "Narrow Width",
+			width: 12,
+			expected: `Next 12 Hours in F
+68.0 |     █
+     |    ▂█
+     |▁   ██
+57.0 |█▄▁▇██
+     +------
+        06h
+`,
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			var b strings.Builder
+			renderHourlyChart(&b, "Next 12 Hours in F", times, temperatures, lc, tc.width)
+			if b.String() != tc.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tc.expected, b.String())
+			}
+		})
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		var b strings.Builder
+		renderHourlyChart(&b, "Next 0 Hours in F", nil, nil, lc, 80)
+		if b.String() != "" {
+			t.Errorf("Expected no output without hourly data, got %q", b.String())
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+	"github.com/mfryhover/weather/logging"
+)
+
+// comparedLocation holds the forecast of one location in a comparison.
+type comparedLocation struct {
+	// Label is the location as given by the user, an address or a favorite name.
+	Label string
+	// Address is the full formatted address.
+	Address string
+	// CurrentTemp is the current temperature.
+	CurrentTemp float64
+	// WeeklyForecast is the daily forecast.
+	WeeklyForecast api.WeeklyForecast
+	// Err is set if the forecast could not be retrieved.
+	Err error
+}
+
+// compareLocations retrieves the forecast of every location concurrently. It returns one result per input, in
+// input order.
+func compareLocations(inputs []string, favs *favorites.Store, c *cache.Cache, cfg config.Config, opts api.ForecastOptions) []comparedLocation {
+	locations := make([]comparedLocation, len(inputs))
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		wg.Add(1)
+		go func(i int, input string) {
+			defer wg.Done()
+			ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+			addressFull, currentTemp, weeklyForecast, _, err := lookupForecast(ctx, input, favs, c, cfg, opts)
+			locations[i] = comparedLocation{
+				Label:          input,
+				Address:        addressFull,
+				CurrentTemp:    currentTemp,
+				WeeklyForecast: weeklyForecast,
+				Err:            err,
+			}
+		}(i, input)
+	}
+	wg.Wait()
+
+	return locations
+}
+
+// writeComparison writes a table with one column per location and one row per forecast day, headed by the current
+// temperature. Days and temperatures are formatted for lc. Locations that failed are listed below the table instead.
+func writeComparison(w io.Writer, locations []comparedLocation, units api.Units, lc locale.Locale) {
+	var ok []comparedLocation
+	for _, l := range locations {
+		if l.Err == nil {
+			ok = append(ok, l)
+		}
+	}
+
+	if len(ok) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+
+		fmt.Fprint(tw, "Day")
+		for _, l := range ok {
+			fmt.Fprintf(tw, "\t%s", l.Label)
+		}
+		fmt.Fprintln(tw)
+
+		fmt.Fprint(tw, "Current")
+		for _, l := range ok {
+			fmt.Fprintf(tw, "\t%s %s", lc.Number(l.CurrentTemp, 1), units.Symbol())
+		}
+		fmt.Fprintln(tw)
+
+		// Days are taken from every location in order, since two locations may straddle a date boundary
+		for _, day := range comparisonDays(ok) {
+			fmt.Fprint(tw, comparisonDayLabel(day, lc))
+			for _, l := range ok {
+				fmt.Fprintf(tw, "\t%s", dayMaxMin(l.WeeklyForecast, day, lc))
+			}
+			fmt.Fprintln(tw)
+		}
+		tw.Flush()
+		fmt.Fprintln(w)
+
+		for _, l := range ok {
+			fmt.Fprintf(w, "%s: %s\n", l.Label, l.Address)
+		}
+	}
+
+	for _, l := range locations {
+		if l.Err != nil {
+			fmt.Fprintf(w, "%s: %s\n", l.Label, l.Err)
+		}
+	}
+}
+
+// comparisonDays returns the union of the forecast days of every location, in order of first appearance.
+func comparisonDays(locations []comparedLocation) []string {
+	seen := make(map[string]bool)
+	var days []string
+	for _, l := range locations {
+		for _, day := range l.WeeklyForecast.Time {
+			if !seen[day] {
+				seen[day] = true
+				days = append(days, day)
+			}
+		}
+	}
+	return days
+}
+
+// comparisonDayLabel formats a forecast day by its weekday and day of the month, or returns it as is if it is not a
+// date.
+func comparisonDayLabel(day string, lc locale.Locale) string {
+	date, err := time.Parse("2006-01-02", day)
+	if err != nil {
+		return day
+	}
+	return lc.ShortDate(date)
+}
+
+// dayMaxMin formats the max and min temperatures of the given day, or a dash if the forecast does not cover it.
+func dayMaxMin(weeklyForecast api.WeeklyForecast, day string, lc locale.Locale) string {
+	for dayIndex, d := range weeklyForecast.Time {
+		if d == day && dayIndex < len(weeklyForecast.Temperature2MMax) && dayIndex < len(weeklyForecast.Temperature2MMin) {
+			return fmt.Sprintf("%s / %s", lc.Number(weeklyForecast.Temperature2MMax[dayIndex], 1), lc.Number(weeklyForecast.Temperature2MMin[dayIndex], 1))
+		}
+	}
+	return "-"
+}
+
+// runCompare runs the compare command, which displays the forecasts of two or more locations side by side. Each
+// argument is an address or a favorite name; addresses containing spaces must be quoted.
+func runCompare(args []string, cfg config.Config, c *cache.Cache, favs *favorites.Store, stdout io.Writer) error {
+	if len(args) < 2 {
+		return errors.New("usage: weather compare <location> <location> [location...]")
+	}
+	if cfg.Providers.GeocodeAPIKey == "" {
+		return errors.New("GEOCODE_API_KEY environment variable is not set")
+	}
+	lc, err := locale.Lookup(cfg.Locale)
+	if err != nil {
+		return err
+	}
+	opts := forecastOptions(cfg)
+
+	locations := compareLocations(args, favs, c, cfg, opts)
+	fmt.Fprintf(stdout, "Max / Min (%s)\n", opts.Units.Symbol())
+	writeComparison(stdout, locations, opts.Units, lc)
+
+	for _, l := range locations {
+		if l.Err != nil {
+			return errors.New("some locations could not be compared")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+)
+
+func TestMain_writeComparison(t *testing.T) {
+	locations := []comparedLocation{
+		{
+			Label:       "hq",
+			Address:     "3001 Esperanza Crossing, Austin, TX 78758, USA",
+			CurrentTemp: 78.6,
+			WeeklyForecast: api.WeeklyForecast{
+				Time:             []string{"2024-09-19", "2024-09-20"},
+				Temperature2MMax: []float64{97.6, 96.1},
+				Temperature2MMin: []float64{75.8, 74.2},
+			},
+		},
+		{
+			Label:       "nyc",
+			Address:     "New York, NY 10001, USA",
+			CurrentTemp: 65,
+			WeeklyForecast: api.WeeklyForecast{
+				Time:             []string{"2024-09-20", "2024-09-21"},
+				Temperature2MMax: []float64{70.1, 71.3},
+				Temperature2MMin: []float64{60.2, 61.4},
+			},
+		},
+		{
+			Label: "nowhere",
+			Err:   errors.New("error retrieving coordinates: no results found for address: nowhere"),
+		},
+	}
+
+	tc := []struct {
+		name     string
+		locale   string
+		expected string
+	}{
+		{
+			name:   "Default Locale",
+			locale: locale.Default,
+			expected: `Day       hq            nyc
+Current   78.6 F        65.0 F
+Thu 19    97.6 / 75.8   -
+Fri 20    96.1 / 74.2   70.1 / 60.2
+Sat 21    -             71.3 / 61.4
+
+hq: 3001 Esperanza Crossing, Austin, TX 78758, USA
+nyc: New York, NY 10001, USA
+nowhere: error retrieving coordinates: no results found for address: nowhere
+`,
+		},
+		{
+			name:   "German Locale",
+			locale: "de-DE",
+			expected: `Day       hq            nyc
+Current   78,6 F        65,0 F
+Do 19     97,6 / 75,8   -
+Fr 20     96,1 / 74,2   70,1 / 60,2
+Sa 21     -             71,3 / 61,4
+
+hq: 3001 Esperanza Crossing, Austin, TX 78758, USA
+nyc: New York, NY 10001, USA
+nowhere: error retrieving coordinates: no results found for address: nowhere
+`,
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			lc, err := locale.Lookup(tc.locale)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			var b strings.Builder
+			writeComparison(&b, locations, api.Fahrenheit, lc)
+			if b.String() != tc.expected {
+				t.Errorf("Expected:\n%s\ngot:\n%s", tc.expected, b.String())
+			}
+		})
+	}
+}
+
+func TestMain_runCompare(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/maps/api/geocode/json" {
+			w.Write([]byte(`{"results": [{"formatted_address": "350 5th Ave, New York, NY 10118, USA",
+				"geometry": {"location": {"lat": 40.7484405, "lng": -73.9856644}}}]}`))
+		}
+		if r.URL.Path == "/v1/forecast" {
+			w.Write([]byte(`{"current": {"temperature_2m": 65.0},
+				"daily": {"time": ["2024-09-19"], "temperature_2m_max": [70.1], "temperature_2m_min": [60.2]}}`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.GeocodeURL = server.URL
+	cfg.Providers.ForecastURL = server.URL
+	cfg.Providers.GeocodeAPIKey = "testApiKey"
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	favs.Save(favorites.Place{Name: "hq", Address: "3001 Esperanza Crossing, Austin, TX 78756, USA", Latitude: 30.3985991, Longitude: -97.7220666})
+
+	var b strings.Builder
+	if err := runCompare([]string{"hq", "350 5th Ave, New York"}, cfg, cache.GetCacheInstance(), favs, &b); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(b.String(), "Thu 19    70.1 / 60.2   70.1 / 60.2") {
+		t.Errorf("Expected a row for Thu 19 with both locations, got:\n%s", b.String())
+	}
+
+	if err := runCompare([]string{"hq"}, cfg, cache.GetCacheInstance(), favs, &b); err == nil {
+		t.Error("Expected an error comparing a single location, got nil")
+	}
+}
@@ -0,0 +1,428 @@
+// Package config loads the weather app configuration. Every setting has a default that can be overridden by a JSON
+// config file, then by environment variables, then by command line flags.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/locale"
+)
+
+const (
+	// masked replaces secrets in the output of Masked.
+	masked = "****"
+	// unmaskedSuffix is the number of trailing characters of a secret left visible by Masked.
+	unmaskedSuffix = 4
+)
+
+// Config holds the effective configuration of the weather app.
+type Config struct {
+	// Providers configures the upstream APIs.
+	Providers Providers `json:"providers"`
+	// Units is the unit system forecasts are requested in, either "fahrenheit" or "celsius".
+	Units string `json:"units"`
+	// ForecastDays is the number of days forecast, starting today, from 1 to 16.
+	ForecastDays int `json:"forecast_days"`
+	// PastDays is the number of days before today shown with the forecast, from 0 to 92.
+	PastDays int `json:"past_days"`
+	// Cache configures the forecast cache.
+	Cache Cache `json:"cache"`
+	// Log configures structured logging.
+	Log Log `json:"log"`
+	// Metrics configures the Prometheus metrics listener.
+	Metrics Metrics `json:"metrics"`
+	// Server configures the HTTP API served by the serve command.
+	Server Server `json:"server"`
+	// FavoritesPath is the JSON file saved favorite locations are kept in.
+	FavoritesPath string `json:"favorites_path"`
+	// HistoryPath is the file the prompt's input history is kept in. Empty keeps the history in memory only.
+	HistoryPath string `json:"history_path"`
+	// Locale is the language tag, such as en-US, that dates and decimal separators are displayed in.
+	Locale string `json:"locale"`
+	// AirQuality adds the current air quality and pollen to forecasts.
+	AirQuality bool `json:"air_quality"`
+	// Chart displays the extended forecast as a temperature chart when the output is a terminal.
+	Chart bool `json:"chart"`
+}
+
+// Providers holds the base URLs and credentials of the upstream APIs.
+type Providers struct {
+	// GeocodeURL is the base URL of the Google Geocode API.
+	GeocodeURL string `json:"geocode_url"`
+	// GeocodeAPIKey is the Google Geocode API key.
+	GeocodeAPIKey string `json:"geocode_api_key"`
+	// ForecastURL is the base URL of the Open-Meteo forecast API.
+	ForecastURL string `json:"forecast_url"`
+	// ArchiveURL is the base URL of the Open-Meteo historical weather API.
+	ArchiveURL string `json:"archive_url"`
+	// AirQualityURL is the base URL of the Open-Meteo air quality API.
+	AirQualityURL string `json:"air_quality_url"`
+}
+
+// Cache holds the forecast cache settings.
+type Cache struct {
+	// TTL is how long a forecast is served from the cache.
+	TTL Duration `json:"ttl"`
+	// PurgeInterval is how often expired entries are removed.
+	PurgeInterval Duration `json:"purge_interval"`
+	// RedisAddr is the host:port of a RESP server to share the cache through. Empty keeps the cache in memory.
+	RedisAddr string `json:"redis_addr"`
+}
+
+// Log holds the structured logging settings.
+type Log struct {
+	// Level is the minimum level logged: debug, info, warn or error.
+	Level string `json:"level"`
+	// Format is the output format, either text or json.
+	Format string `json:"format"`
+	// RedactAddresses masks user addresses in logs in addition to API keys.
+	RedactAddresses bool `json:"redact_addresses"`
+}
+
+// Metrics holds the Prometheus metrics listener settings.
+type Metrics struct {
+	// Addr is the address to serve /metrics on. Empty disables the listener.
+	Addr string `json:"addr"`
+}
+
+// Server holds the HTTP API settings.
+type Server struct {
+	// Addr is the address the serve command listens on.
+	Addr string `json:"addr"`
+	// GRPCAddr is the address the serve command serves the gRPC API on. Empty disables the gRPC API.
+	GRPCAddr string `json:"grpc_addr"`
+}
+
+// Duration is a time.Duration that is written to and read from JSON as a string such as "30m".
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30m\": %v", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Defaults returns the configuration used when nothing is overridden.
+func Defaults() Config {
+	return Config{
+		Providers: Providers{
+			GeocodeURL:    "https://maps.googleapis.com",
+			ForecastURL:   "https://api.open-meteo.com",
+			ArchiveURL:    "https://archive-api.open-meteo.com",
+			AirQualityURL: "https://air-quality-api.open-meteo.com",
+		},
+		Units:        "fahrenheit",
+		ForecastDays: api.DefaultForecastDays,
+		Cache: Cache{
+			TTL:           Duration(30 * time.Minute),
+			PurgeInterval: Duration(1 * time.Hour),
+		},
+		Log: Log{
+			Level:  "warn",
+			Format: "text",
+		},
+		Server: Server{
+			Addr: "localhost:8080",
+		},
+		FavoritesPath: userFile("favorites.json"),
+		HistoryPath:   userFile("history"),
+		Locale:        locale.Default,
+	}
+}
+
+// DefaultPath returns the path of the config file read when none is given, which is weather/config.json in the
+// user configuration directory. It returns an empty string if that directory cannot be determined.
+func DefaultPath() string {
+	return userFile("config.json")
+}
+
+// userFile returns the path of the named file in the weather directory of the user configuration directory, or an
+// empty string if that directory cannot be determined.
+func userFile(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "weather", name)
+}
+
+// Load builds the effective configuration from defaults, the config file, environment variables read through getenv
+// and the command line flags in args, in increasing order of precedence. It returns the configuration and the
+// arguments remaining after the flags, such as a command name.
+//
+// The config file is the one given by -config, else WEATHER_CONFIG, else DefaultPath. A missing file is only an
+// error if it was named explicitly.
+func Load(args []string, getenv func(string) string, output io.Writer) (Config, []string, error) {
+	flags := flag.NewFlagSet("weather", flag.ContinueOnError)
+	flags.SetOutput(output)
+	configPath := flags.String("config", "", "path to a JSON config file")
+	units := flags.String("units", "", "unit system: fahrenheit or celsius")
+	forecastDays := flags.Int("forecast-days", 0, "number of days forecast, from 1 to 16")
+	pastDays := flags.Int("past-days", 0, "number of days before today shown with the forecast, from 0 to 92")
+	geocodeURL := flags.String("geocode-url", "", "base URL of the Google Geocode API")
+	forecastURL := flags.String("forecast-url", "", "base URL of the Open-Meteo forecast API")
+	archiveURL := flags.String("archive-url", "", "base URL of the Open-Meteo historical weather API")
+	airQualityURL := flags.String("air-quality-url", "", "base URL of the Open-Meteo air quality API")
+	cacheTTL := flags.Duration("cache-ttl", 0, "how long a forecast is served from the cache")
+	purgeInterval := flags.Duration("purge-interval", 0, "how often expired cache entries are removed")
+	redisAddr := flags.String("redis-addr", "", "host:port of a RESP server to share the cache through")
+	metricsAddr := flags.String("metrics-addr", "", "address to serve Prometheus metrics on")
+	serverAddr := flags.String("server-addr", "", "address the serve command listens on")
+	grpcAddr := flags.String("grpc-addr", "", "address the serve command serves the gRPC API on")
+	logLevel := flags.String("log-level", "", "minimum log level: debug, info, warn or error")
+	logFormat := flags.String("log-format", "", "log format: text or json")
+	redactAddresses := flags.Bool("redact-addresses", false, "mask user addresses in logs")
+	favoritesPath := flags.String("favorites", "", "path to the JSON file favorite locations are kept in")
+	historyPath := flags.String("history", "", "path to the file the prompt's input history is kept in")
+	localeTag := flags.String("locale", "", "language tag dates and numbers are displayed in, such as en-US or de-DE")
+	airQuality := flags.Bool("air-quality", false, "add the current air quality and pollen to forecasts")
+	chart := flags.Bool("chart", false, "display the extended forecast as a temperature chart on terminals")
+	if err := flags.Parse(args); err != nil {
+		return Config{}, nil, err
+	}
+
+	cfg := Defaults()
+
+	// Config file
+	path, explicit := *configPath, true
+	if path == "" {
+		path = getenv("WEATHER_CONFIG")
+	}
+	if path == "" {
+		path, explicit = DefaultPath(), false
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			if explicit || !errors.Is(err, fs.ErrNotExist) {
+				return Config{}, nil, err
+			}
+		}
+	}
+
+	// Environment
+	if err := cfg.loadEnv(getenv); err != nil {
+		return Config{}, nil, err
+	}
+
+	// Flags, only those that were set on the command line
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "units":
+			cfg.Units = *units
+		case "forecast-days":
+			cfg.ForecastDays = *forecastDays
+		case "past-days":
+			cfg.PastDays = *pastDays
+		case "geocode-url":
+			cfg.Providers.GeocodeURL = *geocodeURL
+		case "forecast-url":
+			cfg.Providers.ForecastURL = *forecastURL
+		case "archive-url":
+			cfg.Providers.ArchiveURL = *archiveURL
+		case "air-quality-url":
+			cfg.Providers.AirQualityURL = *airQualityURL
+		case "cache-ttl":
+			cfg.Cache.TTL = Duration(*cacheTTL)
+		case "purge-interval":
+			cfg.Cache.PurgeInterval = Duration(*purgeInterval)
+		case "redis-addr":
+			cfg.Cache.RedisAddr = *redisAddr
+		case "metrics-addr":
+			cfg.Metrics.Addr = *metricsAddr
+		case "server-addr":
+			cfg.Server.Addr = *serverAddr
+		case "grpc-addr":
+			cfg.Server.GRPCAddr = *grpcAddr
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		case "log-format":
+			cfg.Log.Format = *logFormat
+		case "redact-addresses":
+			cfg.Log.RedactAddresses = *redactAddresses
+		case "favorites":
+			cfg.FavoritesPath = *favoritesPath
+		case "history":
+			cfg.HistoryPath = *historyPath
+		case "locale":
+			cfg.Locale = *localeTag
+		case "air-quality":
+			cfg.AirQuality = *airQuality
+		case "chart":
+			cfg.Chart = *chart
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, nil, err
+	}
+	return cfg, flags.Args(), nil
+}
+
+// loadFile overrides cfg with the settings present in the JSON file at path. Settings absent from the file keep
+// their current value.
+func (cfg *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("error parsing config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides cfg with the environment variables that are set.
+func (cfg *Config) loadEnv(getenv func(string) string) error {
+	stringVars := map[string]*string{
+		"GEOCODE_API_KEY":          &cfg.Providers.GeocodeAPIKey,
+		"WEATHER_GEOCODE_URL":      &cfg.Providers.GeocodeURL,
+		"WEATHER_FORECAST_URL":     &cfg.Providers.ForecastURL,
+		"WEATHER_ARCHIVE_URL":      &cfg.Providers.ArchiveURL,
+		"WEATHER_AIR_QUALITY_URL":  &cfg.Providers.AirQualityURL,
+		"WEATHER_UNITS":            &cfg.Units,
+		"WEATHER_CACHE_REDIS_ADDR": &cfg.Cache.RedisAddr,
+		"WEATHER_METRICS_ADDR":     &cfg.Metrics.Addr,
+		"WEATHER_SERVER_ADDR":      &cfg.Server.Addr,
+		"WEATHER_GRPC_ADDR":        &cfg.Server.GRPCAddr,
+		"WEATHER_LOG_LEVEL":        &cfg.Log.Level,
+		"WEATHER_LOG_FORMAT":       &cfg.Log.Format,
+		"WEATHER_FAVORITES":        &cfg.FavoritesPath,
+		"WEATHER_HISTORY":          &cfg.HistoryPath,
+		"WEATHER_LOCALE":           &cfg.Locale,
+	}
+	for name, field := range stringVars {
+		if v := getenv(name); v != "" {
+			*field = v
+		}
+	}
+
+	intVars := map[string]*int{
+		"WEATHER_FORECAST_DAYS": &cfg.ForecastDays,
+		"WEATHER_PAST_DAYS":     &cfg.PastDays,
+	}
+	for name, field := range intVars {
+		if v := getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*field = n
+		}
+	}
+
+	durationVars := map[string]*Duration{
+		"WEATHER_CACHE_TTL":            &cfg.Cache.TTL,
+		"WEATHER_CACHE_PURGE_INTERVAL": &cfg.Cache.PurgeInterval,
+	}
+	for name, field := range durationVars {
+		if v := getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*field = Duration(d)
+		}
+	}
+
+	boolVars := map[string]*bool{
+		"WEATHER_LOG_REDACT_ADDRESSES": &cfg.Log.RedactAddresses,
+		"WEATHER_CHART":                &cfg.Chart,
+		"WEATHER_AIR_QUALITY":          &cfg.AirQuality,
+	}
+	for name, field := range boolVars {
+		if v := getenv(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*field = b
+		}
+	}
+	return nil
+}
+
+// Validate reports the first setting that has an invalid value.
+func (cfg Config) Validate() error {
+	switch {
+	case cfg.Units != "fahrenheit" && cfg.Units != "celsius":
+		return fmt.Errorf("invalid units %q: must be fahrenheit or celsius", cfg.Units)
+	case cfg.Providers.GeocodeURL == "":
+		return errors.New("geocode URL must not be empty")
+	case cfg.Providers.ForecastURL == "":
+		return errors.New("forecast URL must not be empty")
+	case cfg.Providers.ArchiveURL == "":
+		return errors.New("archive URL must not be empty")
+	case cfg.Providers.AirQualityURL == "":
+		return errors.New("air quality URL must not be empty")
+	case cfg.Cache.TTL <= 0:
+		return fmt.Errorf("invalid cache TTL %s: must be positive", time.Duration(cfg.Cache.TTL))
+	case cfg.Cache.PurgeInterval <= 0:
+		return fmt.Errorf("invalid cache purge interval %s: must be positive", time.Duration(cfg.Cache.PurgeInterval))
+	case cfg.Server.Addr == "":
+		return errors.New("server address must not be empty")
+	case cfg.Log.Format != "text" && cfg.Log.Format != "json":
+		return fmt.Errorf("invalid log format %q: must be text or json", cfg.Log.Format)
+	}
+	// Zero forecast days would select the API default, so it is rejected here rather than passed on
+	if cfg.ForecastDays == 0 {
+		return fmt.Errorf("invalid forecast days 0: must be between 1 and %d", api.MaxForecastDays)
+	}
+	if err := (api.ForecastOptions{ForecastDays: cfg.ForecastDays, PastDays: cfg.PastDays}).Validate(); err != nil {
+		return err
+	}
+	if _, err := locale.Lookup(cfg.Locale); err != nil {
+		return err
+	}
+
+	switch strings.ToLower(cfg.Log.Level) {
+	case "debug", "info", "warn", "error":
+		return nil
+	default:
+		return fmt.Errorf("invalid log level %q: must be debug, info, warn or error", cfg.Log.Level)
+	}
+}
+
+// Masked returns a copy of cfg with secrets masked so that it can be displayed.
+func (cfg Config) Masked() Config {
+	cfg.Providers.GeocodeAPIKey = maskSecret(cfg.Providers.GeocodeAPIKey)
+	return cfg
+}
+
+// maskSecret hides all but the last few characters of a secret. Short secrets are hidden entirely.
+func maskSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	if len(secret) <= 2*unmaskedSuffix {
+		return masked
+	}
+	return masked + secret[len(secret)-unmaskedSuffix:]
+}
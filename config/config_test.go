@@ -0,0 +1,240 @@
+package config
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes contents to a config file in a temporary directory and returns its path.
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("Expected no error writing config file, got %v", err)
+	}
+	return path
+}
+
+// envFrom returns a getenv function that reads from the given map.
+func envFrom(env map[string]string) func(string) string {
+	return func(name string) string { return env[name] }
+}
+
+func TestConfig_Load(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"providers": {"geocode_api_key": "file-key", "forecast_url": "http://file.example"},
+		"units": "celsius",
+		"cache": {"ttl": "10m", "purge_interval": "20m"},
+		"log": {"level": "info"}
+	}`)
+
+	tc := []struct {
+		name     string
+		args     []string
+		env      map[string]string
+		expected func(Config) Config
+		rest     []string
+		err      string
+	}{
+		{
+			name:     "Defaults",
+			args:     []string{"-config", writeConfigFile(t, `{}`)},
+			expected: func(cfg Config) Config { return cfg },
+		},
+		{
+			name: "File Over Defaults",
+			args: []string{"-config", path},
+			expected: func(cfg Config) Config {
+				cfg.Providers.GeocodeAPIKey = "file-key"
+				cfg.Providers.ForecastURL = "http://file.example"
+				cfg.Units = "celsius"
+				cfg.Cache.TTL = Duration(10 * time.Minute)
+				cfg.Cache.PurgeInterval = Duration(20 * time.Minute)
+				cfg.Log.Level = "info"
+				return cfg
+			},
+		},
+		{
+			name: "Env Over File",
+			args: []string{},
+			env: map[string]string{
+				"WEATHER_CONFIG":               path,
+				"GEOCODE_API_KEY":              "env-key",
+				"WEATHER_CACHE_TTL":            "5m",
+				"WEATHER_CHART":                "true",
+				"WEATHER_PAST_DAYS":            "2",
+				"WEATHER_HISTORY":              "/tmp/weather-history",
+				"WEATHER_SERVER_ADDR":          ":9090",
+				"WEATHER_CACHE_REDIS_ADDR":     "localhost:6379",
+				"WEATHER_METRICS_ADDR":         "localhost:9100",
+				"WEATHER_LOG_FORMAT":           "json",
+				"WEATHER_LOG_REDACT_ADDRESSES": "true",
+			},
+			expected: func(cfg Config) Config {
+				cfg.Providers.GeocodeAPIKey = "env-key"
+				cfg.Server.Addr = ":9090"
+				cfg.Cache.RedisAddr = "localhost:6379"
+				cfg.Metrics.Addr = "localhost:9100"
+				cfg.Log.Format = "json"
+				cfg.Log.RedactAddresses = true
+				cfg.HistoryPath = "/tmp/weather-history"
+				cfg.PastDays = 2
+				cfg.Chart = true
+				cfg.Providers.ForecastURL = "http://file.example"
+				cfg.Units = "celsius"
+				cfg.Cache.TTL = Duration(5 * time.Minute)
+				cfg.Cache.PurgeInterval = Duration(20 * time.Minute)
+				cfg.Log.Level = "info"
+				return cfg
+			},
+		},
+		{
+			name: "Unprefixed Env Ignored",
+			args: []string{"-config", path},
+			env: map[string]string{
+				"CACHE_REDIS_ADDR": "localhost:6379",
+				"METRICS_ADDR":     "localhost:9100",
+				"LOG_LEVEL":        "debug",
+				"LOG_FORMAT":       "json",
+			},
+			expected: func(cfg Config) Config {
+				cfg.Providers.GeocodeAPIKey = "file-key"
+				cfg.Providers.ForecastURL = "http://file.example"
+				cfg.Units = "celsius"
+				cfg.Cache.TTL = Duration(10 * time.Minute)
+				cfg.Cache.PurgeInterval = Duration(20 * time.Minute)
+				cfg.Log.Level = "info"
+				return cfg
+			},
+		},
+		{
+			name: "Flags Over Env",
+			args: []string{"-config", path, "-units", "fahrenheit", "-cache-ttl", "1m", "-locale", "de-DE", "-forecast-days", "14", "-grpc-addr", ":9443", "config", "show"},
+			env: map[string]string{
+				"WEATHER_UNITS":     "celsius",
+				"WEATHER_CACHE_TTL": "5m",
+			},
+			expected: func(cfg Config) Config {
+				cfg.Providers.GeocodeAPIKey = "file-key"
+				cfg.Providers.ForecastURL = "http://file.example"
+				cfg.Cache.TTL = Duration(1 * time.Minute)
+				cfg.Cache.PurgeInterval = Duration(20 * time.Minute)
+				cfg.Log.Level = "info"
+				cfg.Locale = "de-DE"
+				cfg.ForecastDays = 14
+				cfg.Server.GRPCAddr = ":9443"
+				return cfg
+			},
+			rest: []string{"config", "show"},
+		},
+		{
+			name: "Missing Explicit File",
+			args: []string{"-config", filepath.Join(t.TempDir(), "missing.json")},
+			err:  "error reading config file",
+		},
+		{
+			name: "Unknown Field",
+			args: []string{"-config", writeConfigFile(t, `{"unit": "celsius"}`)},
+			err:  `unknown field "unit"`,
+		},
+		{
+			name: "Invalid Units",
+			args: []string{"-config", path, "-units", "kelvin"},
+			err:  `invalid units "kelvin"`,
+		},
+		{
+			name: "Invalid Forecast Days",
+			args: []string{"-config", path, "-forecast-days", "17"},
+			err:  "invalid forecast days 17",
+		},
+		{
+			name: "Invalid Past Days",
+			args: []string{"-config", path},
+			env:  map[string]string{"WEATHER_PAST_DAYS": "-1"},
+			err:  "invalid past days -1",
+		},
+		{
+			name: "Invalid Locale",
+			args: []string{"-config", path, "-locale", "xx-YY"},
+			err:  `unsupported locale "xx-YY"`,
+		},
+		{
+			name: "Invalid Env Bool",
+			args: []string{"-config", path},
+			env:  map[string]string{"WEATHER_CHART": "sometimes"},
+			err:  "invalid WEATHER_CHART",
+		},
+		{
+			name: "Invalid Env Redact Addresses",
+			args: []string{"-config", path},
+			env:  map[string]string{"WEATHER_LOG_REDACT_ADDRESSES": "maybe"},
+			err:  "invalid WEATHER_LOG_REDACT_ADDRESSES",
+		},
+		{
+			name: "Invalid Env Duration",
+			args: []string{"-config", path},
+			env:  map[string]string{"WEATHER_CACHE_TTL": "soon"},
+			err:  "invalid WEATHER_CACHE_TTL",
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, rest, err := Load(tc.args, envFrom(tc.env), io.Discard)
+			// Check for error cases
+			if tc.err != "" {
+				if err == nil {
+					t.Fatalf("Expected an error containing '%s', got nil", tc.err)
+				}
+				if !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Expected an error containing '%s', got %s", tc.err, err.Error())
+				}
+				return
+			}
+
+			// Check for success cases
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if expected := tc.expected(Defaults()); cfg != expected {
+				t.Errorf("Expected %+v, got %+v", expected, cfg)
+			}
+			if strings.Join(rest, " ") != strings.Join(tc.rest, " ") {
+				t.Errorf("Expected remaining args %v, got %v", tc.rest, rest)
+			}
+		})
+	}
+}
+
+func TestConfig_Masked(t *testing.T) {
+	cfg := Defaults()
+	cfg.Providers.GeocodeAPIKey = "AIzaSyExampleKey1234"
+
+	out, err := json.Marshal(cfg.Masked())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(string(out), "AIzaSyExampleKey") {
+		t.Errorf("Expected the API key to be masked, got %s", out)
+	}
+	if !strings.Contains(string(out), `"geocode_api_key":"****1234"`) {
+		t.Errorf("Expected the masked key to keep its last 4 characters, got %s", out)
+	}
+	if !strings.Contains(string(out), `"ttl":"30m0s"`) {
+		t.Errorf("Expected durations as strings, got %s", out)
+	}
+	if cfg.Providers.GeocodeAPIKey != "AIzaSyExampleKey1234" {
+		t.Error("Expected Masked to leave the original configuration untouched")
+	}
+
+	short := Defaults()
+	short.Providers.GeocodeAPIKey = "abc"
+	if key := short.Masked().Providers.GeocodeAPIKey; key != "****" {
+		t.Errorf("Expected a short key to be fully masked, got %s", key)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/logging"
+)
+
+// promptCommands are the words the prompt understands as commands, which therefore cannot be favorite names.
+var promptCommands = map[string]bool{"q": true, "cache": true, "favs": true, "save": true, "unsave": true}
+
+// lookupForecast retrieves the forecast for a favorite name or an address. A favorite name skips geocoding, anything
+// else is treated as an address and resolved through getForecast.
+func lookupForecast(ctx context.Context, input string, favs *favorites.Store, c *cache.Cache, cfg config.Config, opts api.ForecastOptions) (string, float64, api.WeeklyForecast, bool, error) {
+	if place, ok := favs.Get(input); ok {
+		currentTemp, weeklyForecast, isFromCache, err := getForecastForCoordinates(ctx, place.Address, place.Latitude, place.Longitude, c, cfg.Providers.ForecastURL, opts)
+		return place.Address, currentTemp, weeklyForecast, isFromCache, err
+	}
+	return getForecast(ctx, input, c, cfg.Providers.GeocodeURL, cfg.Providers.ForecastURL, cfg.Providers.GeocodeAPIKey, opts)
+}
+
+// lookupCoordinates resolves a favorite name or an address to its full address and coordinates. A favorite name skips
+// geocoding.
+func lookupCoordinates(ctx context.Context, input string, favs *favorites.Store, cfg config.Config) (string, float64, float64, error) {
+	if place, ok := favs.Get(input); ok {
+		return place.Address, place.Latitude, place.Longitude, nil
+	}
+
+	addressFull, lat, lng, err := api.AddressToCoordinatesContext(ctx, input, cfg.Providers.GeocodeURL, cfg.Providers.GeocodeAPIKey)
+	if err != nil || addressFull == "" || lat == 0 || lng == 0 {
+		return "", 0, 0, fmt.Errorf("error retrieving coordinates: %v", err)
+	}
+	return addressFull, lat, lng, nil
+}
+
+// saveFavorite geocodes the address and saves the resolved address and coordinates under name, so that later
+// lookups by name skip geocoding.
+func saveFavorite(ctx context.Context, favs *favorites.Store, name string, address string, geocodeURL string, apiKey string) (favorites.Place, error) {
+	if promptCommands[strings.ToLower(name)] {
+		return favorites.Place{}, fmt.Errorf("%q is a prompt command and cannot be used as a favorite name", name)
+	}
+	if !favorites.ValidName(name) {
+		return favorites.Place{}, fmt.Errorf("invalid favorite name %q: use letters, digits, dashes and underscores only", name)
+	}
+
+	addressFull, lat, lng, err := api.AddressToCoordinatesContext(ctx, address, geocodeURL, apiKey)
+	if err != nil || addressFull == "" || lat == 0 || lng == 0 {
+		return favorites.Place{}, fmt.Errorf("error retrieving coordinates: %v", err)
+	}
+
+	place := favorites.Place{Name: name, Address: addressFull, Latitude: lat, Longitude: lng}
+	if err := favs.Save(place); err != nil {
+		return favorites.Place{}, err
+	}
+	return place, nil
+}
+
+// favoriteSummary returns a one-line summary of the current conditions at a favorite.
+func favoriteSummary(ctx context.Context, place favorites.Place, c *cache.Cache, forecastURL string, opts api.ForecastOptions) string {
+	currentTemp, weeklyForecast, _, err := getForecastForCoordinates(ctx, place.Address, place.Latitude, place.Longitude, c, forecastURL, opts)
+	if err != nil {
+		return fmt.Sprintf("%s: %s", place.Name, err)
+	}
+	today := todayIndex(weeklyForecast)
+	if today >= len(weeklyForecast.Temperature2MMax) || today >= len(weeklyForecast.Temperature2MMin) {
+		return fmt.Sprintf("%s: %.1f %s, forecast data is unavailable (%s)", place.Name, currentTemp, opts.Units.Symbol(), place.Address)
+	}
+
+	return fmt.Sprintf("%s: %.1f %s, high %.1f / low %.1f (%s)", place.Name, currentTemp, opts.Units.Symbol(),
+		weeklyForecast.Temperature2MMax[today], weeklyForecast.Temperature2MMin[today], place.Address)
+}
+
+// displayFavorites fetches the forecast of every favorite in parallel and displays a one-line summary for each,
+// in name order.
+func displayFavorites(favs *favorites.Store, c *cache.Cache, forecastURL string, opts api.ForecastOptions) {
+	places := favs.List()
+
+	fmt.Println()
+	fmt.Println("Favorites: ")
+	fmt.Println("---------------------------")
+	if len(places) == 0 {
+		fmt.Println("No favorites saved yet. To save one, enter save <name> <address>")
+		fmt.Println()
+		return
+	}
+
+	summaries := make([]string, len(places))
+	var wg sync.WaitGroup
+	for i, place := range places {
+		wg.Add(1)
+		go func(i int, place favorites.Place) {
+			defer wg.Done()
+			ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+			summaries[i] = favoriteSummary(ctx, place, c, forecastURL, opts)
+		}(i, place)
+	}
+	wg.Wait()
+
+	for _, summary := range summaries {
+		fmt.Println(summary)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,158 @@
+// Package favorites stores named places, such as "hq", together with their resolved address and coordinates so that
+// looking them up again skips geocoding. Favorites are persisted as JSON in a local file.
+package favorites
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mfryhover/weather/atomicfile"
+)
+
+// namePattern matches valid favorite names: a single word of letters, digits, dashes and underscores.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Place is a saved location.
+type Place struct {
+	// Name is the name the place was saved under. Names are case-insensitive.
+	Name string `json:"name"`
+	// Address is the full formatted address returned by the geocode API.
+	Address string `json:"address"`
+	// Latitude is the latitude of the place.
+	Latitude float64 `json:"latitude"`
+	// Longitude is the longitude of the place.
+	Longitude float64 `json:"longitude"`
+}
+
+// Store holds the saved places and persists every change to its file.
+type Store struct {
+	// path is the JSON file the places are persisted to.
+	path string
+	// mu protects places and serializes writes to the file.
+	mu sync.RWMutex
+	// places maps lowercase names to places.
+	places map[string]Place
+}
+
+// Load reads the places saved in the file at path. A missing file yields an empty store that is created on the
+// first save.
+func Load(path string) (*Store, error) {
+	s := &Store{
+		path:   path,
+		places: make(map[string]Place),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading favorites: %v", err)
+	}
+
+	var places []Place
+	if err := json.Unmarshal(data, &places); err != nil {
+		return nil, fmt.Errorf("error unmarshalling favorites: %v", err)
+	}
+	for _, p := range places {
+		s.places[strings.ToLower(p.Name)] = p
+	}
+	return s, nil
+}
+
+// ValidName reports whether name can be used as a favorite name.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Get returns the place saved under name, ignoring case.
+// It is safe for concurrent use.
+func (s *Store) Get(name string) (Place, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	p, ok := s.places[strings.ToLower(name)]
+	return p, ok
+}
+
+// List returns every saved place sorted by name.
+// It is safe for concurrent use.
+func (s *Store) List() []Place {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	places := make([]Place, 0, len(s.places))
+	for _, p := range s.places {
+		places = append(places, p)
+	}
+	sort.Slice(places, func(i, j int) bool { return strings.ToLower(places[i].Name) < strings.ToLower(places[j].Name) })
+	return places
+}
+
+// Save adds or replaces the place saved under p.Name and writes the store to its file.
+// It is safe for concurrent use.
+func (s *Store) Save(p Place) error {
+	if !ValidName(p.Name) {
+		return fmt.Errorf("invalid favorite name %q: use letters, digits, dashes and underscores only", p.Name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.places[strings.ToLower(p.Name)]
+	s.places[strings.ToLower(p.Name)] = p
+	if err := s.write(); err != nil {
+		// Keep memory consistent with the file
+		if existed {
+			s.places[strings.ToLower(p.Name)] = previous
+		} else {
+			delete(s.places, strings.ToLower(p.Name))
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove deletes the place saved under name and writes the store to its file. It returns false if there was none.
+// It is safe for concurrent use.
+func (s *Store) Remove(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.places[strings.ToLower(name)]
+	if !ok {
+		return false, nil
+	}
+	delete(s.places, strings.ToLower(name))
+	if err := s.write(); err != nil {
+		s.places[strings.ToLower(name)] = p
+		return false, err
+	}
+	return true, nil
+}
+
+// write persists the places to the store file, replacing it atomically. The caller must hold s.mu.
+func (s *Store) write() error {
+	places := make([]Place, 0, len(s.places))
+	for _, p := range s.places {
+		places = append(places, p)
+	}
+	sort.Slice(places, func(i, j int) bool { return strings.ToLower(places[i].Name) < strings.ToLower(places[j].Name) })
+
+	data, err := json.MarshalIndent(places, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling favorites: %v", err)
+	}
+
+	if err := atomicfile.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("error writing favorites: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package favorites
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFavorites_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weather", "favorites.json")
+
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error loading a missing file, got %v", err)
+	}
+	if len(s.List()) != 0 {
+		t.Errorf("Expected no favorites, got %v", s.List())
+	}
+
+	hq := Place{Name: "hq", Address: "3001 Esperanza Crossing, Austin, TX 78758, USA", Latitude: 30.3985991, Longitude: -97.72206659999999}
+	nyc := Place{Name: "NYC", Address: "New York, NY 10001, USA", Latitude: 40.7536854, Longitude: -73.9991637}
+	for _, p := range []Place{hq, nyc} {
+		if err := s.Save(p); err != nil {
+			t.Fatalf("Expected no error saving %s, got %v", p.Name, err)
+		}
+	}
+
+	// Reload from disk to check the favorites were persisted
+	s, err = Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p, ok := s.Get("HQ"); !ok || p != hq {
+		t.Errorf("Expected %+v for a case-insensitive lookup, got %+v", hq, p)
+	}
+	list := s.List()
+	if len(list) != 2 || list[0].Name != "hq" || list[1].Name != "NYC" {
+		t.Errorf("Expected [hq NYC] sorted by name, got %+v", list)
+	}
+
+	removed, err := s.Remove("nyc")
+	if err != nil || !removed {
+		t.Errorf("Expected nyc to be removed, got %t, %v", removed, err)
+	}
+	if removed, _ := s.Remove("nyc"); removed {
+		t.Error("Expected removing a missing favorite to report false")
+	}
+	s, _ = Load(path)
+	if _, ok := s.Get("nyc"); ok {
+		t.Error("Expected nyc to stay removed after reloading")
+	}
+}
+
+func TestFavorites_InvalidName(t *testing.T) {
+	s, _ := Load(filepath.Join(t.TempDir(), "favorites.json"))
+	for _, name := range []string{"", "head office", "hq!"} {
+		if err := s.Save(Place{Name: name}); err == nil {
+			t.Errorf("Expected an error saving invalid name %q, got nil", name)
+		}
+	}
+}
+
+func TestFavorites_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "favorites.json")
+	if err := os.WriteFile(path, []byte(`{`), 0o600); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error loading a corrupt file, got nil")
+	}
+}
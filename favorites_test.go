@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/favorites"
+)
+
+func TestMain_saveFavorite(t *testing.T) {
+	var geocodeRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/maps/api/geocode/json" {
+			geocodeRequests.Add(1)
+			w.Write([]byte(`{"results": [{"formatted_address": "3001 Esperanza Crossing, Austin, TX 78759, USA",
+				"geometry": {"location": {"lat": 30.3985991, "lng": -97.72206659999999}}}]}`))
+		}
+		if r.URL.Path == "/v1/forecast" {
+			w.Write([]byte(`{"current": {"temperature_2m": 78.6},
+				"daily": {"time": ["2024-09-19"], "temperature_2m_max": [97.6], "temperature_2m_min": [75.8]}}`))
+		}
+	}))
+	defer server.Close()
+
+	favs, err := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	place, err := saveFavorite(context.Background(), favs, "hq", "3001 Esperanza Crossing, Austin, TX", server.URL, "testApiKey")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if place.Address != "3001 Esperanza Crossing, Austin, TX 78759, USA" || place.Latitude != 30.3985991 {
+		t.Errorf("Expected the resolved address and coordinates to be saved, got %+v", place)
+	}
+	if _, ok := favs.Get("hq"); !ok {
+		t.Error("Expected hq to be saved")
+	}
+
+	// Looking up the favorite must not geocode again
+	summary := favoriteSummary(context.Background(), place, cache.GetCacheInstance(), server.URL, api.ForecastOptions{})
+	if !strings.HasPrefix(summary, "hq: 78.6 F, high 97.6 / low 75.8") {
+		t.Errorf("Expected a one-line summary for hq, got %s", summary)
+	}
+	if geocodeRequests.Load() != 1 {
+		t.Errorf("Expected 1 geocode request, got %d", geocodeRequests.Load())
+	}
+
+	displayFavorites(favs, cache.GetCacheInstance(), server.URL, api.ForecastOptions{})
+
+	if _, err := saveFavorite(context.Background(), favs, "favs", "Austin, TX", server.URL, "testApiKey"); err == nil {
+		t.Error("Expected an error saving a favorite named after a prompt command, got nil")
+	}
+	if _, err := saveFavorite(context.Background(), favs, "head office", "Austin, TX", server.URL, "testApiKey"); err == nil {
+		t.Error("Expected an error saving an invalid favorite name, got nil")
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/logging"
+)
+
+// forecastRecord is the forecast of a location as served by the HTTP API.
+type forecastRecord struct {
+	// Address is the full formatted address.
+	Address string `json:"address"`
+	// CurrentTemp is the current temperature.
+	CurrentTemp float64 `json:"current_temp"`
+	// Units is the unit system of the temperatures.
+	Units api.Units `json:"units"`
+	// Daily holds the forecast for each day.
+	Daily []batchDay `json:"daily"`
+}
+
+// newForecastRecord returns the record of the given forecast.
+func newForecastRecord(addressFull string, units api.Units, currentTemp float64, weeklyForecast api.WeeklyForecast) forecastRecord {
+	return forecastRecord{
+		Address:     addressFull,
+		CurrentTemp: currentTemp,
+		Units:       units,
+		Daily:       newBatchDays(weeklyForecast, time.Now()),
+	}
+}
+
+// parseForecastQuery returns the address and the forecast options selected by the address, units and days parameters
+// of a request. The units and days default to the configuration.
+func parseForecastQuery(cfg config.Config, query url.Values) (string, api.ForecastOptions, error) {
+	opts := forecastOptions(cfg)
+	address := query.Get("address")
+	if address == "" {
+		return "", opts, errors.New("missing address")
+	}
+	if units := query.Get("units"); units != "" {
+		if units != string(api.Fahrenheit) && units != string(api.Celsius) {
+			return "", opts, fmt.Errorf("invalid units %q: must be fahrenheit or celsius", units)
+		}
+		opts.Units = api.Units(units)
+	}
+	if days := query.Get("days"); days != "" {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return "", opts, fmt.Errorf("invalid forecast days %q", days)
+		}
+		opts.ForecastDays = n
+	}
+	return address, opts, opts.Validate()
+}
+
+// forecastETag returns the strong entity tag of a response body, derived from its content.
+func forecastETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// forecastHandler returns the handler of /v1/forecast, which serves the forecast of the address parameter as JSON
+// with HTTP caching semantics: the ETag is derived from the forecast, Last-Modified is when it was cached, and
+// Cache-Control allows clients to keep it for as long as it remains in the cache. Conditional requests with
+// If-None-Match or If-Modified-Since are answered with 304 Not Modified when the forecast has not changed. The
+// units and days parameters select the forecast, and default to the configuration.
+func forecastHandler(cfg config.Config, c *cache.Cache, favs *favorites.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		address, opts, err := parseForecastQuery(cfg, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := logging.WithRequestID(r.Context(), logging.NewRequestID())
+		addressFull, lat, lng, err := lookupCoordinates(ctx, address, favs, cfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		entry, _, err := getForecastEntry(ctx, addressFull, lat, lng, c, cfg.Providers.ForecastURL, opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		body, err := json.Marshal(newForecastRecord(addressFull, opts.Units, entry.CurrentTemp, entry.WeeklyForecast))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// The max-age is rounded down so that clients never keep the forecast longer than the cache does
+		maxAge := int(entry.TTL.Seconds())
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", forecastETag(body))
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+		http.ServeContent(w, r, "", entry.Timestamp, bytes.NewReader(body))
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+)
+
+func TestMain_forecastHandler(t *testing.T) {
+	var forecastRequests atomic.Int32
+	today := time.Now().UTC().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forecastRequests.Add(1)
+		fmt.Fprintf(w, `{"timezone": "UTC", "current": {"temperature_2m": 76.4},
+			"daily": {"time": ["%s"], "temperature_2m_max": [94.7], "temperature_2m_min": [72.9]}}`, today)
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ForecastURL = server.URL
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	favs.Save(favorites.Place{Name: "depot", Address: "3 Etag Ave, Austin, TX 78747, USA", Latitude: 30.1301, Longitude: -97.7611})
+	apiServer := httptest.NewServer(newServeMux(context.Background(), cfg, cache.GetCacheInstance(), favs))
+	defer apiServer.Close()
+
+	// get requests the forecast of the depot with the given request headers
+	get := func(t *testing.T, method string, query string, headers map[string]string) *http.Response {
+		req, _ := http.NewRequest(method, apiServer.URL+"/v1/forecast?address=depot"+query, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := get(t, http.MethodGet, "", nil)
+	body, _ := io.ReadAll(resp.Body)
+	expected := `{"address":"3 Etag Ave, Austin, TX 78747, USA","current_temp":76.4,"units":"fahrenheit","daily":[{"date":"` +
+		today + `","max":94.7,"min":72.9}]}`
+	if resp.StatusCode != http.StatusOK || string(body) != expected {
+		t.Fatalf("Expected status 200 and %s, got %d and %s", expected, resp.StatusCode, body)
+	}
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if !strings.HasPrefix(etag, `"`) || len(etag) != 34 {
+		t.Errorf("Expected a strong ETag, got %q", etag)
+	}
+	if modified, err := http.ParseTime(lastModified); err != nil || time.Since(modified) > time.Minute {
+		t.Errorf("Expected Last-Modified to be when the forecast was cached, got %q", lastModified)
+	}
+	maxAge, err := strconv.Atoi(strings.TrimPrefix(resp.Header.Get("Cache-Control"), "max-age="))
+	if err != nil || maxAge <= 1790 || maxAge >= 1800 {
+		t.Errorf("Expected a max-age of the remaining TTL, got %q", resp.Header.Get("Cache-Control"))
+	}
+
+	tc := []struct {
+		name    string
+		method  string
+		query   string
+		headers map[string]string
+		status  int
+		etag    string
+	}{
+		{name: "Matching ETag", method: http.MethodGet, headers: map[string]string{"If-None-Match": etag}, status: http.StatusNotModified, etag: etag},
+		{name: "Weak ETag In List", method: http.MethodGet, headers: map[string]string{"If-None-Match": `"other", W/` + etag}, status: http.StatusNotModified, etag: etag},
+		{name: "Any ETag", method: http.MethodGet, headers: map[string]string{"If-None-Match": "*"}, status: http.StatusNotModified, etag: etag},
+		{name: "Changed ETag", method: http.MethodGet, headers: map[string]string{"If-None-Match": `"other"`}, status: http.StatusOK, etag: etag},
+		{name: "Not Modified Since", method: http.MethodGet, headers: map[string]string{"If-Modified-Since": lastModified}, status: http.StatusNotModified, etag: etag},
+		{name: "HEAD", method: http.MethodHead, status: http.StatusOK, etag: etag},
+		{name: "Other Units", method: http.MethodGet, query: "&units=celsius", headers: map[string]string{"If-None-Match": etag}, status: http.StatusOK},
+		{name: "Invalid Days", method: http.MethodGet, query: "&days=17", status: http.StatusBadRequest},
+		{name: "Method Not Allowed", method: http.MethodPost, status: http.StatusMethodNotAllowed},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := get(t, tc.method, tc.query, tc.headers)
+			if resp.StatusCode != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, resp.StatusCode)
+			}
+			if tc.etag != "" && resp.Header.Get("ETag") != tc.etag {
+				t.Errorf("Expected ETag %s, got %s", tc.etag, resp.Header.Get("ETag"))
+			}
+			if tc.etag == "" && resp.Header.Get("ETag") == etag {
+				t.Errorf("Expected an ETag other than %s", etag)
+			}
+		})
+	}
+	if forecastRequests.Load() != 2 {
+		t.Errorf("Expected 2 forecast requests, got %d", forecastRequests.Load())
+	}
+}
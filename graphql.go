@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/graphql"
+)
+
+const (
+	// defaultGraphQLHours is the number of hours returned by the hourly field of a forecast when not given.
+	defaultGraphQLHours = 24
+	// graphqlMaxLookups is the number of distinct locations, and of distinct forecasts, one request can look up, which
+	// bounds the upstream requests a single query can cause.
+	graphqlMaxLookups = 20
+	// graphqlParallel is the number of lookups of a batch run at the same time.
+	graphqlParallel = 4
+)
+
+// graphqlLocation is a geocoded address or favorite, the value of the Location type.
+type graphqlLocation struct {
+	Address   string
+	Latitude  float64
+	Longitude float64
+}
+
+// graphqlForecastKey identifies a forecast loaded by a GraphQL request. The address is only used to derive the cache
+// key, like in getForecastForCoordinates, and is empty for forecasts requested by coordinates.
+type graphqlForecastKey struct {
+	Address   string
+	Latitude  float64
+	Longitude float64
+	Opts      api.ForecastOptions
+}
+
+// graphqlForecast is a forecast, the value of the Forecast type.
+type graphqlForecast struct {
+	Key         graphqlForecastKey
+	CurrentTemp float64
+	Weekly      api.WeeklyForecast
+	IsFromCache bool
+}
+
+// graphqlLoaders are the loaders of one GraphQL request, which batch its geocoding and forecast lookups so that each
+// address and forecast is only requested once, however many fields select it.
+type graphqlLoaders struct {
+	locations *graphql.Loader[string, graphqlLocation]
+	forecasts *graphql.Loader[graphqlForecastKey, graphqlForecast]
+}
+
+// graphqlLoadersKey is the context key of the loaders of a request.
+type graphqlLoadersKey struct{}
+
+// newGraphQLLoaders returns the loaders of a request, which look up locations and forecasts through the favorites,
+// the geocoding and forecast APIs and the cache. A request looks up at most graphqlMaxLookups locations and forecasts,
+// and the keys of a batch are looked up concurrently, at most graphqlParallel at a time.
+func newGraphQLLoaders(cfg config.Config, c *cache.Cache, favs *favorites.Store) *graphqlLoaders {
+	return &graphqlLoaders{
+		locations: graphql.NewLoader(func(ctx context.Context, addresses []string) ([]graphqlLocation, []error) {
+			slog.DebugContext(ctx, "graphql batch", "loader", "locations", "keys", len(addresses))
+			locations := make([]graphqlLocation, len(addresses))
+			errs := make([]error, len(addresses))
+			forEachParallel(len(addresses), graphqlParallel, func(i int) {
+				addressFull, lat, lng, err := lookupCoordinates(ctx, addresses[i], favs, cfg)
+				locations[i], errs[i] = graphqlLocation{Address: addressFull, Latitude: lat, Longitude: lng}, err
+			})
+			return locations, errs
+		}, graphqlMaxLookups),
+		forecasts: graphql.NewLoader(func(ctx context.Context, keys []graphqlForecastKey) ([]graphqlForecast, []error) {
+			slog.DebugContext(ctx, "graphql batch", "loader", "forecasts", "keys", len(keys))
+			forecasts := make([]graphqlForecast, len(keys))
+			errs := make([]error, len(keys))
+			forEachParallel(len(keys), graphqlParallel, func(i int) {
+				key := keys[i]
+				currentTemp, weeklyForecast, isFromCache, err := getForecastForCoordinates(ctx, key.Address, key.Latitude, key.Longitude, c, cfg.Providers.ForecastURL, key.Opts)
+				forecasts[i], errs[i] = graphqlForecast{Key: key, CurrentTemp: currentTemp, Weekly: weeklyForecast, IsFromCache: isFromCache}, err
+			})
+			return forecasts, errs
+		}, graphqlMaxLookups),
+	}
+}
+
+// forEachParallel calls f with every index from 0 to n-1, running at most parallel calls at a time, and returns once
+// all of them have returned.
+func forEachParallel(n int, parallel int, f func(i int)) {
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			f(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// loadersFrom returns the loaders of the request carried by ctx.
+func loadersFrom(ctx context.Context) *graphqlLoaders {
+	return ctx.Value(graphqlLoadersKey{}).(*graphqlLoaders)
+}
+
+// graphqlHandler returns the handler of the GraphQL endpoint, which gives each request its own loaders.
+func graphqlHandler(cfg config.Config, c *cache.Cache, favs *favorites.Store) http.Handler {
+	handler := graphql.Handler(newGraphQLSchema(cfg))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), graphqlLoadersKey{}, newGraphQLLoaders(cfg, c, favs))
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// graphqlForecastOptions returns the forecast options selected by the units and days arguments of a field, which
+// default to the configuration.
+func graphqlForecastOptions(cfg config.Config, args map[string]any) (api.ForecastOptions, error) {
+	opts := forecastOptions(cfg)
+	if units, ok := args["units"].(string); ok {
+		if units != string(api.Fahrenheit) && units != string(api.Celsius) {
+			return opts, fmt.Errorf("invalid units %q: must be fahrenheit or celsius", units)
+		}
+		opts.Units = api.Units(units)
+	}
+	if days, ok := args["days"].(int); ok {
+		opts.ForecastDays = days
+	}
+	return opts, opts.Validate()
+}
+
+// loadForecast returns a Thunk that loads the forecast of key once, together with the other forecasts of the request.
+func loadForecast(ctx context.Context, key graphqlForecastKey) graphql.Thunk {
+	thunk := loadersFrom(ctx).forecasts.Load(ctx, key)
+	return func() (any, error) {
+		v, err := thunk()
+		if err != nil {
+			return nil, err
+		}
+		forecast := v.(graphqlForecast)
+		return &forecast, nil
+	}
+}
+
+// graphqlDays returns the days of the forecast as values of the Day type. Values the forecast does not have, such as
+// sunrise during the polar night, are null.
+func graphqlDays(weeklyForecast api.WeeklyForecast) []map[string]any {
+	days := make([]map[string]any, len(weeklyForecast.Time))
+	for i, date := range weeklyForecast.Time {
+		day := map[string]any{"date": date}
+		optional := map[string][]float64{
+			"high":                weeklyForecast.Temperature2MMax,
+			"low":                 weeklyForecast.Temperature2MMin,
+			"precipitation":       weeklyForecast.PrecipitationSum,
+			"precipitationChance": weeklyForecast.PrecipitationProbabilityMax,
+			"snowfall":            weeklyForecast.SnowfallSum,
+			"uvIndex":             weeklyForecast.UVIndexMax,
+		}
+		for name, values := range optional {
+			if i < len(values) {
+				day[name] = values[i]
+			}
+		}
+		if i < len(weeklyForecast.WeatherCode) {
+			day["weatherCode"] = weeklyForecast.WeatherCode[i]
+			day["weather"] = api.WeatherDescription(weeklyForecast.WeatherCode[i])
+		}
+		if sunrise, ok := weeklyForecast.SunriseAt(i); ok {
+			day["sunrise"] = sunrise.Format(time.RFC3339)
+		}
+		if sunset, ok := weeklyForecast.SunsetAt(i); ok {
+			day["sunset"] = sunset.Format(time.RFC3339)
+		}
+		days[i] = day
+	}
+	return days
+}
+
+// newGraphQLSchema returns the GraphQL schema of the weather API:
+//
+//	type Query {
+//	  location(address: String!): Location
+//	  forecast(lat: Float!, lon: Float!, units: String, days: Int): Forecast
+//	}
+//	type Location { address: String, latitude: Float, longitude: Float, forecast(units: String, days: Int): Forecast }
+//	type Forecast { latitude: Float, longitude: Float, units: String, timezone: String, fromCache: Boolean,
+//	  current: Current, daily: [Day], hourly(hours: Int = 24): [Hour] }
+//	type Current { temperature: Float }
+//	type Day { date: String, weather: String, weatherCode: Int, high: Float, low: Float, precipitation: Float,
+//	  precipitationChance: Float, snowfall: Float, uvIndex: Float, sunrise: String, sunset: String }
+//	type Hour { time: String, temperature: Float }
+//
+// Units and days default to the configuration. Lookups go through the loaders of the request.
+func newGraphQLSchema(cfg config.Config) *graphql.Schema {
+	forecastArgs := func() map[string]*graphql.Argument {
+		return map[string]*graphql.Argument{
+			"units": {Type: graphql.String},
+			"days":  {Type: graphql.Int},
+		}
+	}
+
+	current := &graphql.Object{Name: "Current", Fields: map[string]*graphql.Field{
+		"temperature": {Type: graphql.Float},
+	}}
+	day := &graphql.Object{Name: "Day", Fields: map[string]*graphql.Field{
+		"date":                {Type: graphql.NonNull{Of: graphql.String}},
+		"weather":             {Type: graphql.String},
+		"weatherCode":         {Type: graphql.Int},
+		"high":                {Type: graphql.Float},
+		"low":                 {Type: graphql.Float},
+		"precipitation":       {Type: graphql.Float},
+		"precipitationChance": {Type: graphql.Float},
+		"snowfall":            {Type: graphql.Float},
+		"uvIndex":             {Type: graphql.Float},
+		"sunrise":             {Type: graphql.String},
+		"sunset":              {Type: graphql.String},
+	}}
+	hour := &graphql.Object{Name: "Hour", Fields: map[string]*graphql.Field{
+		"time":        {Type: graphql.NonNull{Of: graphql.String}},
+		"temperature": {Type: graphql.Float},
+	}}
+
+	// forecastField resolves a field of the Forecast type from its value
+	forecastField := func(t graphql.Type, resolve func(f *graphqlForecast) any) *graphql.Field {
+		return &graphql.Field{Type: t, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return resolve(p.Source.(*graphqlForecast)), nil
+		}}
+	}
+	forecast := &graphql.Object{Name: "Forecast", Fields: map[string]*graphql.Field{
+		"latitude":  forecastField(graphql.Float, func(f *graphqlForecast) any { return f.Key.Latitude }),
+		"longitude": forecastField(graphql.Float, func(f *graphqlForecast) any { return f.Key.Longitude }),
+		"units":     forecastField(graphql.String, func(f *graphqlForecast) any { return f.Key.Opts.Units }),
+		"timezone":  forecastField(graphql.String, func(f *graphqlForecast) any { return f.Weekly.Timezone }),
+		"fromCache": forecastField(graphql.Boolean, func(f *graphqlForecast) any { return f.IsFromCache }),
+		"current": forecastField(current, func(f *graphqlForecast) any {
+			return map[string]any{"temperature": f.CurrentTemp}
+		}),
+		"daily": forecastField(graphql.List{Of: day}, func(f *graphqlForecast) any { return graphqlDays(f.Weekly) }),
+		"hourly": {
+			Type: graphql.List{Of: hour},
+			Args: map[string]*graphql.Argument{"hours": {Type: graphql.Int, Default: defaultGraphQLHours}},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				times, temperatures := p.Source.(*graphqlForecast).Weekly.HoursFrom(time.Now(), p.Args["hours"].(int))
+				hours := make([]map[string]any, len(times))
+				for i, t := range times {
+					hours[i] = map[string]any{"time": t.Format(time.RFC3339), "temperature": temperatures[i]}
+				}
+				return hours, nil
+			},
+		},
+	}}
+
+	location := &graphql.Object{Name: "Location", Fields: map[string]*graphql.Field{
+		"address": {Type: graphql.String, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(*graphqlLocation).Address, nil
+		}},
+		"latitude": {Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(*graphqlLocation).Latitude, nil
+		}},
+		"longitude": {Type: graphql.Float, Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(*graphqlLocation).Longitude, nil
+		}},
+		"forecast": {Type: forecast, Args: forecastArgs(), Resolve: func(p graphql.ResolveParams) (any, error) {
+			opts, err := graphqlForecastOptions(cfg, p.Args)
+			if err != nil {
+				return nil, err
+			}
+			l := p.Source.(*graphqlLocation)
+			return loadForecast(p.Context, graphqlForecastKey{Address: l.Address, Latitude: l.Latitude, Longitude: l.Longitude, Opts: opts}), nil
+		}},
+	}}
+
+	queryForecastArgs := forecastArgs()
+	queryForecastArgs["lat"] = &graphql.Argument{Type: graphql.NonNull{Of: graphql.Float}}
+	queryForecastArgs["lon"] = &graphql.Argument{Type: graphql.NonNull{Of: graphql.Float}}
+	query := &graphql.Object{Name: "Query", Fields: map[string]*graphql.Field{
+		"location": {
+			Type: location,
+			Args: map[string]*graphql.Argument{"address": {Type: graphql.NonNull{Of: graphql.String}}},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				thunk := loadersFrom(p.Context).locations.Load(p.Context, p.Args["address"].(string))
+				return graphql.Thunk(func() (any, error) {
+					v, err := thunk()
+					if err != nil {
+						return nil, err
+					}
+					l := v.(graphqlLocation)
+					return &l, nil
+				}), nil
+			},
+		},
+		"forecast": {
+			Type: forecast,
+			Args: queryForecastArgs,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				opts, err := graphqlForecastOptions(cfg, p.Args)
+				if err != nil {
+					return nil, err
+				}
+				return loadForecast(p.Context, graphqlForecastKey{Latitude: p.Args["lat"].(float64), Longitude: p.Args["lon"].(float64), Opts: opts}), nil
+			},
+		},
+	}}
+	return &graphql.Schema{Query: query}
+}
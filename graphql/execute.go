@@ -0,0 +1,366 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Request is a GraphQL request, as sent in the body of a POST.
+type Request struct {
+	// Query is the GraphQL document.
+	Query string `json:"query"`
+	// OperationName selects the operation to execute when the document has several.
+	OperationName string `json:"operationName,omitempty"`
+	// Variables are the values of the operation's variables.
+	Variables map[string]any `json:"variables,omitempty"`
+}
+
+// Response is the result of executing a request.
+type Response struct {
+	// Data is the result of the operation, or nil if it could not be executed.
+	Data any `json:"data,omitempty"`
+	// Errors are the errors raised while executing the operation, including those of fields that resolved to null.
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Error is an error raised by a request or one of its fields.
+type Error struct {
+	// Message describes the error.
+	Message string `json:"message"`
+	// Path is the path of the field that raised the error from the root of the response, made of response keys and
+	// list indices.
+	Path []any `json:"path,omitempty"`
+}
+
+// Error implements error.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// result is an object of the response, with its fields in the order they were selected.
+type result []resultField
+
+// resultField is a field of a result.
+type resultField struct {
+	key   string
+	value any
+}
+
+// MarshalJSON implements json.Marshaler, keeping the order of the fields.
+func (r result) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, field := range r {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(field.key)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// executor holds the state of the execution of one operation.
+type executor struct {
+	ctx       context.Context
+	doc       *document
+	variables map[string]any
+	errors    []*Error
+	// pending are the completions of fields whose resolvers returned a Thunk, called once the rest of the query is
+	// resolved.
+	pending []func()
+}
+
+// Execute executes the query of req against the schema. Errors are reported in the response rather than returned,
+// as GraphQL clients expect.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+	variables, err := coerceVariables(op, req.Variables)
+	if err != nil {
+		return &Response{Errors: []*Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{ctx: ctx, doc: doc, variables: variables}
+	data := e.executeSelections(s.Query, nil, op.selections, nil)
+	// Thunks are called a wave at a time, so that the loads of each wave are batched together
+	for len(e.pending) > 0 {
+		wave := e.pending
+		e.pending = nil
+		for _, complete := range wave {
+			complete()
+		}
+	}
+	return &Response{Data: data, Errors: e.errors}
+}
+
+// operation returns the operation of the document with the given name, or its only operation if name is empty.
+func (d *document) operation(name string) (*operation, error) {
+	var op *operation
+	switch {
+	case name == "" && len(d.operations) > 1:
+		return nil, fmt.Errorf("the document has several operations, an operation name is required")
+	case name == "":
+		op = d.operations[0]
+	default:
+		for _, candidate := range d.operations {
+			if candidate.name == name {
+				op = candidate
+			}
+		}
+		if op == nil {
+			return nil, fmt.Errorf("unknown operation %q", name)
+		}
+	}
+	if op.kind != "query" {
+		return nil, fmt.Errorf("%s operations are not supported", op.kind)
+	}
+	return op, nil
+}
+
+// coerceVariables returns the variables of the operation with their defaults applied, checking that the required
+// ones are given.
+func coerceVariables(op *operation, given map[string]any) (map[string]any, error) {
+	variables := map[string]any{}
+	for _, def := range op.variables {
+		v, ok := given[def.name]
+		if !ok && def.defaultValue != nil {
+			var err error
+			if v, err = def.defaultValue.resolve(nil); err != nil {
+				return nil, err
+			}
+		}
+		if v == nil && def.typ.nonNull {
+			return nil, fmt.Errorf("variable $%s of type %s is required", def.name, def.typ)
+		}
+		variables[def.name] = v
+	}
+	return variables, nil
+}
+
+// executeSelections resolves the selected fields of an object whose value is source.
+func (e *executor) executeSelections(object *Object, source any, selections []selection, path []any) result {
+	keys, fields := e.collectFields(object, selections, nil, map[string][]selection{}, map[string]bool{})
+	out := make(result, len(keys))
+	for i, key := range keys {
+		out[i].key = key
+		fieldPath := appendPath(path, key)
+		field := fields[key][0]
+		if field.name == "__typename" {
+			out[i].value = object.Name
+			continue
+		}
+		def, ok := object.Fields[field.name]
+		if !ok {
+			e.fail(fieldPath, fmt.Errorf("cannot query field %q on type %q", field.name, object.Name))
+			continue
+		}
+		args, err := e.coerceArguments(def, field.arguments)
+		if err != nil {
+			e.fail(fieldPath, err)
+			continue
+		}
+
+		v, err := e.resolve(def, source, args, field.name)
+		e.complete(&out[i].value, def.Type, fields[key], v, err, fieldPath)
+	}
+	return out
+}
+
+// resolve calls the resolver of a field, or reads the field from a map source if it has none.
+func (e *executor) resolve(def *Field, source any, args map[string]any, name string) (any, error) {
+	if def.Resolve != nil {
+		return def.Resolve(ResolveParams{Context: e.ctx, Source: source, Args: args})
+	}
+	if m, ok := source.(map[string]any); ok {
+		return m[name], nil
+	}
+	return nil, nil
+}
+
+// complete stores the value of a field in slot, converted to its type, and resolves the selections of objects. The
+// fields are all the selections of the field in the query, which are merged. Thunks are completed once the rest of
+// the query is resolved.
+func (e *executor) complete(slot *any, t Type, fields []selection, v any, err error, path []any) {
+	if err != nil {
+		e.fail(path, err)
+		return
+	}
+	if thunk, ok := v.(Thunk); ok {
+		e.pending = append(e.pending, func() {
+			v, err := thunk()
+			e.complete(slot, t, fields, v, err, path)
+		})
+		return
+	}
+
+	if nonNull, ok := t.(NonNull); ok {
+		if isNil(v) {
+			e.fail(path, fmt.Errorf("non-null field %s resolved to null", fields[0].name))
+			return
+		}
+		t = nonNull.Of
+	}
+	if isNil(v) {
+		*slot = nil
+		return
+	}
+
+	switch t := t.(type) {
+	case *Scalar:
+		serialized, err := t.Serialize(v)
+		if err != nil {
+			e.fail(path, err)
+			return
+		}
+		*slot = serialized
+	case *Object:
+		var selections []selection
+		for _, field := range fields {
+			selections = append(selections, field.selections...)
+		}
+		*slot = e.executeSelections(t, v, selections, path)
+	case List:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			e.fail(path, fmt.Errorf("expected a list for field %s, got %T", fields[0].name, v))
+			return
+		}
+		list := make([]any, rv.Len())
+		for i := range list {
+			e.complete(&list[i], t.Of, fields, rv.Index(i).Interface(), nil, appendPath(path, i))
+		}
+		*slot = list
+	}
+}
+
+// collectFields returns the response keys of the fields selected on object in order, and the selections of each key,
+// expanding fragments and applying the @skip and @include directives.
+func (e *executor) collectFields(object *Object, selections []selection, keys []string, fields map[string][]selection, visited map[string]bool) ([]string, map[string][]selection) {
+	for _, s := range selections {
+		if !e.included(s) {
+			continue
+		}
+		switch {
+		case s.spread != "":
+			f, ok := e.doc.fragments[s.spread]
+			if visited[s.spread] || !ok || f.typeCondition != object.Name {
+				continue
+			}
+			visited[s.spread] = true
+			keys, fields = e.collectFields(object, f.selections, keys, fields, visited)
+		case s.inline:
+			if s.typeCondition != "" && s.typeCondition != object.Name {
+				continue
+			}
+			keys, fields = e.collectFields(object, s.selections, keys, fields, visited)
+		default:
+			key := s.responseKey()
+			if _, ok := fields[key]; !ok {
+				keys = append(keys, key)
+			}
+			fields[key] = append(fields[key], s)
+		}
+	}
+	return keys, fields
+}
+
+// included reports whether a selection is included by its @skip and @include directives.
+func (e *executor) included(s selection) bool {
+	for _, d := range s.directives {
+		if d.name != "skip" && d.name != "include" {
+			continue
+		}
+		condition := false
+		for _, arg := range d.arguments {
+			if arg.name == "if" {
+				v, _ := arg.value.resolve(e.variables)
+				condition, _ = v.(bool)
+			}
+		}
+		if d.name == "skip" && condition || d.name == "include" && !condition {
+			return false
+		}
+	}
+	return true
+}
+
+// coerceArguments returns the arguments of a field, resolved from the query and its variables, with the defaults
+// applied and converted to their types.
+func (e *executor) coerceArguments(def *Field, arguments []argument) (map[string]any, error) {
+	args := map[string]any{}
+	for _, arg := range arguments {
+		if _, ok := def.Args[arg.name]; !ok {
+			return nil, fmt.Errorf("unknown argument %q", arg.name)
+		}
+	}
+	for name, argDef := range def.Args {
+		var v any
+		given := false
+		for _, arg := range arguments {
+			if arg.name != name {
+				continue
+			}
+			if arg.value.kind == valueVariable {
+				v, given = e.variables[arg.value.text]
+			} else {
+				var err error
+				if v, err = arg.value.resolve(e.variables); err != nil {
+					return nil, fmt.Errorf("invalid argument %q: %v", name, err)
+				}
+				given = true
+			}
+		}
+		if !given || v == nil && argDef.Default != nil {
+			v = argDef.Default
+		}
+		coerced, err := coerceInput(argDef.Type, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument %q: %v", name, err)
+		}
+		args[name] = coerced
+	}
+	return args, nil
+}
+
+// fail records the error of the field at path, whose value is null.
+func (e *executor) fail(path []any, err error) {
+	e.errors = append(e.errors, &Error{Message: err.Error(), Path: path})
+}
+
+// appendPath returns path followed by elem, without sharing the backing array of path.
+func appendPath(path []any, elem any) []any {
+	return append(append(make([]any, 0, len(path)+1), path...), elem)
+}
+
+// isNil reports whether v is nil or a nil pointer, map or slice.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
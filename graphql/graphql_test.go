@@ -0,0 +1,299 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testSchema returns a schema of cities whose forecasts are loaded through a loader of at most three cities, a
+// function adding a new loader to a context, and the keys of each batch loaded.
+func testSchema() (*Schema, func(context.Context) context.Context, *[][]string) {
+	var batches [][]string
+	temperatures := map[string]float64{"Austin": 97.6, "Boston": 71.2}
+
+	forecast := &Object{Name: "Forecast", Fields: map[string]*Field{
+		"temperature": {Type: Float},
+		"days":        {Type: List{Of: String}},
+	}}
+	city := &Object{Name: "City", Fields: map[string]*Field{
+		"name": {Type: NonNull{Of: String}},
+		"forecast": {Type: forecast, Resolve: func(p ResolveParams) (any, error) {
+			loader := p.Context.Value(loaderKey{}).(*Loader[string, float64])
+			thunk := loader.Load(p.Context, p.Source.(map[string]any)["name"].(string))
+			return Thunk(func() (any, error) {
+				v, err := thunk()
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{"temperature": v, "days": []string{"Today", "Tomorrow"}}, nil
+			}), nil
+		}},
+	}}
+	query := &Object{Name: "Query", Fields: map[string]*Field{
+		"city": {
+			Type: city,
+			Args: map[string]*Argument{"name": {Type: NonNull{Of: String}}},
+			Resolve: func(p ResolveParams) (any, error) {
+				return map[string]any{"name": p.Args["name"]}, nil
+			},
+		},
+		"count": {
+			Type: Int,
+			Args: map[string]*Argument{"n": {Type: Int, Default: 3}},
+			Resolve: func(p ResolveParams) (any, error) {
+				return p.Args["n"], nil
+			},
+		},
+	}}
+
+	loaders := func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, loaderKey{}, NewLoader(func(ctx context.Context, keys []string) ([]float64, []error) {
+			batches = append(batches, keys)
+			values := make([]float64, len(keys))
+			errs := make([]error, len(keys))
+			for i, key := range keys {
+				t, ok := temperatures[key]
+				if !ok {
+					errs[i] = errors.New("unknown city " + key)
+				}
+				values[i] = t
+			}
+			return values, errs
+		}, 3))
+	}
+	return &Schema{Query: query}, loaders, &batches
+}
+
+// loaderKey is the context key of the loader of testSchema.
+type loaderKey struct{}
+
+func TestSchema_Execute(t *testing.T) {
+	tc := []struct {
+		name      string
+		req       Request
+		expected  string
+		batches   int
+		batchSize int
+	}{
+		{
+			name:      "Aliases Batched",
+			req:       Request{Query: `{ a: city(name: "Austin") { name forecast { temperature } } b: city(name: "Boston") { forecast { temperature } } }`},
+			expected:  `{"data":{"a":{"name":"Austin","forecast":{"temperature":97.6}},"b":{"forecast":{"temperature":71.2}}}}`,
+			batches:   1,
+			batchSize: 2,
+		},
+		{
+			name:      "Duplicates Loaded Once",
+			req:       Request{Query: `{ a: city(name: "Austin") { forecast { temperature } } b: city(name: "Austin") { forecast { days } } }`},
+			expected:  `{"data":{"a":{"forecast":{"temperature":97.6}},"b":{"forecast":{"days":["Today","Tomorrow"]}}}}`,
+			batches:   1,
+			batchSize: 1,
+		},
+		{
+			name: "Variables And Fragments",
+			req: Request{
+				Query:     `query Weather($name: String!) { city(name: $name) { ...details } } fragment details on City { name ... on City { __typename } }`,
+				Variables: map[string]any{"name": "Boston"},
+			},
+			expected: `{"data":{"city":{"name":"Boston","__typename":"City"}}}`,
+		},
+		{
+			name: "Directives",
+			req: Request{
+				Query:     `query ($detailed: Boolean = false) { city(name: "Austin") { name forecast @include(if: $detailed) { temperature } } count @skip(if: true) }`,
+				Variables: map[string]any{},
+			},
+			expected: `{"data":{"city":{"name":"Austin"}}}`,
+		},
+		{
+			name:     "Argument Defaults And JSON Numbers",
+			req:      Request{Query: `query ($n: Int) { a: count b: count(n: $n) }`, Variables: map[string]any{"n": float64(7)}},
+			expected: `{"data":{"a":3,"b":7}}`,
+		},
+		{
+			name:      "Field Errors",
+			req:       Request{Query: `{ city(name: "Paris") { name forecast { temperature } } missing }`},
+			expected:  `{"data":{"city":{"name":"Paris","forecast":null},"missing":null},"errors":[{"message":"cannot query field \"missing\" on type \"Query\"","path":["missing"]},{"message":"unknown city Paris","path":["city","forecast"]}]}`,
+			batches:   1,
+			batchSize: 1,
+		},
+		{
+			name: "Too Many Keys",
+			req: Request{Query: `{ a: city(name: "Austin") { forecast { temperature } } b: city(name: "Boston") { forecast { temperature } }
+				c: city(name: "Paris") { forecast { temperature } } d: city(name: "Rome") { forecast { temperature } } }`},
+			expected: `{"data":{"a":{"forecast":{"temperature":97.6}},"b":{"forecast":{"temperature":71.2}},` +
+				`"c":{"forecast":null},"d":{"forecast":null}},"errors":[{"message":"unknown city Paris","path":["c","forecast"]},` +
+				`{"message":"too many distinct lookups in one request: at most 3 are allowed","path":["d","forecast"]}]}`,
+			batches:   1,
+			batchSize: 3,
+		},
+		{
+			name:     "Invalid Argument",
+			req:      Request{Query: `{ count(n: "three") }`},
+			expected: `{"data":{"count":null},"errors":[{"message":"invalid argument \"n\": expected an Int, got three","path":["count"]}]}`,
+		},
+		{
+			name:     "Missing Required Argument",
+			req:      Request{Query: `{ city { name } }`},
+			expected: `{"data":{"city":null},"errors":[{"message":"invalid argument \"name\": expected a non-null String","path":["city"]}]}`,
+		},
+		{
+			name:     "Missing Variable",
+			req:      Request{Query: `query ($name: String!) { city(name: $name) { name } }`},
+			expected: `{"errors":[{"message":"variable $name of type String! is required"}]}`,
+		},
+		{
+			name:     "Syntax Error",
+			req:      Request{Query: `{ city(name: "Austin") { name }`},
+			expected: `{"errors":[{"message":"syntax error: unexpected end of document"}]}`,
+		},
+		{
+			name:     "Operation Name Required",
+			req:      Request{Query: `query A { count } query B { count }`},
+			expected: `{"errors":[{"message":"the document has several operations, an operation name is required"}]}`,
+		},
+		{
+			name:     "Operation Selected",
+			req:      Request{Query: `query A { count } query B { count(n: 1) }`, OperationName: "B"},
+			expected: `{"data":{"count":1}}`,
+		},
+		{
+			name:     "Mutation",
+			req:      Request{Query: `mutation { count }`},
+			expected: `{"errors":[{"message":"mutation operations are not supported"}]}`,
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			schema, loaders, batches := testSchema()
+			data, _ := json.Marshal(schema.Execute(loaders(context.Background()), tc.req))
+			actual := string(data)
+			if actual != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, actual)
+			}
+			if len(*batches) != tc.batches {
+				t.Errorf("Expected %d batches, got %v", tc.batches, *batches)
+			}
+			if tc.batches > 0 && len((*batches)[0]) != tc.batchSize {
+				t.Errorf("Expected a batch of %d keys, got %v", tc.batchSize, (*batches)[0])
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tc := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{name: "Comments And Commas", query: "# cities\n{ city(name: \"A\\u00e9\\n\"), count }"},
+		{name: "Values", query: `{ f(a: -1.5e3, b: [1, 2], c: {d: null, e: ENUM}, f: false) }`},
+		{name: "Unterminated String", query: `{ city(name: "Austin) }`, err: "unterminated string"},
+		{name: "Invalid Number", query: `{ count(n: 12ab) }`, err: "invalid number"},
+		{name: "Unexpected Character", query: `{ count % }`, err: `unexpected character '%'`},
+		{name: "Empty Selection", query: `{ }`, err: "empty selection set"},
+		{name: "Duplicate Fragment", query: `{ count } fragment a on Query { count } fragment a on Query { count }`, err: `fragment "a" is defined more than once`},
+		{name: "No Operation", query: `fragment a on Query { count }`, err: "the document has no operation"},
+		{name: "Nested List", query: `{ f(a: ` + strings.Repeat("[", 100000) + `) }`, err: "nested more than 64 levels deep"},
+		{name: "Nested Selection Set", query: strings.Repeat("{ f ", 100000) + strings.Repeat("}", 100000), err: "nested more than 64 levels deep"},
+		{name: "Nested List Type", query: `query ($a: ` + strings.Repeat("[", 100000) + `Int) { f }`, err: "nested more than 64 levels deep"},
+		{name: "Deepest Nesting", query: strings.Repeat("{ f ", 63) + "{ f }" + strings.Repeat("}", 63)},
+		{name: "Too Deep Nesting", query: strings.Repeat("{ f ", 64) + "{ f }" + strings.Repeat("}", 64), err: "nested more than 64 levels deep"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parse(tc.query)
+			if tc.err == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+				t.Errorf("Expected an error containing '%s', got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	schema, loaders, _ := testSchema()
+	handler := Handler(schema)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(loaders(r.Context())))
+	}))
+	defer server.Close()
+
+	tc := []struct {
+		name     string
+		method   string
+		url      string
+		body     string
+		status   int
+		expected string
+	}{
+		{
+			name:     "POST",
+			method:   http.MethodPost,
+			body:     `{"query": "query ($n: Int) { count(n: $n) }", "variables": {"n": 5}}`,
+			status:   http.StatusOK,
+			expected: `{"data":{"count":5}}`,
+		},
+		{
+			name:     "GET",
+			method:   http.MethodGet,
+			url:      "?query=" + url.QueryEscape(`query ($n: Int) { count(n: $n) }`) + "&variables=" + url.QueryEscape(`{"n": 2}`),
+			status:   http.StatusOK,
+			expected: `{"data":{"count":2}}`,
+		},
+		{
+			name:     "Invalid Body",
+			method:   http.MethodPost,
+			body:     `{"query": `,
+			status:   http.StatusBadRequest,
+			expected: `{"errors":[{"message":"invalid request: unexpected EOF"}]}`,
+		},
+		{
+			name:     "Missing Query",
+			method:   http.MethodGet,
+			status:   http.StatusBadRequest,
+			expected: `{"errors":[{"message":"missing query"}]}`,
+		},
+		{
+			name:     "Syntax Error",
+			method:   http.MethodPost,
+			body:     `{"query": "{ count"}`,
+			status:   http.StatusBadRequest,
+			expected: `{"errors":[{"message":"syntax error: unexpected end of document"}]}`,
+		},
+		{
+			name:     "Method Not Allowed",
+			method:   http.MethodPut,
+			status:   http.StatusMethodNotAllowed,
+			expected: "method not allowed",
+		},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tc.method, server.URL+tc.url, strings.NewReader(tc.body))
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, resp.StatusCode)
+			}
+			if strings.TrimSpace(string(body)) != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxRequestSize bounds the size of the body of a request.
+const maxRequestSize = 1 << 20
+
+// Handler returns an HTTP handler executing the requests it receives against schema. Requests are read from the JSON
+// body of a POST, or from the query, operationName and variables parameters of a GET.
+func Handler(schema *Schema) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		switch r.Method {
+		case http.MethodGet:
+			req.Query = r.URL.Query().Get("query")
+			req.OperationName = r.URL.Query().Get("operationName")
+			if variables := r.URL.Query().Get("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+					writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: fmt.Sprintf("invalid variables: %v", err)}}})
+					return
+				}
+			}
+		case http.MethodPost:
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+				writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: fmt.Sprintf("invalid request: %v", err)}}})
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if req.Query == "" {
+			writeResponse(w, http.StatusBadRequest, &Response{Errors: []*Error{{Message: "missing query"}}})
+			return
+		}
+
+		resp := schema.Execute(r.Context(), req)
+		status := http.StatusOK
+		if resp.Data == nil {
+			// The request could not be executed at all, such as a query with a syntax error
+			status = http.StatusBadRequest
+		}
+		writeResponse(w, status, resp)
+	})
+}
+
+// writeResponse writes resp as JSON with the given status.
+func writeResponse(w http.ResponseWriter, status int, resp *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
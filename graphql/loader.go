@@ -0,0 +1,86 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// BatchFunc loads the values of keys, returning a value and an error for each key in the same order.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) ([]V, []error)
+
+// Loader batches and deduplicates the loads of one request. Load only queues the key and returns a Thunk; the keys
+// queued by the fields resolved so far are loaded together by a single call of the batch function when the first of
+// their thunks is called. Each key is loaded once, so a Loader must not outlive the request it was created for.
+type Loader[K comparable, V any] struct {
+	batch BatchFunc[K, V]
+	// maxKeys is the number of distinct keys the loader loads, or 0 for no limit.
+	maxKeys int
+
+	mu      sync.Mutex
+	queued  []K
+	entries map[K]*loaderEntry[V]
+}
+
+// loaderEntry is the value of a key, once loaded.
+type loaderEntry[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// NewLoader returns a loader that loads at most maxKeys distinct keys with batch, or any number if maxKeys is 0.
+func NewLoader[K comparable, V any](batch BatchFunc[K, V], maxKeys int) *Loader[K, V] {
+	return &Loader[K, V]{batch: batch, maxKeys: maxKeys, entries: map[K]*loaderEntry[V]{}}
+}
+
+// Load queues key and returns a Thunk that returns its value. Once the loader holds maxKeys distinct keys, the thunks
+// of new keys return an error without loading them.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) Thunk {
+	l.mu.Lock()
+	entry, ok := l.entries[key]
+	if !ok && l.maxKeys > 0 && len(l.entries) >= l.maxKeys {
+		l.mu.Unlock()
+		return func() (any, error) {
+			return nil, fmt.Errorf("too many distinct lookups in one request: at most %d are allowed", l.maxKeys)
+		}
+	}
+	if !ok {
+		entry = &loaderEntry[V]{done: make(chan struct{})}
+		l.entries[key] = entry
+		l.queued = append(l.queued, key)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.dispatch(ctx)
+		<-entry.done
+		return entry.value, entry.err
+	}
+}
+
+// dispatch loads the queued keys, if any.
+func (l *Loader[K, V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	keys := l.queued
+	l.queued = nil
+	entries := make([]*loaderEntry[V], len(keys))
+	for i, key := range keys {
+		entries[i] = l.entries[key]
+	}
+	l.mu.Unlock()
+	if len(keys) == 0 {
+		return
+	}
+
+	values, errs := l.batch(ctx, keys)
+	for i, entry := range entries {
+		if i < len(values) {
+			entry.value = values[i]
+		}
+		if i < len(errs) {
+			entry.err = errs[i]
+		}
+		close(entry.done)
+	}
+}
@@ -0,0 +1,739 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token of a GraphQL document.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// token is a lexical token with its offset in the document, used in syntax errors.
+type token struct {
+	kind  tokenKind
+	text  string
+	value string
+	pos   int
+}
+
+// lexer splits a GraphQL document into tokens, skipping whitespace, commas and comments, which are insignificant.
+type lexer struct {
+	src string
+	pos int
+}
+
+// next returns the next token of the document.
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != ',' {
+			break
+		}
+		l.pos++
+	}
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunctuator, text: "...", pos: start}, nil
+	case strings.IndexByte("!$():=@[]{}", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunctuator, text: string(c), pos: start}, nil
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for l.pos < len(l.src) && isNameByte(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenName, text: l.src[start:l.pos], pos: start}, nil
+	case c == '-' || c >= '0' && c <= '9':
+		return l.number()
+	case c == '"':
+		return l.string()
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, fmt.Errorf("unexpected character %q at offset %d", r, start)
+}
+
+// isNameByte reports whether c can appear in a name after its first character.
+func isNameByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// number reads an integer or a float.
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	kind := tokenInt
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+			l.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return token{}, fmt.Errorf("invalid number at offset %d", start)
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if digits() == 0 {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, fmt.Errorf("invalid number at offset %d", start)
+		}
+	}
+	if l.pos < len(l.src) && (isNameByte(l.src[l.pos]) || l.src[l.pos] == '.') {
+		return token{}, fmt.Errorf("invalid number at offset %d", start)
+	}
+	return token{kind: kind, text: l.src[start:l.pos], pos: start}, nil
+}
+
+// string reads a quoted string and unescapes it. Block strings are not supported.
+func (l *lexer) string() (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		return token{}, fmt.Errorf("block strings are not supported, at offset %d", start)
+	}
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, text: l.src[start:l.pos], value: b.String(), pos: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, fmt.Errorf("unterminated string at offset %d", start)
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			escape := l.src[l.pos+1]
+			l.pos += 2
+			switch escape {
+			case '"', '\\', '/':
+				b.WriteByte(escape)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return token{}, fmt.Errorf("invalid unicode escape at offset %d", l.pos-2)
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, fmt.Errorf("invalid unicode escape at offset %d", l.pos-2)
+				}
+				b.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, fmt.Errorf("invalid escape \\%c at offset %d", escape, l.pos-2)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, fmt.Errorf("unterminated string at offset %d", start)
+}
+
+// document is a parsed GraphQL document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is an operation definition, such as a query.
+type operation struct {
+	kind       string
+	name       string
+	variables  []variableDefinition
+	selections []selection
+}
+
+// variableDefinition declares a variable of an operation.
+type variableDefinition struct {
+	name         string
+	typ          typeRef
+	defaultValue *value
+}
+
+// typeRef is a type as written in a variable definition, such as [String!]!.
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+// String returns the type as written in GraphQL.
+func (t typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// fragment is a named fragment definition.
+type fragment struct {
+	typeCondition string
+	selections    []selection
+}
+
+// selection is a field, a fragment spread or an inline fragment of a selection set.
+type selection struct {
+	// alias, name, arguments and selections describe a field. Inline fragments only have selections.
+	alias      string
+	name       string
+	arguments  []argument
+	selections []selection
+	// spread is the name of the fragment of a fragment spread.
+	spread string
+	// inline is set for inline fragments, which apply to typeCondition if set.
+	inline        bool
+	typeCondition string
+	directives    []directive
+}
+
+// responseKey returns the key of the field in the response, which is its alias if it has one.
+func (s selection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// argument is a named argument of a field or a directive.
+type argument struct {
+	name  string
+	value value
+}
+
+// directive is a directive applied to a selection, such as @skip(if: true).
+type directive struct {
+	name      string
+	arguments []argument
+}
+
+// valueKind is the kind of a literal value.
+type valueKind int
+
+const (
+	valueNull valueKind = iota
+	valueVariable
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueEnum
+	valueList
+	valueObject
+)
+
+// value is a literal value or a variable reference in a document.
+type value struct {
+	kind valueKind
+	// text is the literal, the variable name or the enum value.
+	text   string
+	list   []value
+	fields []argument
+}
+
+// maxDepth is how deeply selection sets, list and object values and list types can nest. The parser recurses into
+// each level, so deeper documents are rejected rather than exhausting the stack.
+const maxDepth = 64
+
+// parser builds a document from the tokens of a lexer.
+type parser struct {
+	lex *lexer
+	tok token
+	// depth is the number of levels the parser is nested in.
+	depth int
+}
+
+// parse parses a GraphQL document. Only executable definitions, operations and fragments, are accepted.
+func parse(src string) (*document, error) {
+	p := &parser{lex: &lexer{src: src}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.tok.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunctuator, "{"):
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peek(tokenName, "fragment"):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectKeyword("on"); err != nil {
+				return nil, err
+			}
+			typeCondition, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[name]; ok {
+				return nil, fmt.Errorf("fragment %q is defined more than once", name)
+			}
+			doc.fragments[name] = &fragment{typeCondition: typeCondition, selections: selections}
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("the document has no operation")
+	}
+	return doc, nil
+}
+
+// enter descends one nesting level, and fails past maxDepth. Each successful call must be followed by a call to leave.
+func (p *parser) enter() error {
+	if p.depth >= maxDepth {
+		return fmt.Errorf("syntax error: nested more than %d levels deep at offset %d", maxDepth, p.tok.pos)
+	}
+	p.depth++
+	return nil
+}
+
+// leave returns to the enclosing nesting level.
+func (p *parser) leave() {
+	p.depth--
+}
+
+// advance reads the next token.
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return fmt.Errorf("syntax error: %v", err)
+	}
+	p.tok = tok
+	return nil
+}
+
+// peek reports whether the current token is of the given kind and text.
+func (p *parser) peek(kind tokenKind, text string) bool {
+	return p.tok.kind == kind && p.tok.text == text
+}
+
+// unexpected returns the syntax error for the current token.
+func (p *parser) unexpected() error {
+	if p.tok.kind == tokenEOF {
+		return fmt.Errorf("syntax error: unexpected end of document")
+	}
+	return fmt.Errorf("syntax error: unexpected %q at offset %d", p.tok.text, p.tok.pos)
+}
+
+// expect reads the given punctuator.
+func (p *parser) expect(text string) error {
+	if !p.peek(tokenPunctuator, text) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+// expectKeyword reads the given name.
+func (p *parser) expectKeyword(text string) error {
+	if !p.peek(tokenName, text) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+// name reads a name.
+func (p *parser) name() (string, error) {
+	if p.tok.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.tok.text
+	return name, p.advance()
+}
+
+// operation reads an operation definition starting with its kind.
+func (p *parser) operation() (*operation, error) {
+	op := &operation{kind: p.tok.text}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == tokenName {
+		op.name = p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek(tokenPunctuator, "(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.peek(tokenPunctuator, ")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			var def variableDefinition
+			var err error
+			if def.name, err = p.name(); err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if def.typ, err = p.typeRef(); err != nil {
+				return nil, err
+			}
+			if p.peek(tokenPunctuator, "=") {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+				v, err := p.value(true)
+				if err != nil {
+					return nil, err
+				}
+				def.defaultValue = &v
+			}
+			op.variables = append(op.variables, def)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	op.selections = selections
+	return op, nil
+}
+
+// typeRef reads a type reference, such as [String!]!.
+func (p *parser) typeRef() (typeRef, error) {
+	var t typeRef
+	if err := p.enter(); err != nil {
+		return t, err
+	}
+	defer p.leave()
+	if p.peek(tokenPunctuator, "[") {
+		if err := p.advance(); err != nil {
+			return t, err
+		}
+		elem, err := p.typeRef()
+		if err != nil {
+			return t, err
+		}
+		t.elem = &elem
+		if err := p.expect("]"); err != nil {
+			return t, err
+		}
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return t, err
+		}
+		t.name = name
+	}
+	if p.peek(tokenPunctuator, "!") {
+		t.nonNull = true
+		return t, p.advance()
+	}
+	return t, nil
+}
+
+// selectionSet reads a selection set between braces.
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []selection
+	for !p.peek(tokenPunctuator, "}") {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("syntax error: empty selection set at offset %d", p.tok.pos)
+	}
+	return selections, p.advance()
+}
+
+// selection reads a field, a fragment spread or an inline fragment.
+func (p *parser) selection() (selection, error) {
+	var s selection
+	var err error
+	if p.peek(tokenPunctuator, "...") {
+		if err := p.advance(); err != nil {
+			return s, err
+		}
+		if p.tok.kind == tokenName && p.tok.text != "on" {
+			s.spread = p.tok.text
+			if err := p.advance(); err != nil {
+				return s, err
+			}
+			s.directives, err = p.directives()
+			return s, err
+		}
+		s.inline = true
+		if p.peek(tokenName, "on") {
+			if err := p.advance(); err != nil {
+				return s, err
+			}
+			if s.typeCondition, err = p.name(); err != nil {
+				return s, err
+			}
+		}
+		if s.directives, err = p.directives(); err != nil {
+			return s, err
+		}
+		s.selections, err = p.selectionSet()
+		return s, err
+	}
+
+	if s.name, err = p.name(); err != nil {
+		return s, err
+	}
+	if p.peek(tokenPunctuator, ":") {
+		if err := p.advance(); err != nil {
+			return s, err
+		}
+		s.alias = s.name
+		if s.name, err = p.name(); err != nil {
+			return s, err
+		}
+	}
+	if s.arguments, err = p.arguments(false); err != nil {
+		return s, err
+	}
+	if s.directives, err = p.directives(); err != nil {
+		return s, err
+	}
+	if p.peek(tokenPunctuator, "{") {
+		s.selections, err = p.selectionSet()
+	}
+	return s, err
+}
+
+// arguments reads the arguments between parentheses, if any. Constant arguments cannot reference variables.
+func (p *parser) arguments(constant bool) ([]argument, error) {
+	if !p.peek(tokenPunctuator, "(") {
+		return nil, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	var arguments []argument
+	for !p.peek(tokenPunctuator, ")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value(constant)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument{name: name, value: v})
+	}
+	return arguments, p.advance()
+}
+
+// directives reads the directives applied to a definition or a selection, if any.
+func (p *parser) directives() ([]directive, error) {
+	var directives []directive
+	for p.peek(tokenPunctuator, "@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := p.arguments(false)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, directive{name: name, arguments: arguments})
+	}
+	return directives, nil
+}
+
+// value reads a value. Constant values, such as variable defaults, cannot reference variables.
+func (p *parser) value(constant bool) (value, error) {
+	if err := p.enter(); err != nil {
+		return value{}, err
+	}
+	defer p.leave()
+	tok := p.tok
+	switch {
+	case tok.kind == tokenPunctuator && tok.text == "$" && !constant:
+		if err := p.advance(); err != nil {
+			return value{}, err
+		}
+		name, err := p.name()
+		return value{kind: valueVariable, text: name}, err
+	case tok.kind == tokenInt:
+		return value{kind: valueInt, text: tok.text}, p.advance()
+	case tok.kind == tokenFloat:
+		return value{kind: valueFloat, text: tok.text}, p.advance()
+	case tok.kind == tokenString:
+		return value{kind: valueString, text: tok.value}, p.advance()
+	case tok.kind == tokenName:
+		v := value{kind: valueEnum, text: tok.text}
+		switch tok.text {
+		case "true", "false":
+			v.kind = valueBoolean
+		case "null":
+			v.kind = valueNull
+		}
+		return v, p.advance()
+	case tok.kind == tokenPunctuator && tok.text == "[":
+		if err := p.advance(); err != nil {
+			return value{}, err
+		}
+		v := value{kind: valueList}
+		for !p.peek(tokenPunctuator, "]") {
+			elem, err := p.value(constant)
+			if err != nil {
+				return value{}, err
+			}
+			v.list = append(v.list, elem)
+		}
+		return v, p.advance()
+	case tok.kind == tokenPunctuator && tok.text == "{":
+		if err := p.advance(); err != nil {
+			return value{}, err
+		}
+		v := value{kind: valueObject}
+		for !p.peek(tokenPunctuator, "}") {
+			name, err := p.name()
+			if err != nil {
+				return value{}, err
+			}
+			if err := p.expect(":"); err != nil {
+				return value{}, err
+			}
+			field, err := p.value(constant)
+			if err != nil {
+				return value{}, err
+			}
+			v.fields = append(v.fields, argument{name: name, value: field})
+		}
+		return v, p.advance()
+	}
+	return value{}, p.unexpected()
+}
+
+// resolve returns the Go value of v, with variables read from variables: nil, int, float64, string, bool, []any or
+// map[string]any. Enum values are returned as strings.
+func (v value) resolve(variables map[string]any) (any, error) {
+	switch v.kind {
+	case valueVariable:
+		return variables[v.text], nil
+	case valueInt:
+		n, err := strconv.Atoi(v.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Int %s", v.text)
+		}
+		return n, nil
+	case valueFloat:
+		f, err := strconv.ParseFloat(v.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Float %s", v.text)
+		}
+		return f, nil
+	case valueString, valueEnum:
+		return v.text, nil
+	case valueBoolean:
+		return v.text == "true", nil
+	case valueList:
+		list := make([]any, len(v.list))
+		for i, elem := range v.list {
+			resolved, err := elem.resolve(variables)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = resolved
+		}
+		return list, nil
+	case valueObject:
+		object := make(map[string]any, len(v.fields))
+		for _, field := range v.fields {
+			resolved, err := field.value.resolve(variables)
+			if err != nil {
+				return nil, err
+			}
+			object[field.name] = resolved
+		}
+		return object, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,223 @@
+// Package graphql serves queries over a schema of Go resolvers in the GraphQL query language. It implements only what
+// the weather app needs, without any third party dependencies: queries with arguments, variables, aliases, fragments
+// and the @skip and @include directives, but no mutations, subscriptions or introspection.
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Type is the type of a field or an argument: a *Scalar, an *Object, a List or a NonNull.
+type Type interface {
+	// String returns the type as written in GraphQL, such as [Day!].
+	String() string
+}
+
+// Scalar is a leaf type, such as Float.
+type Scalar struct {
+	// Name is the name of the type.
+	Name string
+	// Serialize converts a value returned by a resolver to its JSON representation.
+	Serialize func(v any) (any, error)
+	// Parse converts an argument or a variable to the value passed to resolvers.
+	Parse func(v any) (any, error)
+}
+
+// String implements Type.
+func (s *Scalar) String() string { return s.Name }
+
+// Object is a type with fields, such as Query.
+type Object struct {
+	// Name is the name of the type.
+	Name string
+	// Fields are the fields of the type by name.
+	Fields map[string]*Field
+}
+
+// String implements Type.
+func (o *Object) String() string { return o.Name }
+
+// List is a list of values of the type Of.
+type List struct {
+	Of Type
+}
+
+// String implements Type.
+func (l List) String() string { return "[" + l.Of.String() + "]" }
+
+// NonNull is a value of the type Of that cannot be null. Arguments of such types are required.
+type NonNull struct {
+	Of Type
+}
+
+// String implements Type.
+func (n NonNull) String() string { return n.Of.String() + "!" }
+
+// Field is a field of an object type.
+type Field struct {
+	// Type is the type of the field's value.
+	Type Type
+	// Args are the arguments the field accepts by name.
+	Args map[string]*Argument
+	// Resolve returns the value of the field. If nil, the value is read from sources that are a map[string]any.
+	Resolve ResolveFunc
+}
+
+// Argument is an argument of a field.
+type Argument struct {
+	// Type is the type of the argument.
+	Type Type
+	// Default is the value of the argument when it is not given, if not nil.
+	Default any
+}
+
+// ResolveParams are the inputs of a resolver.
+type ResolveParams struct {
+	// Context is the context of the request.
+	Context context.Context
+	// Source is the value of the object the field belongs to, as returned by the parent field's resolver.
+	Source any
+	// Args are the arguments of the field, with their defaults applied and parsed by their types.
+	Args map[string]any
+}
+
+// ResolveFunc returns the value of a field. Values of object types are passed as Source to the resolvers of their
+// fields, and values of list types must be slices. A resolver can return a Thunk to resolve the value later, which
+// lets a Loader batch the loads of sibling fields.
+type ResolveFunc func(p ResolveParams) (any, error)
+
+// Thunk returns a value resolved later. The thunks of a query are called once no field can be resolved without
+// them, so that the loads they wait on are batched.
+type Thunk func() (any, error)
+
+// Schema is the set of types a query is executed against.
+type Schema struct {
+	// Query is the root type of queries.
+	Query *Object
+}
+
+// Int is the built-in signed 32-bit integer type.
+var Int = &Scalar{
+	Name: "Int",
+	Serialize: func(v any) (any, error) {
+		f, ok := toFloat(v)
+		if !ok || f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, fmt.Errorf("cannot represent %v as Int", v)
+		}
+		return int(f), nil
+	},
+	Parse: func(v any) (any, error) {
+		f, ok := toFloat(v)
+		if !ok || f != math.Trunc(f) || f < math.MinInt32 || f > math.MaxInt32 {
+			return nil, fmt.Errorf("expected an Int, got %v", v)
+		}
+		return int(f), nil
+	},
+}
+
+// Float is the built-in double precision floating point type.
+var Float = &Scalar{
+	Name: "Float",
+	Serialize: func(v any) (any, error) {
+		f, ok := toFloat(v)
+		if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("cannot represent %v as Float", v)
+		}
+		return f, nil
+	},
+	Parse: func(v any) (any, error) {
+		f, ok := toFloat(v)
+		if !ok {
+			return nil, fmt.Errorf("expected a Float, got %v", v)
+		}
+		return f, nil
+	},
+}
+
+// String is the built-in UTF-8 text type. Values of types based on string, such as enums, serialize as String.
+var String = &Scalar{
+	Name: "String",
+	Serialize: func(v any) (any, error) {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot represent %v as String", v)
+		}
+		return rv.String(), nil
+	},
+	Parse: func(v any) (any, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a String, got %v", v)
+		}
+		return s, nil
+	},
+}
+
+// Boolean is the built-in true or false type.
+var Boolean = &Scalar{
+	Name: "Boolean",
+	Serialize: func(v any) (any, error) {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot represent %v as Boolean", v)
+		}
+		return b, nil
+	},
+	Parse: func(v any) (any, error) {
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a Boolean, got %v", v)
+		}
+		return b, nil
+	},
+}
+
+// toFloat converts the numeric kinds to float64. Numbers decoded from JSON variables are float64.
+func toFloat(v any) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// coerceInput converts an argument or a variable to the given type, checking that it is valid.
+func coerceInput(t Type, v any) (any, error) {
+	if nonNull, ok := t.(NonNull); ok {
+		if v == nil {
+			return nil, fmt.Errorf("expected a non-null %s", nonNull.Of)
+		}
+		return coerceInput(nonNull.Of, v)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	switch t := t.(type) {
+	case *Scalar:
+		return t.Parse(v)
+	case List:
+		items, ok := v.([]any)
+		if !ok {
+			// A single value is a list of one
+			items = []any{v}
+		}
+		list := make([]any, len(items))
+		for i, item := range items {
+			coerced, err := coerceInput(t.Of, item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = coerced
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("%s cannot be used as an input type", t)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+)
+
+func TestMain_graphqlHandler(t *testing.T) {
+	var geocodeRequests, forecastRequests atomic.Int32
+	today := time.Now().UTC().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/maps/api/geocode/json":
+			geocodeRequests.Add(1)
+			fmt.Fprint(w, `{"results": [{"formatted_address": "5 Graph Ln, Austin, TX 78705, USA",
+				"geometry": {"location": {"lat": 30.2901, "lng": -97.7411}}}], "status": "OK"}`)
+		case "/v1/forecast":
+			forecastRequests.Add(1)
+			fmt.Fprintf(w, `{"timezone": "UTC", "current": {"temperature_2m": 78.6},
+				"daily": {"time": ["%s"], "temperature_2m_max": [97.6], "temperature_2m_min": [75.8], "weather_code": [0]}}`, today)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.GeocodeURL = server.URL
+	cfg.Providers.GeocodeAPIKey = "testApiKey"
+	cfg.Providers.ForecastURL = server.URL
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	apiServer := httptest.NewServer(newServeMux(context.Background(), cfg, cache.GetCacheInstance(), favs))
+	defer apiServer.Close()
+
+	// tooManyQuery looks up one forecast more than a request is allowed to
+	var tooManyQuery, tooManyData strings.Builder
+	for i := 0; i <= graphqlMaxLookups; i++ {
+		fmt.Fprintf(&tooManyQuery, "f%d: forecast(lat: %.2f, lon: -97.7411) { units } ", i, 31+float64(i)/100)
+		if i < graphqlMaxLookups {
+			fmt.Fprintf(&tooManyData, `"f%d":{"units":"fahrenheit"},`, i)
+		}
+	}
+
+	tc := []struct {
+		name             string
+		query            string
+		expected         string
+		geocodeRequests  int32
+		forecastRequests int32
+	}{
+		{
+			name: "Batched Lookups",
+			query: `{
+				a: location(address: "5 Graph Ln") { address forecast { current { temperature } daily { date high weather } } }
+				b: location(address: "5 Graph Ln") { forecast { timezone } }
+				c: forecast(lat: 30.2901, lon: -97.7411, units: "celsius", days: 3) { units fromCache }
+			}`,
+			expected: `{"data":{"a":{"address":"5 Graph Ln, Austin, TX 78705, USA","forecast":{"current":{"temperature":78.6},` +
+				`"daily":[{"date":"` + today + `","high":97.6,"weather":"Clear sky"}]}},"b":{"forecast":{"timezone":"UTC"}},` +
+				`"c":{"units":"celsius","fromCache":false}}}`,
+			geocodeRequests:  1,
+			forecastRequests: 2,
+		},
+		{
+			name:             "Served From Cache",
+			query:            `{ forecast(lat: 30.2901, lon: -97.7411, units: "celsius", days: 3) { fromCache } }`,
+			expected:         `{"data":{"forecast":{"fromCache":true}}}`,
+			geocodeRequests:  1,
+			forecastRequests: 2,
+		},
+		{
+			name:             "Invalid Units",
+			query:            `{ forecast(lat: 30.2901, lon: -97.7411, units: "kelvin") { units } }`,
+			expected:         `{"data":{"forecast":null},"errors":[{"message":"invalid units \"kelvin\": must be fahrenheit or celsius","path":["forecast"]}]}`,
+			geocodeRequests:  1,
+			forecastRequests: 2,
+		},
+		{
+			name:             "Invalid Days",
+			query:            `{ forecast(lat: 30.2901, lon: -97.7411, days: 17) { units } }`,
+			expected:         `{"data":{"forecast":null},"errors":[{"message":"invalid forecast days 17: must be between 1 and 16","path":["forecast"]}]}`,
+			geocodeRequests:  1,
+			forecastRequests: 2,
+		},
+		{
+			name:  "Too Many Lookups",
+			query: "{ " + tooManyQuery.String() + "}",
+			expected: `{"data":{` + tooManyData.String() + fmt.Sprintf(`"f%d":null},"errors":[{"message":`, graphqlMaxLookups) +
+				fmt.Sprintf(`"too many distinct lookups in one request: at most %d are allowed","path":["f%d"]}]}`, graphqlMaxLookups, graphqlMaxLookups),
+			geocodeRequests:  1,
+			forecastRequests: 2 + graphqlMaxLookups,
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"query": %q}`, tc.query)
+			resp, err := http.Post(apiServer.URL+"/graphql", "application/json", strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+			if strings.TrimSpace(string(data)) != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, data)
+			}
+			if geocodeRequests.Load() != tc.geocodeRequests || forecastRequests.Load() != tc.forecastRequests {
+				t.Errorf("Expected %d geocode and %d forecast requests, got %d and %d", tc.geocodeRequests, tc.forecastRequests,
+					geocodeRequests.Load(), forecastRequests.Load())
+			}
+		})
+	}
+}
+
+func TestMain_runServe(t *testing.T) {
+	cfg := config.Defaults()
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+
+	// Canceling the context shuts the server down cleanly
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var b strings.Builder
+	if err := runServe(ctx, []string{"--addr", "127.0.0.1:0"}, cfg, cache.GetCacheInstance(), favs, &b); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(b.String(), "Serving the weather API on http://127.0.0.1:") {
+		t.Errorf("Expected the listen address, got %q", b.String())
+	}
+
+	err := runServe(context.Background(), []string{"extra"}, cfg, cache.GetCacheInstance(), favs, &b)
+	if err == nil || !strings.Contains(err.Error(), "usage: weather serve") {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+}
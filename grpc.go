@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/logging"
+	"github.com/mfryhover/weather/weatherpb"
+)
+
+const (
+	// defaultWatchInterval is how often WatchForecast refreshes the forecast when the request has no interval.
+	defaultWatchInterval = 10 * time.Minute
+	// minWatchInterval is the shortest interval WatchForecast accepts.
+	minWatchInterval = time.Second
+	// grpcHours is the number of hours of the hourly forecast sent with each forecast.
+	grpcHours = 24
+)
+
+// weatherServer implements weatherpb.WeatherServiceServer over the favorites, the API functions and the cache.
+type weatherServer struct {
+	weatherpb.UnimplementedWeatherServiceServer
+
+	// done ends the watches in progress, so that stopping the server gracefully does not wait for them.
+	done context.Context
+	cfg  config.Config
+	c    *cache.Cache
+	favs *favorites.Store
+}
+
+// newGRPCServer returns a gRPC server with the weather service registered. Watches end once ctx is done, so that
+// stopping the server gracefully does not wait for them.
+func newGRPCServer(ctx context.Context, cfg config.Config, c *cache.Cache, favs *favorites.Store) *grpc.Server {
+	server := grpc.NewServer()
+	weatherpb.RegisterWeatherServiceServer(server, &weatherServer{done: ctx, cfg: cfg, c: c, favs: favs})
+	return server
+}
+
+// Geocode implements weatherpb.WeatherServiceServer.
+func (s *weatherServer) Geocode(ctx context.Context, req *weatherpb.GeocodeRequest) (*weatherpb.Location, error) {
+	return s.geocode(logging.WithRequestID(ctx, logging.NewRequestID()), req.GetAddress())
+}
+
+// GetForecast implements weatherpb.WeatherServiceServer.
+func (s *weatherServer) GetForecast(ctx context.Context, req *weatherpb.GetForecastRequest) (*weatherpb.Forecast, error) {
+	ctx = logging.WithRequestID(ctx, logging.NewRequestID())
+	location, opts, err := s.resolve(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.forecast(ctx, location, opts)
+}
+
+// WatchForecast implements weatherpb.WeatherServiceServer. Refreshes that fail are logged and skipped, so that a
+// watch outlives short upstream outages; only the first forecast must succeed.
+func (s *weatherServer) WatchForecast(req *weatherpb.WatchForecastRequest, stream grpc.ServerStreamingServer[weatherpb.Forecast]) error {
+	interval := defaultWatchInterval
+	if req.GetInterval() != nil {
+		interval = req.GetInterval().AsDuration()
+	}
+	if interval < minWatchInterval {
+		return status.Errorf(codes.InvalidArgument, "invalid interval %s: must be at least %s", interval, minWatchInterval)
+	}
+	ctx := stream.Context()
+	location, opts, err := s.resolve(logging.WithRequestID(ctx, logging.NewRequestID()), req.GetForecast())
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for first := true; ; first = false {
+		forecast, err := s.forecast(logging.WithRequestID(ctx, logging.NewRequestID()), location, opts)
+		switch {
+		case err != nil && first:
+			return err
+		case err != nil:
+			slog.WarnContext(ctx, "forecast refresh failed", "error", err)
+		default:
+			if err := stream.Send(forecast); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.done.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// resolve returns the location and the forecast options of a forecast request, geocoding its address if it has one.
+func (s *weatherServer) resolve(ctx context.Context, req *weatherpb.GetForecastRequest) (*weatherpb.Location, api.ForecastOptions, error) {
+	opts := forecastOptions(s.cfg)
+	switch req.GetUnits() {
+	case weatherpb.Units_UNITS_FAHRENHEIT:
+		opts.Units = api.Fahrenheit
+	case weatherpb.Units_UNITS_CELSIUS:
+		opts.Units = api.Celsius
+	}
+	if req.GetDays() != 0 {
+		opts.ForecastDays = int(req.GetDays())
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, opts, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	switch location := req.GetLocation().(type) {
+	case *weatherpb.GetForecastRequest_Address:
+		l, err := s.geocode(ctx, location.Address)
+		return l, opts, err
+	case *weatherpb.GetForecastRequest_Coordinates:
+		return &weatherpb.Location{Coordinates: location.Coordinates}, opts, nil
+	}
+	return nil, opts, status.Error(codes.InvalidArgument, "an address or coordinates are required")
+}
+
+// geocode resolves an address or a favorite name to its location.
+func (s *weatherServer) geocode(ctx context.Context, address string) (*weatherpb.Location, error) {
+	if strings.TrimSpace(address) == "" {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+	addressFull, lat, lng, err := lookupCoordinates(ctx, address, s.favs, s.cfg)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return &weatherpb.Location{Address: addressFull, Coordinates: &weatherpb.Coordinates{Latitude: lat, Longitude: lng}}, nil
+}
+
+// forecast retrieves the forecast of a location through the cache.
+func (s *weatherServer) forecast(ctx context.Context, location *weatherpb.Location, opts api.ForecastOptions) (*weatherpb.Forecast, error) {
+	lat, lng := location.GetCoordinates().GetLatitude(), location.GetCoordinates().GetLongitude()
+	entry, isFromCache, err := getForecastEntry(ctx, location.GetAddress(), lat, lng, s.c, s.cfg.Providers.ForecastURL, opts)
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return forecastProto(location, opts.Units, entry, isFromCache, time.Now()), nil
+}
+
+// forecastProto converts a cached forecast to its protobuf message, updated when it was cached, with the hourly
+// forecast from now on.
+func forecastProto(location *weatherpb.Location, units api.Units, entry cache.Entry, isFromCache bool, now time.Time) *weatherpb.Forecast {
+	weeklyForecast := entry.WeeklyForecast
+	forecast := &weatherpb.Forecast{
+		Location:           location,
+		Units:              weatherpb.Units_UNITS_FAHRENHEIT,
+		Timezone:           weeklyForecast.Timezone,
+		CurrentTemperature: entry.CurrentTemp,
+		FromCache:          isFromCache,
+		UpdatedAt:          timestamppb.New(entry.Timestamp),
+	}
+	if units == api.Celsius {
+		forecast.Units = weatherpb.Units_UNITS_CELSIUS
+	}
+
+	// optional returns a pointer to the value of the given day, or nil if the forecast does not have it
+	optional := func(values []float64, day int) *float64 {
+		if day < len(values) {
+			v := values[day]
+			return &v
+		}
+		return nil
+	}
+	for i, date := range weeklyForecast.Time {
+		day := &weatherpb.Day{
+			Date:                date,
+			High:                optional(weeklyForecast.Temperature2MMax, i),
+			Low:                 optional(weeklyForecast.Temperature2MMin, i),
+			Precipitation:       optional(weeklyForecast.PrecipitationSum, i),
+			PrecipitationChance: optional(weeklyForecast.PrecipitationProbabilityMax, i),
+			Snowfall:            optional(weeklyForecast.SnowfallSum, i),
+			UvIndex:             optional(weeklyForecast.UVIndexMax, i),
+		}
+		if i < len(weeklyForecast.WeatherCode) {
+			code := int32(weeklyForecast.WeatherCode[i])
+			weather := api.WeatherDescription(weeklyForecast.WeatherCode[i])
+			day.WeatherCode, day.Weather = &code, &weather
+		}
+		if sunrise, ok := weeklyForecast.SunriseAt(i); ok {
+			day.Sunrise = timestamppb.New(sunrise)
+		}
+		if sunset, ok := weeklyForecast.SunsetAt(i); ok {
+			day.Sunset = timestamppb.New(sunset)
+		}
+		forecast.Daily = append(forecast.Daily, day)
+	}
+
+	times, temperatures := weeklyForecast.HoursFrom(now, grpcHours)
+	for i, t := range times {
+		forecast.Hourly = append(forecast.Hourly, &weatherpb.Hour{Time: timestamppb.New(t), Temperature: temperatures[i]})
+	}
+	return forecast
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/weatherpb"
+)
+
+// newTestWeatherClient serves the weather service on an in-memory listener until ctx is done, and returns a client
+// connected to it.
+func newTestWeatherClient(t *testing.T, ctx context.Context, cfg config.Config) weatherpb.WeatherServiceClient {
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer(ctx, cfg, cache.GetCacheInstance(), favs)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return weatherpb.NewWeatherServiceClient(conn)
+}
+
+func TestMain_weatherServer(t *testing.T) {
+	var geocodeRequests, forecastRequests atomic.Int32
+	today := time.Now().UTC().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/maps/api/geocode/json":
+			geocodeRequests.Add(1)
+			fmt.Fprint(w, `{"results": [{"formatted_address": "9 Proto Way, Austin, TX 78731, USA",
+				"geometry": {"location": {"lat": 30.3301, "lng": -97.7551}}}], "status": "OK"}`)
+		case "/v1/forecast":
+			forecastRequests.Add(1)
+			fmt.Fprintf(w, `{"timezone": "UTC", "current": {"temperature_2m": 81.3},
+				"daily": {"time": ["%s"], "temperature_2m_max": [96.1], "temperature_2m_min": [74.2], "weather_code": [2]}}`, today)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.GeocodeURL = server.URL
+	cfg.Providers.GeocodeAPIKey = "testApiKey"
+	cfg.Providers.ForecastURL = server.URL
+	client := newTestWeatherClient(t, context.Background(), cfg)
+	ctx := context.Background()
+
+	location, err := client.Geocode(ctx, &weatherpb.GeocodeRequest{Address: "9 Proto Way"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if location.GetAddress() != "9 Proto Way, Austin, TX 78731, USA" || location.GetCoordinates().GetLatitude() != 30.3301 {
+		t.Errorf("Expected the geocoded location, got %v", location)
+	}
+
+	tc := []struct {
+		name             string
+		req              *weatherpb.GetForecastRequest
+		address          string
+		units            weatherpb.Units
+		fromCache        bool
+		geocodeRequests  int32
+		forecastRequests int32
+	}{
+		{
+			name:             "By Address",
+			req:              &weatherpb.GetForecastRequest{Location: &weatherpb.GetForecastRequest_Address{Address: "9 Proto Way"}},
+			address:          "9 Proto Way, Austin, TX 78731, USA",
+			units:            weatherpb.Units_UNITS_FAHRENHEIT,
+			geocodeRequests:  2,
+			forecastRequests: 1,
+		},
+		{
+			name: "By Coordinates",
+			req: &weatherpb.GetForecastRequest{Location: &weatherpb.GetForecastRequest_Coordinates{
+				Coordinates: &weatherpb.Coordinates{Latitude: 30.3301, Longitude: -97.7551},
+			}},
+			units:            weatherpb.Units_UNITS_FAHRENHEIT,
+			geocodeRequests:  2,
+			forecastRequests: 2,
+		},
+		{
+			name: "By Coordinates From Cache",
+			req: &weatherpb.GetForecastRequest{Location: &weatherpb.GetForecastRequest_Coordinates{
+				Coordinates: &weatherpb.Coordinates{Latitude: 30.3301, Longitude: -97.7551},
+			}},
+			units:            weatherpb.Units_UNITS_FAHRENHEIT,
+			fromCache:        true,
+			geocodeRequests:  2,
+			forecastRequests: 2,
+		},
+		{
+			name: "Celsius",
+			req: &weatherpb.GetForecastRequest{
+				Location: &weatherpb.GetForecastRequest_Coordinates{Coordinates: &weatherpb.Coordinates{Latitude: 30.3301, Longitude: -97.7551}},
+				Units:    weatherpb.Units_UNITS_CELSIUS,
+			},
+			units:            weatherpb.Units_UNITS_CELSIUS,
+			geocodeRequests:  2,
+			forecastRequests: 3,
+		},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			forecast, err := client.GetForecast(ctx, tc.req)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if forecast.GetLocation().GetAddress() != tc.address || forecast.GetUnits() != tc.units || forecast.GetFromCache() != tc.fromCache {
+				t.Errorf("Expected address %q, units %v and fromCache %v, got %v", tc.address, tc.units, tc.fromCache, forecast)
+			}
+			if forecast.GetCurrentTemperature() != 81.3 || len(forecast.GetDaily()) != 1 {
+				t.Fatalf("Expected the current temperature and one day, got %v", forecast)
+			}
+			day := forecast.GetDaily()[0]
+			if day.GetDate() != today || day.GetHigh() != 96.1 || day.GetWeather() != "Partly cloudy" || day.Snowfall != nil {
+				t.Errorf("Expected today's forecast without snowfall, got %v", day)
+			}
+			if geocodeRequests.Load() != tc.geocodeRequests || forecastRequests.Load() != tc.forecastRequests {
+				t.Errorf("Expected %d geocode and %d forecast requests, got %d and %d", tc.geocodeRequests, tc.forecastRequests,
+					geocodeRequests.Load(), forecastRequests.Load())
+			}
+		})
+	}
+}
+
+func TestMain_weatherServerErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results": [], "status": "ZERO_RESULTS"}`)
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.GeocodeURL = server.URL
+	cfg.Providers.GeocodeAPIKey = "testApiKey"
+	client := newTestWeatherClient(t, context.Background(), cfg)
+	ctx := context.Background()
+	coordinates := &weatherpb.GetForecastRequest_Coordinates{Coordinates: &weatherpb.Coordinates{Latitude: 30.3302, Longitude: -97.7552}}
+
+	tc := []struct {
+		name string
+		call func() error
+		code codes.Code
+	}{
+		{
+			name: "Empty Address",
+			call: func() error {
+				_, err := client.Geocode(ctx, &weatherpb.GeocodeRequest{Address: " "})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Unknown Address",
+			call: func() error {
+				_, err := client.Geocode(ctx, &weatherpb.GeocodeRequest{Address: "Nowhere"})
+				return err
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "Missing Location",
+			call: func() error {
+				_, err := client.GetForecast(ctx, &weatherpb.GetForecastRequest{})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Invalid Days",
+			call: func() error {
+				_, err := client.GetForecast(ctx, &weatherpb.GetForecastRequest{Location: coordinates, Days: 17})
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "Invalid Interval",
+			call: func() error {
+				stream, err := client.WatchForecast(ctx, &weatherpb.WatchForecastRequest{
+					Forecast: &weatherpb.GetForecastRequest{Location: coordinates},
+					Interval: durationpb.New(time.Millisecond),
+				})
+				if err != nil {
+					return err
+				}
+				_, err = stream.Recv()
+				return err
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			if code := status.Code(tc.call()); code != tc.code {
+				t.Errorf("Expected code %v, got %v", tc.code, code)
+			}
+		})
+	}
+}
+
+func TestMain_weatherServerWatchForecast(t *testing.T) {
+	var forecastRequests atomic.Int32
+	today := time.Now().UTC().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forecastRequests.Add(1)
+		fmt.Fprintf(w, `{"timezone": "UTC", "current": {"temperature_2m": 82.5}, "daily": {"time": ["%s"]}}`, today)
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ForecastURL = server.URL
+	client := newTestWeatherClient(t, context.Background(), cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.WatchForecast(ctx, &weatherpb.WatchForecastRequest{
+		Forecast: &weatherpb.GetForecastRequest{Location: &weatherpb.GetForecastRequest_Coordinates{
+			Coordinates: &weatherpb.Coordinates{Latitude: 30.3303, Longitude: -97.7553},
+		}},
+		Interval: durationpb.New(time.Second),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The refresh within the cache TTL is served from the cache, updated when it was first retrieved
+	var updatedAt time.Time
+	for _, fromCache := range []bool{false, true} {
+		forecast, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if forecast.GetCurrentTemperature() != 82.5 || forecast.GetFromCache() != fromCache {
+			t.Errorf("Expected a current temperature of 82.5 with fromCache %v, got %v", fromCache, forecast)
+		}
+		if !fromCache {
+			updatedAt = forecast.GetUpdatedAt().AsTime()
+		} else if !forecast.GetUpdatedAt().AsTime().Equal(updatedAt) {
+			t.Errorf("Expected updatedAt %v, got %v", updatedAt, forecast.GetUpdatedAt().AsTime())
+		}
+	}
+	if forecastRequests.Load() != 1 {
+		t.Errorf("Expected 1 forecast request, got %d", forecastRequests.Load())
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Errorf("Expected the stream to be canceled, got %v", err)
+	}
+}
+
+func TestMain_weatherServerGracefulStop(t *testing.T) {
+	today := time.Now().UTC().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"timezone": "UTC", "current": {"temperature_2m": 79.1}, "daily": {"time": ["%s"]}}`, today)
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ForecastURL = server.URL
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	serveCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := newGRPCServer(serveCtx, cfg, cache.GetCacheInstance(), favs)
+	go grpcServer.Serve(listener)
+	conn, _ := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	defer conn.Close()
+
+	stream, err := weatherpb.NewWeatherServiceClient(conn).WatchForecast(context.Background(), &weatherpb.WatchForecastRequest{
+		Forecast: &weatherpb.GetForecastRequest{Location: &weatherpb.GetForecastRequest_Coordinates{
+			Coordinates: &weatherpb.Coordinates{Latitude: 30.3304, Longitude: -97.7554},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// Shutting down ends the watch in progress, so that the graceful stop does not wait for the client
+	stop()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		grpcServer.Stop()
+		t.Fatal("Expected the graceful stop not to wait for the watch")
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Expected the watch to end, got %v", err)
+	}
+}
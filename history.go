@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+	"github.com/mfryhover/weather/logging"
+)
+
+// parseInterleaved parses args with flags, allowing flags to follow positional arguments as in
+// "history hq --from 2024-09-01". It returns the positional arguments in order.
+func parseInterleaved(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+// historyCacheKey returns the cache key of the history of the given location key between two dates in the given unit
+// system.
+func historyCacheKey(location string, from time.Time, to time.Time, opts api.ForecastOptions) string {
+	units := opts.Units
+	if units == "" {
+		units = api.Fahrenheit
+	}
+	return fmt.Sprintf("history:%s:%s:%s:%s", location, units, from.Format("2006-01-02"), to.Format("2006-01-02"))
+}
+
+// getHistory retrieves the daily weather observed at an address that has already been geocoded between two dates,
+// both inclusive. It returns the history and a boolean indicating if it was retrieved from the cache. History never
+// changes and is cached permanently.
+func getHistory(ctx context.Context, addressFull string, lat, lng float64, c *cache.Cache, archiveURL string, from time.Time, to time.Time, opts api.ForecastOptions) (api.WeeklyForecast, bool, error) {
+	key := historyCacheKey(locationKey(addressFull, lat, lng), from, to, opts)
+	_, history, ok := c.Get(key)
+	slog.InfoContext(ctx, "cache lookup", "key", key, "hit", ok)
+	if ok {
+		return history, true, nil
+	}
+
+	history, err := api.GetHistoryContext(ctx, lat, lng, archiveURL, from, to, opts)
+	if err != nil {
+		return api.WeeklyForecast{}, false, fmt.Errorf("error retrieving history: %v", err)
+	}
+	c.AddPermanent(key, history)
+	return history, false, nil
+}
+
+// writeHistory writes a table with one row per day of the history.
+func writeHistory(w io.Writer, addressFull string, history api.WeeklyForecast, isFromCache bool, units api.Units, lc locale.Locale) {
+	if isFromCache {
+		fmt.Fprintln(w, "***Retrieved history from cache***")
+	}
+	fmt.Fprintf(w, "Here is the weather history for address: %s\n", addressFull)
+	if history.Timezone != "" {
+		fmt.Fprintf(w, "Times are local to %s\n", history.Timezone)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(tw, "Date\tWeather\tMax (%s)\tMin (%s)\tPrecipitation (%s)\tSunrise\tSunset\n",
+		units.Symbol(), units.Symbol(), units.PrecipitationSymbol())
+	for dayIndex, day := range history.Time {
+		weather, maxTemp, minTemp, precipitation, sunrise, sunset := "-", "-", "-", "-", "-", "-"
+		if dayIndex < len(history.WeatherCode) {
+			weather = api.WeatherDescription(history.WeatherCode[dayIndex])
+		}
+		if dayIndex < len(history.Temperature2MMax) {
+			maxTemp = lc.Number(history.Temperature2MMax[dayIndex], 1)
+		}
+		if dayIndex < len(history.Temperature2MMin) {
+			minTemp = lc.Number(history.Temperature2MMin[dayIndex], 1)
+		}
+		if dayIndex < len(history.PrecipitationSum) {
+			precipitation = lc.Number(history.PrecipitationSum[dayIndex], precipitationDecimals(units))
+		}
+		if t, ok := history.SunriseAt(dayIndex); ok {
+			sunrise = lc.Time(t)
+		}
+		if t, ok := history.SunsetAt(dayIndex); ok {
+			sunset = lc.Time(t)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", day, weather, maxTemp, minTemp, precipitation, sunrise, sunset)
+	}
+	tw.Flush()
+}
+
+// runHistory runs the history command, which displays the daily weather observed at an address or a favorite
+// between two dates.
+func runHistory(args []string, cfg config.Config, c *cache.Cache, favs *favorites.Store, stdout io.Writer) error {
+	flags := flag.NewFlagSet("history", flag.ContinueOnError)
+	fromFlag := flags.String("from", "", "first day of the range, as YYYY-MM-DD")
+	toFlag := flags.String("to", "", "last day of the range, as YYYY-MM-DD (default: the first day)")
+	positional, err := parseInterleaved(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *fromFlag == "" {
+		return errors.New("usage: weather history <location> --from YYYY-MM-DD [--to YYYY-MM-DD]")
+	}
+	if *toFlag == "" {
+		*toFlag = *fromFlag
+	}
+	from, err := time.Parse("2006-01-02", *fromFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --from date %q: must be YYYY-MM-DD", *fromFlag)
+	}
+	to, err := time.Parse("2006-01-02", *toFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --to date %q: must be YYYY-MM-DD", *toFlag)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("invalid date range: %s is before %s", *toFlag, *fromFlag)
+	}
+	if cfg.Providers.GeocodeAPIKey == "" {
+		return errors.New("GEOCODE_API_KEY environment variable is not set")
+	}
+	lc, err := locale.Lookup(cfg.Locale)
+	if err != nil {
+		return err
+	}
+	opts := forecastOptions(cfg)
+
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	addressFull, lat, lng, err := lookupCoordinates(ctx, positional[0], favs, cfg)
+	if err != nil {
+		return err
+	}
+	history, isFromCache, err := getHistory(ctx, addressFull, lat, lng, c, cfg.Providers.ArchiveURL, from, to, opts)
+	if err != nil {
+		return err
+	}
+
+	writeHistory(stdout, addressFull, history, isFromCache, opts.Units, lc)
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+)
+
+func TestMain_parseInterleaved(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	from := flags.String("from", "", "")
+	to := flags.String("to", "", "")
+
+	positional, err := parseInterleaved(flags, []string{"hq", "--from", "2024-09-01", "extra", "--to", "2024-09-02"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(positional, " ") != "hq extra" || *from != "2024-09-01" || *to != "2024-09-02" {
+		t.Errorf("Expected positional [hq extra] from 2024-09-01 to 2024-09-02, got %v from %s to %s", positional, *from, *to)
+	}
+}
+
+func TestMain_runHistory(t *testing.T) {
+	archiveRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/archive" {
+			archiveRequests++
+			w.Write([]byte(`{"timezone": "America/Chicago", "utc_offset_seconds": -18000,
+				"daily": {"time": ["2024-09-01", "2024-09-02"], "temperature_2m_max": [99.1, 97.3], "temperature_2m_min": [76.3, 75.0],
+				"precipitation_sum": [0, 0.42], "weather_code": [1, 63],
+				"sunrise": ["2024-09-01T07:05", "2024-09-02T07:06"], "sunset": ["2024-09-01T19:52", "2024-09-02T19:51"]}}`))
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ArchiveURL = server.URL
+	cfg.Providers.GeocodeAPIKey = "testApiKey"
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	favs.Save(favorites.Place{Name: "site", Address: "3001 Esperanza Crossing, Austin, TX 78741, USA", Latitude: 30.3985991, Longitude: -97.7220666})
+
+	var b strings.Builder
+	if err := runHistory([]string{"site", "--from", "2024-09-01", "--to", "2024-09-02"}, cfg, cache.GetCacheInstance(), favs, &b); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, expected := range []string{"Times are local to America/Chicago", "2024-09-01   Mainly clear   99.1", "2024-09-02   Rain           97.3", "7:05 AM"} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("Expected the history to contain %q, got:\n%s", expected, b.String())
+		}
+	}
+
+	// History is served from the cache from then on
+	b.Reset()
+	if err := runHistory([]string{"site", "--from", "2024-09-01", "--to", "2024-09-02"}, cfg, cache.GetCacheInstance(), favs, &b); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if archiveRequests != 1 || !strings.Contains(b.String(), "Retrieved history from cache") {
+		t.Errorf("Expected the second lookup to be served from the cache, got %d requests", archiveRequests)
+	}
+
+	// Recent history is cached permanently as well
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Truncate(24 * time.Hour)
+	if _, _, err := getHistory(context.Background(), "recent", 30.2672, -97.7431, cache.GetCacheInstance(), server.URL, yesterday, yesterday, api.ForecastOptions{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	key := historyCacheKey(locationKey("recent", 30.2672, -97.7431), yesterday, yesterday, api.ForecastOptions{})
+	if entry, ok := cache.GetCacheInstance().GetEntry(key); !ok || !entry.Permanent {
+		t.Errorf("Expected recent history to be cached permanently, got %v", entry)
+	}
+
+	tc := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "Missing From", args: []string{"site"}, err: "usage: weather history"},
+		{name: "Invalid Date", args: []string{"site", "--from", "09/01/2024"}, err: `invalid --from date "09/01/2024"`},
+		{name: "Reversed Range", args: []string{"site", "--from", "2024-09-02", "--to", "2024-09-01"}, err: "invalid date range"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			err := runHistory(tc.args, cfg, cache.GetCacheInstance(), favs, &b)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected an error containing '%s', got %v", tc.err, err)
+			}
+		})
+	}
+}
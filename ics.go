@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/atomicfile"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/logging"
+)
+
+// icsLineLimit is the length in bytes that iCalendar content lines are folded at, per RFC 5545.
+const icsLineLimit = 75
+
+// icsProductID identifies the app as the producer of the calendars it exports.
+const icsProductID = "-//mfryhover//World's Best Weather App//EN"
+
+// icsEscaper escapes the characters that are special in iCalendar text values.
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// icsUID returns the unique identifier of the event of a day of the forecast for the given location key. It only
+// depends on the location and the day, so that calendar clients subscribed to the feed update the events of a day as
+// the forecast changes instead of duplicating them.
+func icsUID(location string, day string) string {
+	sum := sha1.Sum([]byte(location))
+	return fmt.Sprintf("%s-%s@weather", strings.ReplaceAll(day, "-", ""), hex.EncodeToString(sum[:8]))
+}
+
+// icsSummary returns the title of the event of a day, such as "H 97 / L 76, Sunny".
+func icsSummary(weeklyForecast api.WeeklyForecast, dayIndex int) string {
+	summary := fmt.Sprintf("H %.0f / L %.0f", weeklyForecast.Temperature2MMax[dayIndex], weeklyForecast.Temperature2MMin[dayIndex])
+	if dayIndex < len(weeklyForecast.WeatherCode) {
+		summary += ", " + api.WeatherDescription(weeklyForecast.WeatherCode[dayIndex])
+	}
+	return summary
+}
+
+// icsDescription returns the details of the event of a day: the temperatures with their units and the precipitation,
+// when the forecast has them.
+func icsDescription(weeklyForecast api.WeeklyForecast, dayIndex int, units api.Units) string {
+	lines := []string{fmt.Sprintf("High %.1f %s, low %.1f %s", weeklyForecast.Temperature2MMax[dayIndex], units.Symbol(),
+		weeklyForecast.Temperature2MMin[dayIndex], units.Symbol())}
+	if dayIndex < len(weeklyForecast.PrecipitationSum) {
+		line := fmt.Sprintf("Precipitation %.*f %s", precipitationDecimals(units), weeklyForecast.PrecipitationSum[dayIndex], units.PrecipitationSymbol())
+		if dayIndex < len(weeklyForecast.PrecipitationProbabilityMax) {
+			line += fmt.Sprintf(", %.0f%% chance", weeklyForecast.PrecipitationProbabilityMax[dayIndex])
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeICSLine writes a content line, folding it into lines of at most icsLineLimit bytes that continue with a space.
+// Lines are only folded between characters, so that multi-byte characters are not split.
+func writeICSLine(w io.Writer, line string) {
+	for len(line) > icsLineLimit {
+		cut := icsLineLimit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		fmt.Fprintf(w, "%s\r\n", line[:cut])
+		line = " " + line[cut:]
+	}
+	fmt.Fprintf(w, "%s\r\n", line)
+}
+
+// writeICS writes the forecast as an iCalendar feed with one all-day event per day, such as "H 97 / L 76, Sunny".
+// location is the location key of the address, which the event UIDs are derived from. now is the time the feed is
+// generated at, which clients compare to tell updated events.
+func writeICS(w io.Writer, addressFull string, location string, weeklyForecast api.WeeklyForecast, units api.Units, now time.Time) error {
+	dates, err := weeklyForecast.Dates()
+	if err != nil {
+		return fmt.Errorf("error reading forecast dates: %v", err)
+	}
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	writeICSLine(w, "BEGIN:VCALENDAR")
+	writeICSLine(w, "VERSION:2.0")
+	writeICSLine(w, "PRODID:"+icsProductID)
+	writeICSLine(w, "CALSCALE:GREGORIAN")
+	writeICSLine(w, "METHOD:PUBLISH")
+	writeICSLine(w, "X-WR-CALNAME:"+icsEscaper.Replace("Weather for "+addressFull))
+	for dayIndex, date := range dates {
+		if dayIndex >= len(weeklyForecast.Temperature2MMax) || dayIndex >= len(weeklyForecast.Temperature2MMin) {
+			break
+		}
+		writeICSLine(w, "BEGIN:VEVENT")
+		writeICSLine(w, "UID:"+icsUID(location, weeklyForecast.Time[dayIndex]))
+		writeICSLine(w, "DTSTAMP:"+stamp)
+		writeICSLine(w, "LAST-MODIFIED:"+stamp)
+		writeICSLine(w, "DTSTART;VALUE=DATE:"+date.Format("20060102"))
+		writeICSLine(w, "DTEND;VALUE=DATE:"+date.AddDate(0, 0, 1).Format("20060102"))
+		writeICSLine(w, "SUMMARY:"+icsEscaper.Replace(icsSummary(weeklyForecast, dayIndex)))
+		writeICSLine(w, "DESCRIPTION:"+icsEscaper.Replace(icsDescription(weeklyForecast, dayIndex, units)))
+		writeICSLine(w, "LOCATION:"+icsEscaper.Replace(addressFull))
+		// Forecasts are informational and do not make anyone busy
+		writeICSLine(w, "TRANSP:TRANSPARENT")
+		writeICSLine(w, "END:VEVENT")
+	}
+	writeICSLine(w, "END:VCALENDAR")
+	return nil
+}
+
+// runICS runs the ics command, which exports the forecast of an address or a favorite as an iCalendar feed, written
+// to stdout or to the file given with --output.
+func runICS(args []string, cfg config.Config, c *cache.Cache, favs *favorites.Store, stdout io.Writer) error {
+	flags := flag.NewFlagSet("ics", flag.ContinueOnError)
+	output := flags.String("output", "", "file to write the feed to (default: stdout)")
+	positional, err := parseInterleaved(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: weather ics <location> [--output weather.ics]")
+	}
+	opts := forecastOptions(cfg)
+
+	ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+	addressFull, lat, lng, err := lookupCoordinates(ctx, positional[0], favs, cfg)
+	if err != nil {
+		return err
+	}
+	_, weeklyForecast, _, err := getForecastForCoordinates(ctx, addressFull, lat, lng, c, cfg.Providers.ForecastURL, opts)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	if err := writeICS(&b, addressFull, locationKey(addressFull, lat, lng), weeklyForecast, opts.Units, time.Now()); err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err := stdout.Write(b.Bytes())
+		return err
+	}
+	// Readers, such as a web server publishing the feed, never see a partial file
+	if err := atomicfile.WriteFile(*output, b.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %v", *output, err)
+	}
+	fmt.Fprintf(stdout, "Wrote the forecast for %s to %s\n", addressFull, *output)
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+)
+
+func TestMain_writeICS(t *testing.T) {
+	weeklyForecast := api.WeeklyForecast{
+		Time:                        []string{"2024-09-19", "2024-09-20"},
+		Temperature2MMax:            []float64{97.4, 96.1},
+		Temperature2MMin:            []float64{75.8, 74.2},
+		PrecipitationSum:            []float64{0, 0.12},
+		PrecipitationProbabilityMax: []float64{5, 40},
+		WeatherCode:                 []int{0, 61},
+	}
+	now := time.Date(2024, time.September, 19, 15, 4, 5, 0, time.UTC)
+
+	var b strings.Builder
+	if err := writeICS(&b, "3001 Esperanza Crossing, Austin, TX 78758, USA", "78758", weeklyForecast, api.Fahrenheit, now); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	feed := b.String()
+
+	tc := []struct {
+		name     string
+		expected string
+	}{
+		{name: "Calendar", expected: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"},
+		{name: "All-Day Start", expected: "DTSTART;VALUE=DATE:20240919\r\nDTEND;VALUE=DATE:20240920\r\n"},
+		{name: "Summary", expected: "SUMMARY:H 97 / L 76\\, Clear sky\r\n"},
+		{name: "Description", expected: "DESCRIPTION:High 96.1 F\\, low 74.2 F\\nPrecipitation 0.12 in\\, 40% chance\r\n"},
+		{name: "Stamp", expected: "DTSTAMP:20240919T150405Z\r\n"},
+		{name: "Location", expected: "LOCATION:3001 Esperanza Crossing\\, Austin\\, TX 78758\\, USA\r\n"},
+		{name: "End", expected: "END:VEVENT\r\nEND:VCALENDAR\r\n"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			if !strings.Contains(feed, tc.expected) {
+				t.Errorf("Expected the feed to contain %q, got:\n%s", tc.expected, feed)
+			}
+		})
+	}
+	if strings.Count(feed, "BEGIN:VEVENT") != 2 {
+		t.Errorf("Expected 2 events, got:\n%s", feed)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(feed, "\r\n"), "\r\n") {
+		if len(line) > icsLineLimit {
+			t.Errorf("Expected lines of at most %d bytes, got %q", icsLineLimit, line)
+		}
+	}
+
+	// A later export of the same days keeps their UIDs, so that calendar clients update the events
+	b.Reset()
+	weeklyForecast.Temperature2MMax[0] = 99
+	writeICS(&b, "3001 Esperanza Crossing, Austin, TX 78758, USA", "78758", weeklyForecast, api.Fahrenheit, now.Add(time.Hour))
+	uid := "UID:" + icsUID("78758", "2024-09-19") + "\r\n"
+	if !strings.Contains(feed, uid) || !strings.Contains(b.String(), uid) {
+		t.Errorf("Expected both feeds to contain %q", uid)
+	}
+	if icsUID("78758", "2024-09-19") == icsUID("78702", "2024-09-19") {
+		t.Errorf("Expected different locations to have different UIDs")
+	}
+}
+
+func TestMain_writeICSLine(t *testing.T) {
+	tc := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{name: "Short", line: "SUMMARY:H 97 / L 76", expected: "SUMMARY:H 97 / L 76\r\n"},
+		{name: "Folded", line: "DESCRIPTION:" + strings.Repeat("a", 70), expected: "DESCRIPTION:" + strings.Repeat("a", 63) + "\r\n " + strings.Repeat("a", 7) + "\r\n"},
+		{name: "Multi-Byte", line: "LOCATION:" + strings.Repeat("a", 65) + "é", expected: "LOCATION:" + strings.Repeat("a", 65) + "\r\n é\r\n"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			var b strings.Builder
+			writeICSLine(&b, tc.line)
+			if b.String() != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, b.String())
+			}
+		})
+	}
+}
+
+func TestMain_runICS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"current": {"temperature_2m": 78.6},
+			"daily": {"time": ["%s"], "temperature_2m_max": [97.6], "temperature_2m_min": [75.8]}}`, time.Now().UTC().Format("2006-01-02"))
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ForecastURL = server.URL
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	favs.Save(favorites.Place{Name: "dock", Address: "1 Dock St, Austin, TX 78741, USA", Latitude: 30.2311, Longitude: -97.7233})
+
+	var b strings.Builder
+	if err := runICS([]string{"dock"}, cfg, cache.GetCacheInstance(), favs, &b); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.Contains(b.String(), "SUMMARY:H 98 / L 76\r\n") {
+		t.Errorf("Expected the feed on stdout, got:\n%s", b.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "feeds", "weather.ics")
+	b.Reset()
+	if err := runICS([]string{"dock", "--output", path}, cfg, cache.GetCacheInstance(), favs, &b); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "BEGIN:VCALENDAR\r\n") {
+		t.Errorf("Expected the feed in %s, got %q (%v)", path, data, err)
+	}
+	if !strings.Contains(b.String(), "Wrote the forecast for 1 Dock St") {
+		t.Errorf("Expected a confirmation, got %q", b.String())
+	}
+
+	if err := runICS(nil, cfg, cache.GetCacheInstance(), favs, &b); err == nil || !strings.Contains(err.Error(), "usage: weather ics") {
+		t.Errorf("Expected a usage error, got %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxHistory is the number of lines kept in the prompt history.
+const maxHistory = 500
+
+// Keys the line editor handles, as read from a terminal in raw mode.
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyBackspace = 8
+	keyTab       = 9
+	keyCtrlK     = 11
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// lineEditor reads the lines typed at the prompt. On a terminal, it supports moving the cursor, browsing the history
+// with the up and down arrows and completing with tab. Elsewhere, such as when input is piped, it reads plain lines.
+type lineEditor struct {
+	// r is the input.
+	r *bufio.Reader
+	// w is the output the prompt and the line being edited are drawn on.
+	w io.Writer
+	// raw switches the terminal to raw mode and returns a function restoring it. It is nil if the input is not a
+	// terminal, in which case plain lines are read.
+	raw func() (func(), error)
+	// history holds the lines entered, oldest first.
+	history []string
+	// historyPath is the file the history is persisted to. Empty keeps it in memory only.
+	historyPath string
+	// complete returns the completions of the text before the cursor.
+	complete func(prefix string) []string
+}
+
+// newLineEditor returns a line editor reading from in and drawing on out. Line editing is enabled if in is a
+// terminal. The history is loaded from historyPath, if set.
+func newLineEditor(in *os.File, out io.Writer, historyPath string, complete func(prefix string) []string) *lineEditor {
+	e := &lineEditor{r: bufio.NewReader(in), w: out, historyPath: historyPath, complete: complete}
+	if isTerminal(in) {
+		// Ctrl+C is read as a key rather than raising SIGINT, so that the terminal is always restored
+		e.raw = func() (func(), error) { return makeRaw(in, false) }
+	}
+	if err := e.loadHistory(); err != nil {
+		fmt.Fprintf(out, "Could not load the prompt history: %v\n", err)
+	}
+	return e
+}
+
+// loadHistory reads the history file, keeping its last maxHistory lines. A missing file is not an error.
+func (e *lineEditor) loadHistory() error {
+	if e.historyPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(e.historyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading history: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			e.history = append(e.history, line)
+		}
+	}
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+	return nil
+}
+
+// addHistory appends a line to the history and to the history file. Blank lines and repeats of the previous line
+// are skipped.
+func (e *lineEditor) addHistory(line string) error {
+	if line == "" || len(e.history) > 0 && e.history[len(e.history)-1] == line {
+		return nil
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+
+	if e.historyPath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(e.historyPath), 0o700); err != nil {
+		return fmt.Errorf("error creating history directory: %v", err)
+	}
+	f, err := os.OpenFile(e.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening history: %v", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, line); err != nil {
+		return fmt.Errorf("error writing history: %v", err)
+	}
+	return nil
+}
+
+// ReadLine displays the prompt and returns the line entered, without its line ending and surrounding spaces. It
+// returns io.EOF once the input ends, on Ctrl+D on an empty line, or on Ctrl+C. On a terminal, the line is added to the history.
+func (e *lineEditor) ReadLine(prompt string) (string, error) {
+	if e.raw == nil {
+		fmt.Fprint(e.w, prompt)
+		line, err := e.r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	restore, err := e.raw()
+	if err != nil {
+		// Fall back to plain lines, for good
+		e.raw = nil
+		return e.ReadLine(prompt)
+	}
+	line, err := e.editLine(prompt)
+	restore()
+	if err != nil {
+		return "", err
+	}
+
+	line = strings.TrimSpace(line)
+	if err := e.addHistory(line); err != nil {
+		fmt.Fprintf(e.w, "Could not save the prompt history: %v\n", err)
+	}
+	return line, nil
+}
+
+// editLine reads keys until Enter and returns the line edited. It expects the input to be in raw mode.
+func (e *lineEditor) editLine(prompt string) (string, error) {
+	var buf []rune
+	pos := 0
+	// historyIndex is the history line displayed, or len(e.history) for the line being typed, which is saved in
+	// pending while browsing
+	historyIndex, pending := len(e.history), ""
+	lastTab := false
+
+	redraw := func() {
+		fmt.Fprintf(e.w, "\r%s%s\033[K", prompt, string(buf))
+		if back := len(buf) - pos; back > 0 {
+			fmt.Fprintf(e.w, "\033[%dD", back)
+		}
+	}
+	browse := func(index int) {
+		if index < 0 || index > len(e.history) {
+			fmt.Fprint(e.w, "\a")
+			return
+		}
+		if historyIndex == len(e.history) {
+			pending = string(buf)
+		}
+		historyIndex = index
+		if index == len(e.history) {
+			buf = []rune(pending)
+		} else {
+			buf = []rune(e.history[index])
+		}
+		pos = len(buf)
+		redraw()
+	}
+
+	fmt.Fprint(e.w, prompt)
+	for {
+		r, _, err := e.r.ReadRune()
+		if err != nil {
+			if err == io.EOF && len(buf) > 0 {
+				fmt.Fprintln(e.w)
+				return string(buf), nil
+			}
+			return "", err
+		}
+		tab := r == keyTab
+
+		switch r {
+		case '\r', '\n':
+			fmt.Fprintln(e.w)
+			return string(buf), nil
+
+		case keyCtrlC:
+			fmt.Fprintln(e.w, "^C")
+			return "", io.EOF
+
+		case keyCtrlD:
+			if len(buf) == 0 {
+				fmt.Fprintln(e.w)
+				return "", io.EOF
+			}
+			if pos < len(buf) {
+				buf = append(buf[:pos], buf[pos+1:]...)
+				redraw()
+			}
+
+		case keyBackspace, keyDelete:
+			if pos > 0 {
+				buf = append(buf[:pos-1], buf[pos:]...)
+				pos--
+				redraw()
+			}
+
+		case keyCtrlA:
+			pos = 0
+			redraw()
+
+		case keyCtrlE:
+			pos = len(buf)
+			redraw()
+
+		case keyCtrlK:
+			buf = buf[:pos]
+			redraw()
+
+		case keyCtrlU:
+			buf = append([]rune(nil), buf[pos:]...)
+			pos = 0
+			redraw()
+
+		case keyTab:
+			if e.complete == nil || pos != len(buf) {
+				fmt.Fprint(e.w, "\a")
+				break
+			}
+			matches := e.complete(string(buf))
+			completed := completion(string(buf), matches)
+			switch {
+			case len(matches) == 0:
+				fmt.Fprint(e.w, "\a")
+			case completed != string(buf):
+				buf = []rune(completed)
+				pos = len(buf)
+				redraw()
+			case lastTab:
+				// A second tab without progress lists the candidates
+				fmt.Fprintf(e.w, "\n%s\n", strings.Join(matches, "   "))
+				redraw()
+			default:
+				fmt.Fprint(e.w, "\a")
+			}
+
+		case keyEscape:
+			switch readEscape(e.r) {
+			case 'A':
+				browse(historyIndex - 1)
+			case 'B':
+				browse(historyIndex + 1)
+			case 'C':
+				if pos < len(buf) {
+					pos++
+					redraw()
+				}
+			case 'D':
+				if pos > 0 {
+					pos--
+					redraw()
+				}
+			case 'H':
+				pos = 0
+				redraw()
+			case 'F':
+				pos = len(buf)
+				redraw()
+			case '3':
+				if pos < len(buf) {
+					buf = append(buf[:pos], buf[pos+1:]...)
+					redraw()
+				}
+			}
+
+		default:
+			if r < ' ' {
+				// Other control keys are ignored
+				break
+			}
+			buf = append(buf[:pos], append([]rune{r}, buf[pos:]...)...)
+			pos++
+			redraw()
+		}
+		lastTab = tab
+	}
+}
+
+// readEscape reads the rest of an escape sequence from r, such as "[A" for the up arrow or "[3~" for delete, and
+// returns its final letter, or '3' for delete. It returns 0 for sequences that are not handled.
+func readEscape(r *bufio.Reader) rune {
+	introducer, _, err := r.ReadRune()
+	if err != nil || introducer != '[' && introducer != 'O' {
+		return 0
+	}
+	code, _, err := r.ReadRune()
+	if err != nil {
+		return 0
+	}
+	if code >= '0' && code <= '9' {
+		// Sequences with a parameter end with ~, such as delete, "[3~"
+		for {
+			next, _, err := r.ReadRune()
+			if err != nil || next == '~' {
+				break
+			}
+		}
+	}
+	return code
+}
+
+// completion returns the longest completion of prefix shared by all matches, which are compared case-insensitively so
+// that "esp" completes to "Esperanza". It returns prefix if the matches have no longer common prefix.
+func completion(prefix string, matches []string) string {
+	if len(matches) == 0 {
+		return prefix
+	}
+	common := []rune(matches[0])
+	for _, m := range matches[1:] {
+		mr := []rune(m)
+		n := 0
+		for n < len(common) && n < len(mr) && strings.EqualFold(string(common[n]), string(mr[n])) {
+			n++
+		}
+		common = common[:n]
+	}
+	if len(common) <= len([]rune(prefix)) {
+		return prefix
+	}
+	return string(common)
+}
+
+// completionCandidates returns the candidates of words that start with prefix, ignoring case, sorted and without
+// duplicates.
+func completionCandidates(prefix string, words []string) []string {
+	seen := map[string]bool{}
+	var matches []string
+	for _, w := range words {
+		if seen[w] || len(w) <= len(prefix) || !strings.HasPrefix(strings.ToLower(w), strings.ToLower(prefix)) {
+			continue
+		}
+		seen[w] = true
+		matches = append(matches, w)
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestEditor returns a line editor in raw mode that reads the given keys.
+func newTestEditor(keys string, history []string, w io.Writer) *lineEditor {
+	return &lineEditor{
+		r:       bufio.NewReader(strings.NewReader(keys)),
+		w:       w,
+		raw:     func() (func(), error) { return func() {}, nil },
+		history: history,
+		complete: func(prefix string) []string {
+			return completionCandidates(prefix, []string{"3001 Esperanza Crossing, Austin", "3001 Guadalupe St, Austin", "350 5th Ave, New York", "hq", ":help"})
+		},
+	}
+}
+
+func TestMain_lineEditor_ReadLine(t *testing.T) {
+	tc := []struct {
+		name     string
+		keys     string
+		history  []string
+		expected string
+	}{
+		{name: "Plain Address", keys: "78758\r", expected: "78758"},
+		{name: "Backspace", keys: "787599\x7f\x7f8\r", expected: "78758"},
+		{name: "Insert After Left Arrow", keys: "7858\x1b[D\x1b[D7\r", expected: "78758"},
+		{name: "Home And Delete", keys: "x78758\x01\x1b[3~\r", expected: "78758"},
+		{name: "Kill Line", keys: "New York\x01\x0b78758\r", expected: "78758"},
+		{name: "Up Arrow", keys: "\x1b[A\x1b[A\r", history: []string{"hq", "78758"}, expected: "hq"},
+		{name: "Down Arrow Restores Typed Line", keys: "784\x1b[A\x1b[B\r", history: []string{"hq"}, expected: "784"},
+		{name: "Tab Completes Unique Match", keys: "3001 E\t\r", expected: "3001 Esperanza Crossing, Austin"},
+		{name: "Tab Completes Common Prefix", keys: "30\t\r", expected: "3001"},
+		{name: "Tab Completes Ignoring Case", keys: ":HE\t\r", expected: ":help"},
+		{name: "Input Ends Mid Line", keys: "78758", expected: "78758"},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			var b strings.Builder
+			line, err := newTestEditor(tc.keys, tc.history, &b).ReadLine("-> ")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if line != tc.expected {
+				t.Errorf("Expected '%s', got '%s'", tc.expected, line)
+			}
+		})
+	}
+}
+
+func TestMain_lineEditor_Completion(t *testing.T) {
+	var b strings.Builder
+	editor := newTestEditor("3\t\t\r", nil, &b)
+	editor.complete = func(prefix string) []string {
+		return completionCandidates(prefix, []string{"3001 Esperanza Crossing, Austin", "350 5th Ave, New York"})
+	}
+	line, err := editor.ReadLine("-> ")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// The first tab has nothing in common to add past "3", so the second one lists the candidates
+	if line != "3" || !strings.Contains(b.String(), "\n3001 Esperanza Crossing, Austin   350 5th Ave, New York\n") {
+		t.Errorf("Expected the candidates to be listed, got %q", b.String())
+	}
+}
+
+func TestMain_lineEditor_EOF(t *testing.T) {
+	var b strings.Builder
+	if _, err := newTestEditor("\x04", nil, &b).ReadLine("-> "); err != io.EOF {
+		t.Errorf("Expected io.EOF on Ctrl+D, got %v", err)
+	}
+	if _, err := newTestEditor("78758\x03\r", nil, &b).ReadLine("-> "); err != io.EOF {
+		t.Errorf("Expected io.EOF on Ctrl+C, got %v", err)
+	}
+
+	// Without a terminal, plain lines are read
+	editor := &lineEditor{r: bufio.NewReader(strings.NewReader("78758\nq")), w: &b}
+	for _, expected := range []string{"78758", "q"} {
+		if line, err := editor.ReadLine("-> "); err != nil || line != expected {
+			t.Errorf("Expected '%s', got '%s', %v", expected, line, err)
+		}
+	}
+	if _, err := editor.ReadLine("-> "); err != io.EOF {
+		t.Errorf("Expected io.EOF at the end of the input, got %v", err)
+	}
+	if len(editor.history) != 0 {
+		t.Errorf("Expected plain lines not to be added to the history, got %v", editor.history)
+	}
+}
+
+func TestMain_lineEditor_History(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weather", "history")
+	var b strings.Builder
+	editor := newTestEditor("78758\r78758\r\rhq\r", nil, &b)
+	editor.historyPath = path
+	for i := 0; i < 4; i++ {
+		if _, err := editor.ReadLine("-> "); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the history file to be written, got %v", err)
+	}
+	if string(data) != "78758\nhq\n" {
+		t.Errorf("Expected blank lines and repeats to be skipped, got %q", data)
+	}
+
+	// A new session starts with the saved history
+	reloaded := &lineEditor{historyPath: path}
+	if err := reloaded.loadHistory(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(reloaded.history, "|") != "78758|hq" {
+		t.Errorf("Expected the history to be reloaded, got %v", reloaded.history)
+	}
+}
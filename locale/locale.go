@@ -0,0 +1,159 @@
+// Package locale formats forecast dates, times and numbers for display in the conventions of a language and region, such
+// as "Thu, Sep 19" and 97.6 for en-US or "Do., 19. Sep." and 97,6 for de-DE.
+package locale
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default is the tag of the locale used when none is configured.
+const Default = "en-US"
+
+// Locale holds the date and number conventions of a language and region.
+type Locale struct {
+	// Tag is the BCP 47 language tag of the locale, such as en-US.
+	Tag string
+	// DecimalSeparator separates the integer and fractional parts of numbers.
+	DecimalSeparator string
+	// Today labels the current day.
+	Today string
+	// Tomorrow labels the day after the current day.
+	Tomorrow string
+	// timeLayout is the time.Format layout of a time of day.
+	timeLayout string
+	// datePattern lays out a date using the {weekday}, {day} and {month} placeholders.
+	datePattern string
+	// weekdays holds the abbreviated weekday names, starting on Sunday.
+	weekdays [7]string
+	// months holds the abbreviated month names, starting in January.
+	months [12]string
+}
+
+// locales holds the supported locales by tag.
+var locales = map[string]Locale{
+	"en-US": {
+		Tag:              "en-US",
+		DecimalSeparator: ".",
+		Today:            "Today",
+		Tomorrow:         "Tomorrow",
+		timeLayout:       "3:04 PM",
+		datePattern:      "{weekday}, {month} {day}",
+		weekdays:         [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		months:           [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	},
+	"en-GB": {
+		Tag:              "en-GB",
+		DecimalSeparator: ".",
+		Today:            "Today",
+		Tomorrow:         "Tomorrow",
+		timeLayout:       "15:04",
+		datePattern:      "{weekday} {day} {month}",
+		weekdays:         [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+		months:           [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	},
+	"de-DE": {
+		Tag:              "de-DE",
+		DecimalSeparator: ",",
+		Today:            "Heute",
+		Tomorrow:         "Morgen",
+		timeLayout:       "15:04",
+		datePattern:      "{weekday}., {day}. {month}.",
+		weekdays:         [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+		months:           [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+	},
+	"fr-FR": {
+		Tag:              "fr-FR",
+		DecimalSeparator: ",",
+		Today:            "Aujourd'hui",
+		Tomorrow:         "Demain",
+		timeLayout:       "15:04",
+		datePattern:      "{weekday} {day} {month}",
+		weekdays:         [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		months:           [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+	},
+	"es-ES": {
+		Tag:              "es-ES",
+		DecimalSeparator: ",",
+		Today:            "Hoy",
+		Tomorrow:         "Mañana",
+		timeLayout:       "15:04",
+		datePattern:      "{weekday}, {day} {month}",
+		weekdays:         [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		months:           [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sep", "oct", "nov", "dic"},
+	},
+}
+
+// Lookup returns the locale with the given tag. Tags are matched case-insensitively and may use an underscore, as in
+// de_DE, or name only the language, as in de, which selects the first supported region of that language.
+func Lookup(tag string) (Locale, error) {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	for _, t := range Tags() {
+		if strings.ToLower(t) == normalized {
+			return locales[t], nil
+		}
+	}
+	for _, t := range Tags() {
+		if language, _, _ := strings.Cut(strings.ToLower(t), "-"); language == normalized {
+			return locales[t], nil
+		}
+	}
+	return Locale{}, fmt.Errorf("unsupported locale %q: must be one of %s", tag, strings.Join(Tags(), ", "))
+}
+
+// Tags returns the tags of the supported locales in sorted order.
+func Tags() []string {
+	tags := make([]string, 0, len(locales))
+	for tag := range locales {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Weekday returns the abbreviated name of the weekday of t.
+func (l Locale) Weekday(t time.Time) string {
+	return l.weekdays[t.Weekday()]
+}
+
+// Date formats the weekday, day and month of t, such as "Thu, Sep 19".
+func (l Locale) Date(t time.Time) string {
+	return strings.NewReplacer(
+		"{weekday}", l.Weekday(t),
+		"{day}", strconv.Itoa(t.Day()),
+		"{month}", l.months[t.Month()-1],
+	).Replace(l.datePattern)
+}
+
+// ShortDate formats the weekday and day of the month of t, such as "Thu 19", for narrow spaces.
+func (l Locale) ShortDate(t time.Time) string {
+	return fmt.Sprintf("%s %d", strings.TrimSuffix(l.Weekday(t), "."), t.Day())
+}
+
+// Time formats the time of day of t, such as "7:15 AM" or "07:15".
+func (l Locale) Time(t time.Time) string {
+	return t.Format(l.timeLayout)
+}
+
+// Day formats t like Date, but labels the day of now and the day after it as Today and Tomorrow. Both times should be
+// in the location the forecast applies to, since that is where the day is counted.
+func (l Locale) Day(t time.Time, now time.Time) string {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch day.Sub(today) {
+	case 0:
+		return l.Today
+	case 24 * time.Hour:
+		return l.Tomorrow
+	default:
+		return l.Date(t)
+	}
+}
+
+// Number formats v with prec decimals using the decimal separator of the locale.
+func (l Locale) Number(v float64, prec int) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', prec, 64), ".", l.DecimalSeparator, 1)
+}
@@ -0,0 +1,109 @@
+package locale
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocale_Lookup(t *testing.T) {
+	tc := []struct {
+		name     string
+		tag      string
+		expected string
+		err      string
+	}{
+		{name: "Exact", tag: "en-GB", expected: "en-GB"},
+		{name: "Case And Underscore", tag: "de_de", expected: "de-DE"},
+		{name: "Language Only", tag: "fr", expected: "fr-FR"},
+		{name: "Unsupported", tag: "xx-YY", err: `unsupported locale "xx-YY"`},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := Lookup(tc.tag)
+			// Check for error cases
+			if tc.err != "" {
+				if err == nil {
+					t.Fatalf("Expected an error containing '%s', got nil", tc.err)
+				}
+				if !strings.Contains(err.Error(), tc.err) {
+					t.Errorf("Expected an error containing '%s', got %s", tc.err, err.Error())
+				}
+				return
+			}
+
+			// Check for success cases
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if l.Tag != tc.expected {
+				t.Errorf("Expected locale %s, got %s", tc.expected, l.Tag)
+			}
+		})
+	}
+}
+
+func TestLocale_Formatting(t *testing.T) {
+	day := time.Date(2024, time.September, 19, 19, 32, 0, 0, time.UTC)
+
+	tc := []struct {
+		tag       string
+		date      string
+		shortDate string
+		time      string
+		number    string
+	}{
+		{tag: "en-US", date: "Thu, Sep 19", shortDate: "Thu 19", time: "7:32 PM", number: "97.6"},
+		{tag: "en-GB", date: "Thu 19 Sep", shortDate: "Thu 19", time: "19:32", number: "97.6"},
+		{tag: "de-DE", date: "Do., 19. Sep.", shortDate: "Do 19", time: "19:32", number: "97,6"},
+		{tag: "fr-FR", date: "jeu. 19 sept.", shortDate: "jeu 19", time: "19:32", number: "97,6"},
+		{tag: "es-ES", date: "jue, 19 sep", shortDate: "jue 19", time: "19:32", number: "97,6"},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.tag, func(t *testing.T) {
+			l, err := Lookup(tc.tag)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := l.Date(day); got != tc.date {
+				t.Errorf("Expected date %q, got %q", tc.date, got)
+			}
+			if got := l.ShortDate(day); got != tc.shortDate {
+				t.Errorf("Expected short date %q, got %q", tc.shortDate, got)
+			}
+			if got := l.Time(day); got != tc.time {
+				t.Errorf("Expected time %q, got %q", tc.time, got)
+			}
+			if got := l.Number(97.6, 1); got != tc.number {
+				t.Errorf("Expected number %q, got %q", tc.number, got)
+			}
+		})
+	}
+}
+
+func TestLocale_Day(t *testing.T) {
+	l, _ := Lookup(Default)
+	now := time.Date(2024, time.September, 19, 23, 30, 0, 0, time.UTC)
+
+	tc := []struct {
+		name     string
+		day      time.Time
+		expected string
+	}{
+		{name: "Today", day: time.Date(2024, time.September, 19, 0, 0, 0, 0, time.UTC), expected: "Today"},
+		{name: "Tomorrow", day: time.Date(2024, time.September, 20, 0, 0, 0, 0, time.UTC), expected: "Tomorrow"},
+		{name: "Later", day: time.Date(2024, time.September, 21, 0, 0, 0, 0, time.UTC), expected: "Sat, Sep 21"},
+		{name: "Yesterday", day: time.Date(2024, time.September, 18, 0, 0, 0, 0, time.UTC), expected: "Wed, Sep 18"},
+		{name: "Across Months", day: time.Date(2024, time.October, 1, 0, 0, 0, 0, time.UTC), expected: "Tue, Oct 1"},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := l.Day(tc.day, now); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Package logging configures structured logging with log/slog. It attaches a per-request ID to every record and
+// redacts secrets, such as the Google API key embedded in geocode URLs, before anything is written.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const (
+	// redacted replaces any masked value.
+	redacted = "REDACTED"
+	// requestIDKey is the attribute key of the request ID.
+	requestIDKey = "request_id"
+)
+
+var (
+	// secretParamPattern matches query parameters that carry credentials, such as key=... in Google API URLs.
+	secretParamPattern = regexp.MustCompile(`(?i)([?&](?:key|api_key|apikey|token|access_token)=)[^&\s"']+`)
+	// addressParamPattern matches the address query parameter sent to the geocode API.
+	addressParamPattern = regexp.MustCompile(`(?i)([?&]address=)[^&\s"']+`)
+	// addressKeys are the attribute keys that hold user addresses.
+	addressKeys = map[string]bool{"address": true, "full_address": true}
+)
+
+// requestIDContextKey is the context key under which the request ID is stored.
+type requestIDContextKey struct{}
+
+// Options configures the handler returned by NewHandler.
+type Options struct {
+	// Level is the minimum level that is logged.
+	Level slog.Level
+	// JSON selects the JSON output format instead of the default key=value text format.
+	JSON bool
+	// RedactAddresses masks user addresses in addition to API keys.
+	RedactAddresses bool
+}
+
+// NewHandler returns a slog.Handler that writes to w, adds the request ID from the context to every record and
+// redacts secrets from all string attributes.
+func NewHandler(w io.Writer, opts Options) slog.Handler {
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+	var next slog.Handler
+	if opts.JSON {
+		next = slog.NewJSONHandler(w, handlerOpts)
+	} else {
+		next = slog.NewTextHandler(w, handlerOpts)
+	}
+
+	return &redactHandler{next: next, redactAddresses: opts.RedactAddresses}
+}
+
+// ParseLevel converts a level name such as "debug", "info", "warn" or "error" into a slog.Level.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", name)
+	}
+	return level, nil
+}
+
+// NewRequestID returns a random identifier used to correlate the log records of a single lookup.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a copy of ctx that carries the given request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// RedactSecrets masks the values of credential query parameters, such as key=..., found anywhere in s.
+func RedactSecrets(s string) string {
+	return secretParamPattern.ReplaceAllString(s, "${1}"+redacted)
+}
+
+// redactHandler wraps another slog.Handler, redacting attributes and adding the request ID.
+type redactHandler struct {
+	// next is the handler records are passed to once redacted.
+	next slog.Handler
+	// redactAddresses masks user addresses in addition to API keys.
+	redactAddresses bool
+}
+
+// Enabled implements slog.Handler.
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	redactedRecord := slog.NewRecord(r.Time, r.Level, h.redactString(r.Message), r.PC)
+	if id := RequestID(ctx); id != "" {
+		redactedRecord.AddAttrs(slog.String(requestIDKey, id))
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		redactedRecord.AddAttrs(h.redactAttr(a))
+		return true
+	})
+
+	return h.next.Handle(ctx, redactedRecord)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		redactedAttrs = append(redactedAttrs, h.redactAttr(a))
+	}
+	return &redactHandler{next: h.next.WithAttrs(redactedAttrs), redactAddresses: h.redactAddresses}
+}
+
+// WithGroup implements slog.Handler.
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), redactAddresses: h.redactAddresses}
+}
+
+// redactAttr returns a copy of a with secrets, and optionally addresses, masked.
+func (h *redactHandler) redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		redactedGroup := make([]slog.Attr, 0, len(group))
+		for _, ga := range group {
+			redactedGroup = append(redactedGroup, h.redactAttr(ga))
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(redactedGroup...)}
+	case slog.KindString:
+		if h.redactAddresses && addressKeys[strings.ToLower(a.Key)] {
+			return slog.String(a.Key, redacted)
+		}
+		return slog.String(a.Key, h.redactString(a.Value.String()))
+	case slog.KindAny:
+		// Errors and other values are formatted by the next handler, so redact their text up front
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, h.redactString(err.Error()))
+		}
+		if s, ok := a.Value.Any().(fmt.Stringer); ok {
+			return slog.String(a.Key, h.redactString(s.String()))
+		}
+	}
+
+	return a
+}
+
+// redactString masks secrets, and optionally the geocode address parameter, in s.
+func (h *redactHandler) redactString(s string) string {
+	s = RedactSecrets(s)
+	if h.redactAddresses {
+		s = addressParamPattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+	return s
+}
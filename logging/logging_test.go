@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogging_RedactSecrets(t *testing.T) {
+	tc := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Geocode URL",
+			input:    "https://maps.googleapis.com/maps/api/geocode/json?address=Austin&key=AIzaSecret",
+			expected: "https://maps.googleapis.com/maps/api/geocode/json?address=Austin&key=REDACTED",
+		},
+		{
+			name:     "Key First",
+			input:    `Get "https://example.com/path?key=AIzaSecret&address=Austin": dial tcp`,
+			expected: `Get "https://example.com/path?key=REDACTED&address=Austin": dial tcp`,
+		},
+		{
+			name:     "No Secret",
+			input:    "https://api.open-meteo.com/v1/forecast?latitude=30.4&longitude=-97.7",
+			expected: "https://api.open-meteo.com/v1/forecast?latitude=30.4&longitude=-97.7",
+		},
+	}
+
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := RedactSecrets(tc.input); got != tc.expected {
+				t.Errorf("Expected '%s', got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLogging_Handler(t *testing.T) {
+	var b strings.Builder
+	logger := slog.New(NewHandler(&b, Options{Level: slog.LevelInfo, RedactAddresses: true}))
+	ctx := WithRequestID(context.Background(), "abc123")
+
+	logger.DebugContext(ctx, "hidden")
+	logger.InfoContext(ctx, "upstream request",
+		"url", "https://maps.googleapis.com/maps/api/geocode/json?address=3001+Esperanza&key=AIzaSecret",
+		"address", "3001 Esperanza Crossing, Austin, TX 78758, USA",
+		"error", errors.New(`Get "https://example.com/?key=AIzaSecret": timeout`))
+
+	out := b.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("Expected debug records to be filtered, got %s", out)
+	}
+	if strings.Contains(out, "AIzaSecret") {
+		t.Errorf("Expected API key to be redacted, got %s", out)
+	}
+	if strings.Contains(out, "Esperanza") {
+		t.Errorf("Expected address to be redacted, got %s", out)
+	}
+	if !strings.Contains(out, "request_id=abc123") {
+		t.Errorf("Expected request_id=abc123, got %s", out)
+	}
+}
+
+func TestLogging_HandlerKeepsAddresses(t *testing.T) {
+	var b strings.Builder
+	logger := slog.New(NewHandler(&b, Options{Level: slog.LevelInfo, JSON: true})).With("address", "Austin, TX")
+
+	logger.Info("cache lookup", "key", "78758")
+
+	out := b.String()
+	if !strings.Contains(out, `"address":"Austin, TX"`) {
+		t.Errorf("Expected address to be kept, got %s", out)
+	}
+	if strings.Contains(out, requestIDKey) {
+		t.Errorf("Expected no request_id without one in the context, got %s", out)
+	}
+}
+
+func TestLogging_ParseLevel(t *testing.T) {
+	if level, err := ParseLevel("debug"); err != nil || level != slog.LevelDebug {
+		t.Errorf("Expected debug level, got %v, %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("Expected an error for an invalid level, got nil")
+	}
+}
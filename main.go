@@ -3,52 +3,179 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/mfryhover/weather/api"
 	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+	"github.com/mfryhover/weather/logging"
+	"github.com/mfryhover/weather/metrics"
 )
 
 // displayPrompt displays the user prompt instructions.
 func displayPrompt() {
 	fmt.Println("To exit please enter q")
-	fmt.Println("Otherwise, please enter your address")
-	fmt.Print("-> ")
+	fmt.Println("To see cache statistics please enter cache")
+	fmt.Println("To save a favorite please enter save <name> <address>, or unsave <name> to remove it")
+	fmt.Println("To see all favorites please enter favs")
+	fmt.Println("For more commands, such as :units c or :again, please enter :help")
+	fmt.Println("Otherwise, please enter your address or the name of a favorite")
 }
 
 // displayCurrentForecast displays the current weather forecast for the given address.
 // It shows the current temperature, today's high and low, and indicates if the data was retrieved from the cache.
-func displayCurrentForecast(address string, currentTemp, maxTemp, minTemp float64, isFromCache bool) {
+func displayCurrentForecast(address string, currentTemp, maxTemp, minTemp float64, isFromCache bool, units api.Units, lc locale.Locale) {
 	fmt.Println()
 	if isFromCache {
 		fmt.Println("***Retrieved forecast from cache***")
 	}
 	fmt.Printf("Here is the weather for address: %s\n", address)
 	fmt.Println("---------------------------")
-	fmt.Printf("The current temperature is %.1f F\n", currentTemp)
-	fmt.Printf("The high for today is %.1f F\n", maxTemp)
-	fmt.Printf("The low for today is %.1f F\n", minTemp)
+	fmt.Printf("The current temperature is %s %s\n", lc.Number(currentTemp, 1), units.Symbol())
+	fmt.Printf("The high for today is %s %s\n", lc.Number(maxTemp, 1), units.Symbol())
+	fmt.Printf("The low for today is %s %s\n", lc.Number(minTemp, 1), units.Symbol())
 	fmt.Println()
 }
 
+// dayLabels labels each day of the forecast with format, such as lc.Day for "Today" or "Thu, Sep 19", relative to
+// now in the forecast's time zone. Days that cannot be parsed keep their raw date.
+func dayLabels(weeklyForecast api.WeeklyForecast, now time.Time, format func(day time.Time, now time.Time) string) []string {
+	labels := append([]string(nil), weeklyForecast.Time...)
+	dates, err := weeklyForecast.Dates()
+	if err != nil {
+		slog.Warn("displaying raw forecast dates", "error", err)
+		return labels
+	}
+	now = now.In(weeklyForecast.Location())
+	for i, date := range dates {
+		labels[i] = format(date, now)
+	}
+	return labels
+}
+
 // displayExtendedForecast displays the extended weather forecast for the week.
-func displayExtendedForecast(weeklyForecast api.WeeklyForecast) {
+func displayExtendedForecast(weeklyForecast api.WeeklyForecast, units api.Units, lc locale.Locale) {
 	fmt.Println("Extended Forecast: ")
 	fmt.Println("---------------------------")
+	labels := dayLabels(weeklyForecast, time.Now(), lc.Day)
+	// Days before today, requested with past days, hold observations and analysis rather than forecasts
+	for dayIndex := 0; dayIndex < weeklyForecast.DayIndex(time.Now()); dayIndex++ {
+		labels[dayIndex] += " (observed)"
+	}
 	for dayIndex := range weeklyForecast.Time {
-		fmt.Println(weeklyForecast.Time[dayIndex])
-		fmt.Printf("Max Temp: %.1f F\n", weeklyForecast.Temperature2MMax[dayIndex])
-		fmt.Printf("Min Temp: %.1f F\n", weeklyForecast.Temperature2MMin[dayIndex])
+		if dayIndex < len(weeklyForecast.WeatherCode) {
+			fmt.Printf("%s: %s\n", labels[dayIndex], api.WeatherDescription(weeklyForecast.WeatherCode[dayIndex]))
+		} else {
+			fmt.Println(labels[dayIndex])
+		}
+		fmt.Printf("Max Temp: %s %s\n", lc.Number(weeklyForecast.Temperature2MMax[dayIndex], 1), units.Symbol())
+		fmt.Printf("Min Temp: %s %s\n", lc.Number(weeklyForecast.Temperature2MMin[dayIndex], 1), units.Symbol())
+		displayPrecipitation(weeklyForecast, dayIndex, units, lc)
+		displayDaylight(weeklyForecast, dayIndex, lc)
 		fmt.Println("--------------------")
 	}
 	fmt.Println()
 }
 
+// precipitationDecimals returns the number of decimals precipitation is shown with: inches to the hundredth and
+// millimeters to the tenth.
+func precipitationDecimals(units api.Units) int {
+	if units == api.Celsius {
+		return 1
+	}
+	return 2
+}
+
+// displayPrecipitation displays the total precipitation of the given day and its chance, broken down into rain and
+// snowfall when it snows. Nothing is displayed if the forecast has no precipitation data for that day.
+func displayPrecipitation(weeklyForecast api.WeeklyForecast, dayIndex int, units api.Units, lc locale.Locale) {
+	if dayIndex >= len(weeklyForecast.PrecipitationSum) {
+		return
+	}
+	decimals := precipitationDecimals(units)
+
+	line := fmt.Sprintf("Precipitation: %s %s", lc.Number(weeklyForecast.PrecipitationSum[dayIndex], decimals), units.PrecipitationSymbol())
+	if dayIndex < len(weeklyForecast.SnowfallSum) && weeklyForecast.SnowfallSum[dayIndex] > 0 {
+		rain := 0.0
+		if dayIndex < len(weeklyForecast.RainSum) {
+			rain = weeklyForecast.RainSum[dayIndex]
+		}
+		line += fmt.Sprintf(" (rain %s %s, snow %s %s)", lc.Number(rain, decimals), units.PrecipitationSymbol(),
+			lc.Number(weeklyForecast.SnowfallSum[dayIndex], decimals), units.SnowfallSymbol())
+	}
+	if dayIndex < len(weeklyForecast.PrecipitationProbabilityMax) {
+		line += fmt.Sprintf(", %.0f%% chance", weeklyForecast.PrecipitationProbabilityMax[dayIndex])
+	}
+	fmt.Println(line)
+}
+
+// displayDaylight displays the sunrise, sunset, daylight duration and max UV index of the given day, skipping the
+// values the forecast does not have, such as sunrise during the polar night.
+func displayDaylight(weeklyForecast api.WeeklyForecast, dayIndex int, lc locale.Locale) {
+	var sun []string
+	if sunrise, ok := weeklyForecast.SunriseAt(dayIndex); ok {
+		sun = append(sun, "Sunrise: "+lc.Time(sunrise))
+	}
+	if sunset, ok := weeklyForecast.SunsetAt(dayIndex); ok {
+		sun = append(sun, "Sunset: "+lc.Time(sunset))
+	}
+	if daylight, ok := weeklyForecast.Daylight(dayIndex); ok {
+		sun = append(sun, fmt.Sprintf("Daylight: %dh %02dm", int(daylight.Hours()), int(daylight.Minutes())%60))
+	}
+	if len(sun) > 0 {
+		fmt.Println(strings.Join(sun, "  "))
+	}
+	if dayIndex < len(weeklyForecast.UVIndexMax) {
+		fmt.Printf("UV Index: %s\n", lc.Number(weeklyForecast.UVIndexMax[dayIndex], 1))
+	}
+}
+
+// displayCacheStats displays the cache counters followed by every live entry and its remaining time-to-live.
+func displayCacheStats(c *cache.Cache) {
+	stats := c.Stats()
+	total := stats.Hits + stats.Misses
+	hitRatio := 0.0
+	if total > 0 {
+		hitRatio = float64(stats.Hits) / float64(total) * 100
+	}
+
+	fmt.Println()
+	fmt.Println("Cache Statistics: ")
+	fmt.Println("---------------------------")
+	fmt.Printf("Hits: %d\n", stats.Hits)
+	fmt.Printf("Misses: %d\n", stats.Misses)
+	fmt.Printf("Hit Ratio: %.1f%%\n", hitRatio)
+	fmt.Printf("Expirations: %d\n", stats.Expirations)
+	fmt.Printf("Purges: %d\n", stats.Purges)
+	fmt.Printf("Evictions: %d\n", stats.Evictions)
+	fmt.Printf("Store Errors: %d\n", stats.StoreErrors)
+	fmt.Printf("Size: %d\n", stats.Size)
+	fmt.Printf("Oldest Entry Age: %s\n", stats.OldestEntryAge.Round(time.Second))
+	fmt.Println("---------------------------")
+	for _, e := range c.Entries() {
+		if e.Permanent {
+			fmt.Printf("%s: (never expires)\n", e.Key)
+			continue
+		}
+		fmt.Printf("%s: %.1f (expires in %s)\n", e.Key, e.CurrentTemp, e.TTL.Round(time.Second))
+	}
+	fmt.Println()
+}
+
 // getPostalCode extracts and returns the postal code from a full address string.
 // For example, given an address like "3001 Esperanza Crossing, Austin, TX 78758, USA",
 // it returns "78758".
@@ -63,77 +190,307 @@ func getPostalCode(address string) string {
 	return ""
 }
 
+// locationKey returns the postal code of the address, which identifies the location in the cache. Addresses without a
+// US postal code, such as most international addresses, fall back to their coordinates so that they do not share
+// one cache entry.
+func locationKey(addressFull string, lat, lng float64) string {
+	if pc := getPostalCode(addressFull); pc != "" {
+		return pc
+	}
+	return fmt.Sprintf("%.4f,%.4f", lat, lng)
+}
+
+// forecastCacheKey returns the cache key of a forecast for the given location key. Options that change the forecast
+// data, such as the unit system and the forecast and past days, are part of the key so that differently requested
+// forecasts do not overwrite each other.
+func forecastCacheKey(location string, opts api.ForecastOptions) string {
+	units := opts.Units
+	if units == "" {
+		units = api.Fahrenheit
+	}
+	forecastDays, pastDays := opts.Days()
+	return fmt.Sprintf("%s:%s:%dd:%dp", location, units, forecastDays, pastDays)
+}
+
 // getForecast retrieves the current temperature and weekly forecast for the given address.
 // It returns the full formatted address, current temperature, weekly forecast, and a boolean indicating if the data was retrieved from the cache.
-func getForecast(address string, c *cache.Cache, geocodeURL string, forecastURL string, apiKey string) (string, float64, api.WeeklyForecast, bool, error) {
+// The request ID carried by ctx is logged with every upstream request and cache lookup.
+func getForecast(ctx context.Context, address string, c *cache.Cache, geocodeURL string, forecastURL string, apiKey string, opts api.ForecastOptions) (string, float64, api.WeeklyForecast, bool, error) {
+	slog.DebugContext(ctx, "forecast requested", "address", address)
+
 	// Get the latitude and longitude of the address
-	addressFull, lat, lng, err := api.AddressToCoordinates(address, geocodeURL, apiKey)
+	addressFull, lat, lng, err := api.AddressToCoordinatesContext(ctx, address, geocodeURL, apiKey)
 	if err != nil || addressFull == "" || lat == 0 || lng == 0 {
 		return "", 0, api.WeeklyForecast{}, false, fmt.Errorf("error retrieving coordinates: %v", err)
 	}
 
-	// Get the postal code from the address
-	pc := getPostalCode(addressFull)
+	currentTemp, weeklyForecast, isFromCache, err := getForecastForCoordinates(ctx, addressFull, lat, lng, c, forecastURL, opts)
+	if err != nil {
+		return "", 0, api.WeeklyForecast{}, false, err
+	}
+	return addressFull, currentTemp, weeklyForecast, isFromCache, nil
+}
+
+// getForecastForCoordinates retrieves the current temperature and weekly forecast for an address that has already
+// been geocoded, such as a saved favorite. The full address is only used to derive the cache key.
+// It returns the current temperature, weekly forecast, and a boolean indicating if the data was retrieved from the cache.
+func getForecastForCoordinates(ctx context.Context, addressFull string, lat, lng float64, c *cache.Cache, forecastURL string, opts api.ForecastOptions) (float64, api.WeeklyForecast, bool, error) {
+	entry, isFromCache, err := getForecastEntry(ctx, addressFull, lat, lng, c, forecastURL, opts)
+	if err != nil {
+		return 0, api.WeeklyForecast{}, false, err
+	}
+	return entry.CurrentTemp, entry.WeeklyForecast, isFromCache, nil
+}
+
+// getForecastEntry is like getForecastForCoordinates, but returns the cache entry holding the forecast, with when it
+// was cached and the time remaining before it expires.
+func getForecastEntry(ctx context.Context, addressFull string, lat, lng float64, c *cache.Cache, forecastURL string, opts api.ForecastOptions) (cache.Entry, bool, error) {
+	// Get the postal code, or the coordinates, that identify the location
+	key := forecastCacheKey(locationKey(addressFull, lat, lng), opts)
 
 	// Get the current temperature and weekly forecast
-	isFromCache := true
-	currentTemp, weeklyForecast, ok := c.Get(pc)
-	if !ok {
-		currentTemp, weeklyForecast, err = api.GetForecast(lat, lng, forecastURL)
-		if err != nil {
-			return "", 0, api.WeeklyForecast{}, false, fmt.Errorf("error retrieving forecast: %v", err)
+	entry, ok := c.GetEntry(key)
+	slog.InfoContext(ctx, "cache lookup", "key", key, "hit", ok)
+	if ok {
+		// A forecast cached before midnight at the location no longer covers the local day and is fetched again
+		if entry.WeeklyForecast.DayIndex(time.Now()) >= 0 {
+			return entry, true, nil
 		}
-		c.Add(pc, currentTemp, weeklyForecast)
-		isFromCache = false
-		return addressFull, currentTemp, weeklyForecast, isFromCache, nil
+		slog.InfoContext(ctx, "cached forecast is from a previous local day", "key", key)
 	}
 
-	return addressFull, currentTemp, weeklyForecast, isFromCache, nil
+	currentTemp, weeklyForecast, err := api.GetForecastContext(ctx, lat, lng, forecastURL, opts)
+	if err != nil {
+		return cache.Entry{}, false, fmt.Errorf("error retrieving forecast: %v", err)
+	}
+	return c.Add(key, currentTemp, weeklyForecast), false, nil
 }
 
-func main() {
+// serveMetrics serves the default metrics registry at /metrics on the given address.
+// It only returns if the listener fails, in which case the error is reported and the app keeps running.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("metrics listener stopped", "addr", addr, "error", err)
+	}
+}
+
+// setupLogging installs the default slog logger, writing to stderr so that logs do not interleave with the prompt.
+func setupLogging(cfg config.Log) error {
+	level, err := logging.ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	opts := logging.Options{
+		Level:           level,
+		JSON:            cfg.Format == "json",
+		RedactAddresses: cfg.RedactAddresses,
+	}
+	slog.SetDefault(slog.New(logging.NewHandler(os.Stderr, opts)))
+	return nil
+}
+
+// setupCache configures the cache singleton from the cache and metrics settings and starts purging it.
+func setupCache(cfg config.Config) *cache.Cache {
 	c := cache.GetCacheInstance()
-	c.StartAutoPurge(1 * time.Hour)
+	c.SetEntryTTL(time.Duration(cfg.Cache.TTL))
+	if cfg.Cache.RedisAddr != "" {
+		c.SetStore(cache.NewRESPStore(cfg.Cache.RedisAddr, "weather:"))
+	}
+	c.StartAutoPurge(time.Duration(cfg.Cache.PurgeInterval))
+	c.RegisterMetrics(metrics.Default)
 
-	// Retrieve the API key once
-	apiKey := os.Getenv("GEOCODE_API_KEY")
-	if apiKey == "" {
-		fmt.Println("GEOCODE_API_KEY environment variable is not set.")
-		os.Exit(1)
+	// Serve metrics in the background if a listen address was given
+	if cfg.Metrics.Addr != "" {
+		go serveMetrics(cfg.Metrics.Addr)
+	}
+
+	return c
+}
+
+// forecastOptions returns the forecast request options selected by the configuration.
+func forecastOptions(cfg config.Config) api.ForecastOptions {
+	return api.ForecastOptions{Units: api.Units(cfg.Units), ForecastDays: cfg.ForecastDays, PastDays: cfg.PastDays}
+}
+
+// runConfig runs the config command. The only subcommand, show, prints the effective configuration as JSON with
+// secrets masked.
+func runConfig(args []string, cfg config.Config, w io.Writer) error {
+	if len(args) != 1 || args[0] != "show" {
+		return errors.New("usage: weather config show")
+	}
+
+	out, err := json.MarshalIndent(cfg.Masked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, string(out))
+	return nil
+}
+
+// todayIndex returns the index of the current day at the location in the forecast, or 0 if the forecast does not
+// cover it.
+func todayIndex(weeklyForecast api.WeeklyForecast) int {
+	return max(weeklyForecast.DayIndex(time.Now()), 0)
+}
+
+// displayForecast displays the current and extended forecast, or a notice if the forecast data is incomplete. The air
+// quality, if not nil, is displayed with the current conditions. If chart is set and stdout is a terminal, the
+// extended forecast is drawn as a chart sized to the terminal instead of a list.
+func displayForecast(addressFull string, currentTemp float64, weeklyForecast api.WeeklyForecast, isFromCache bool, airQuality *api.AirQuality, units api.Units, lc locale.Locale, chart bool) {
+	today := todayIndex(weeklyForecast)
+	if today < len(weeklyForecast.Temperature2MMax) && today < len(weeklyForecast.Temperature2MMin) && len(weeklyForecast.Time) > 0 {
+		displayCurrentForecast(addressFull, currentTemp, weeklyForecast.Temperature2MMax[today], weeklyForecast.Temperature2MMin[today], isFromCache, units, lc)
+		if airQuality != nil {
+			displayAirQuality(os.Stdout, *airQuality, lc, isTerminal(os.Stdout))
+		}
+		if chart && isTerminal(os.Stdout) {
+			displayForecastChart(weeklyForecast, units, lc, terminalWidth(os.Stdout))
+		} else {
+			displayExtendedForecast(weeklyForecast, units, lc)
+		}
+	} else {
+		fmt.Println("Forecast data is unavailable. Please try again!")
+	}
+}
+
+// runPrompt runs the interactive prompt until the user enters q or stdin is closed.
+func runPrompt(cfg config.Config, c *cache.Cache, favs *favorites.Store) error {
+	// Check the API key once
+	if cfg.Providers.GeocodeAPIKey == "" {
+		return errors.New("GEOCODE_API_KEY environment variable is not set")
+	}
+	lc, err := locale.Lookup(cfg.Locale)
+	if err != nil {
+		return err
 	}
+	session := &promptSession{cfg: cfg, c: c, favs: favs, opts: forecastOptions(cfg), lc: lc, out: os.Stdout}
 
 	fmt.Println("World's Best Weather App")
 	fmt.Println("---------------------------")
 	displayPrompt()
 
-	scanner := bufio.NewScanner(os.Stdin)
+	var editor *lineEditor
+	editor = newLineEditor(os.Stdin, os.Stdout, cfg.HistoryPath, func(prefix string) []string {
+		return completionCandidates(prefix, session.completionWords(editor.history))
+	})
 
-	for scanner.Scan() {
-		address := scanner.Text()
-
-		if strings.EqualFold(address, "q") {
-			fmt.Println("Thanks for using the World's Best Weather App!")
-			break
+	for {
+		address, err := editor.ReadLine("-> ")
+		if err == io.EOF {
+			return nil
 		}
-
-		addressFull, currentTemp, weeklyForecast, isFromCache, err := getForecast(address, c, "https://maps.googleapis.com", "https://api.open-meteo.com", apiKey)
 		if err != nil {
-			fmt.Printf("Oops! Looks like there was a mistake: %s. Please try again!\n", err)
-			displayPrompt()
-			continue
+			return fmt.Errorf("error reading input: %v", err)
 		}
+		command, rest, _ := strings.Cut(address, " ")
+		ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
 
-		if len(weeklyForecast.Temperature2MMax) > 0 && len(weeklyForecast.Temperature2MMin) > 0 && len(weeklyForecast.Time) > 0 {
-			displayCurrentForecast(addressFull, currentTemp, weeklyForecast.Temperature2MMax[0], weeklyForecast.Temperature2MMin[0], isFromCache)
-			displayExtendedForecast(weeklyForecast)
-		} else {
-			fmt.Println("Forecast data is unavailable. Please try again!")
+		switch {
+		case strings.EqualFold(address, "q"):
+			fmt.Println("Thanks for using the World's Best Weather App!")
+			return nil
+
+		case strings.HasPrefix(address, ":"):
+			session.runCommand(ctx, address)
+
+		case strings.EqualFold(address, "cache"):
+			displayCacheStats(c)
+
+		case strings.EqualFold(address, "favs"):
+			displayFavorites(favs, c, cfg.Providers.ForecastURL, session.opts)
+
+		case strings.EqualFold(command, "save"):
+			name, favAddress, _ := strings.Cut(strings.TrimSpace(rest), " ")
+			if name == "" || strings.TrimSpace(favAddress) == "" {
+				fmt.Println("To save a favorite please enter save <name> <address>")
+				break
+			}
+			place, err := saveFavorite(ctx, favs, name, strings.TrimSpace(favAddress), cfg.Providers.GeocodeURL, cfg.Providers.GeocodeAPIKey)
+			if err != nil {
+				fmt.Printf("Oops! Looks like there was a mistake: %s. Please try again!\n", err)
+				break
+			}
+			fmt.Printf("Saved %s as %s\n", place.Address, place.Name)
+
+		case strings.EqualFold(command, "unsave"):
+			removed, err := favs.Remove(strings.TrimSpace(rest))
+			switch {
+			case err != nil:
+				fmt.Printf("Oops! Looks like there was a mistake: %s. Please try again!\n", err)
+			case !removed:
+				fmt.Printf("There is no favorite named %s\n", strings.TrimSpace(rest))
+			default:
+				fmt.Printf("Removed favorite %s\n", strings.TrimSpace(rest))
+			}
+
+		default:
+			session.lookup(ctx, address)
 		}
 
 		displayPrompt()
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Printf("Error reading input: %v\n", err)
+func main() {
+	cfg, args, err := config.Load(os.Args[1:], os.Getenv, os.Stderr)
+	if err != nil {
+		fmt.Printf("Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	if err := setupLogging(cfg.Log); err != nil {
+		fmt.Printf("Invalid logging configuration: %v\n", err)
+		os.Exit(2)
+	}
+
+	// Commands that do not need the cache
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfig(args[1:], cfg, os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		return
+	}
+
+	c := setupCache(cfg)
+	favs, err := favorites.Load(cfg.FavoritesPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	switch {
+	case len(args) == 0:
+		err = runPrompt(cfg, c, favs)
+	case args[0] == "batch":
+		err = runBatch(args[1:], cfg, c, os.Stdin, os.Stdout)
+	case args[0] == "compare":
+		err = runCompare(args[1:], cfg, c, favs, os.Stdout)
+	case args[0] == "ics":
+		err = runICS(args[1:], cfg, c, favs, os.Stdout)
+	case args[0] == "history":
+		err = runHistory(args[1:], cfg, c, favs, os.Stdout)
+	case args[0] == "alerts":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err = runAlerts(ctx, args[1:], cfg, c, favs, os.Stdout)
+		stop()
+	case args[0] == "serve":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err = runServe(ctx, args[1:], cfg, c, favs, os.Stdout)
+		stop()
+	case args[0] == "tui":
+		err = runTUI(args[1:], cfg, c, favs)
+	case args[0] == "watch":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err = runWatch(ctx, args[1:], cfg, c, favs, os.Stdout, isTerminal(os.Stdout))
+		stop()
+	default:
+		err = fmt.Errorf("unknown command %q", args[0])
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
 }
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mfryhover/weather/api"
 
 	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/locale"
 )
 
 func TestMain_displayPrompt(t *testing.T) {
@@ -15,16 +20,50 @@ func TestMain_displayPrompt(t *testing.T) {
 }
 
 func TestMain_displayCurrentForecast(t *testing.T) {
-	displayCurrentForecast("3001 Esperanza Crossing, Austin, TX 78758, USA", 78.6, 97.6, 75.8, false)
+	lc, _ := locale.Lookup(locale.Default)
+	displayCurrentForecast("3001 Esperanza Crossing, Austin, TX 78758, USA", 78.6, 97.6, 75.8, false, api.Fahrenheit, lc)
 }
 
 func TestMain_displayExtendedForecast(t *testing.T) {
 	weeklyForecast := api.WeeklyForecast{
-		Time:             []string{"2024-09-19"},
-		Temperature2MMax: []float64{97.6},
-		Temperature2MMin: []float64{75.8},
+		Time:                        []string{"2024-09-19"},
+		Temperature2MMax:            []float64{97.6},
+		Temperature2MMin:            []float64{75.8},
+		Sunrise:                     []string{"2024-09-19T07:15"},
+		Sunset:                      []string{"2024-09-19T19:32"},
+		DaylightDuration:            []float64{44220},
+		UVIndexMax:                  []float64{8.1},
+		PrecipitationSum:            []float64{0.5},
+		PrecipitationProbabilityMax: []float64{70},
+		RainSum:                     []float64{0.2},
+		SnowfallSum:                 []float64{1.5},
+		WeatherCode:                 []int{71},
+		Timezone:                    "America/Chicago",
+		UTCOffsetSeconds:            -18000,
 	}
-	displayExtendedForecast(weeklyForecast)
+	lc, _ := locale.Lookup(locale.Default)
+	displayExtendedForecast(weeklyForecast, api.Fahrenheit, lc)
+}
+
+func TestMain_dayLabels(t *testing.T) {
+	lc, _ := locale.Lookup(locale.Default)
+	now := time.Date(2024, time.September, 19, 15, 0, 0, 0, time.UTC)
+
+	labels := dayLabels(api.WeeklyForecast{Time: []string{"2024-09-19", "2024-09-20", "2024-09-21"}}, now, lc.Day)
+	if strings.Join(labels, "|") != "Today|Tomorrow|Sat, Sep 21" {
+		t.Errorf("Expected Today, Tomorrow and Sat, Sep 21, got %v", labels)
+	}
+
+	labels = dayLabels(api.WeeklyForecast{Time: []string{"2024-09-19", "soon"}}, now, lc.Day)
+	if strings.Join(labels, "|") != "2024-09-19|soon" {
+		t.Errorf("Expected the raw dates when one cannot be parsed, got %v", labels)
+	}
+}
+
+func TestMain_displayCacheStats(t *testing.T) {
+	c := cache.GetCacheInstance()
+	c.Add("TestMain_displayCacheStats", 78.6, api.WeeklyForecast{})
+	displayCacheStats(c)
 }
 
 func TestMain_getPostalCode(t *testing.T) {
@@ -36,8 +75,76 @@ func TestMain_getPostalCode(t *testing.T) {
 	}
 }
 
+func TestMain_locationKey(t *testing.T) {
+	if key := locationKey("3001 Esperanza Crossing, Austin, TX 78758, USA", 30.3985991, -97.7220666); key != "78758" {
+		t.Errorf("Expected key 78758, got %s", key)
+	}
+	if key := locationKey("Paris, France", 48.856614, 2.3522219); key != "48.8566,2.3522" {
+		t.Errorf("Expected key 48.8566,2.3522, got %s", key)
+	}
+}
+
+func TestMain_forecastCacheKey(t *testing.T) {
+	if key := forecastCacheKey("78758", api.ForecastOptions{}); key != "78758:fahrenheit:7d:0p" {
+		t.Errorf("Expected key 78758:fahrenheit:7d:0p, got %s", key)
+	}
+	if key := forecastCacheKey("78758", api.ForecastOptions{Units: api.Celsius}); key != "78758:celsius:7d:0p" {
+		t.Errorf("Expected key 78758:celsius:7d:0p, got %s", key)
+	}
+	if key := forecastCacheKey("78758", api.ForecastOptions{ForecastDays: 14, PastDays: 3}); key != "78758:fahrenheit:14d:3p" {
+		t.Errorf("Expected key 78758:fahrenheit:14d:3p, got %s", key)
+	}
+}
+
+func TestMain_runConfig(t *testing.T) {
+	cfg := config.Defaults()
+	cfg.Providers.GeocodeAPIKey = "AIzaSyExampleKey1234"
+
+	var b strings.Builder
+	if err := runConfig([]string{"show"}, cfg, &b); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(b.String(), "AIzaSyExampleKey") {
+		t.Errorf("Expected the API key to be masked, got %s", b.String())
+	}
+	if !strings.Contains(b.String(), `"forecast_url": "https://api.open-meteo.com"`) {
+		t.Errorf("Expected the effective forecast URL, got %s", b.String())
+	}
+
+	if err := runConfig([]string{"edit"}, cfg, &b); err == nil {
+		t.Error("Expected an error for an unknown subcommand, got nil")
+	}
+}
+
+func TestMain_getForecastForCoordinates_PreviousDay(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"current": {"temperature_2m": 78.6},
+			"daily": {"time": ["` + time.Now().UTC().Format("2006-01-02") + `"], "temperature_2m_max": [97.6], "temperature_2m_min": [75.8]}}`))
+	}))
+	defer server.Close()
+
+	// A forecast cached on a previous day no longer covers today and must be fetched again
+	c := cache.GetCacheInstance()
+	c.Add(forecastCacheKey("99901", api.ForecastOptions{}), 60, api.WeeklyForecast{Time: []string{"2000-01-01"}, Temperature2MMax: []float64{70}, Temperature2MMin: []float64{50}})
+
+	_, weeklyForecast, isFromCache, err := getForecastForCoordinates(context.Background(), "Somewhere, TX 99901, USA", 30.1, -97.1, c, server.URL, api.ForecastOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if isFromCache || requests != 1 {
+		t.Errorf("Expected the stale forecast to be fetched again, got isFromCache %t after %d requests", isFromCache, requests)
+	}
+	if weeklyForecast.Temperature2MMax[0] != 97.6 {
+		t.Errorf("Expected the fresh forecast, got %v", weeklyForecast)
+	}
+}
+
 func TestMain_getForecast(t *testing.T) {
 	c := cache.GetCacheInstance()
+	// Cached forecasts are only served while they cover the current day
+	today := time.Now().UTC().Format("2006-01-02")
 	testcases := []struct {
 		name                 string
 		geocodeStatus        int
@@ -58,7 +165,7 @@ func TestMain_getForecast(t *testing.T) {
 							  },
 							  "daily": {
 								"time": [
-								  "2024-09-19"
+								  "` + today + `"
 								],
 								"temperature_2m_max": [
 								  97.6
@@ -100,7 +207,7 @@ func TestMain_getForecast(t *testing.T) {
 							  },
 							  "daily": {
 								"time": [
-								  "2024-09-19"
+								  "` + today + `"
 								],
 								"temperature_2m_max": [
 								  97.6
@@ -143,7 +250,7 @@ func TestMain_getForecast(t *testing.T) {
 							  },
 							  "daily": {
 								"time": [
-								  "2024-09-19"
+								  "` + today + `"
 								],
 								"temperature_2m_max": [
 								  97.6
@@ -186,7 +293,7 @@ func TestMain_getForecast(t *testing.T) {
 							  },
 							  "daily": {
 								"time": [
-								  "2024-09-19"
+								  "` + today + `"
 								],
 								"temperature_2m_max": [
 								  97.6
@@ -235,7 +342,7 @@ func TestMain_getForecast(t *testing.T) {
 			}))
 			defer server.Close()
 
-			fullAddress, currentTemp, weeklyForecast, isFromCache, err := getForecast(tc.address, c, server.URL, server.URL, "testApiKey")
+			fullAddress, currentTemp, weeklyForecast, isFromCache, err := getForecast(context.Background(), tc.address, c, server.URL, server.URL, "testApiKey", api.ForecastOptions{})
 			// Check for error cases
 			if tc.err != "" {
 				if err != nil {
@@ -0,0 +1,373 @@
+// Package metrics provides counters, gauges and histograms that are exposed over HTTP in the Prometheus text
+// exposition format. It implements only what the weather app needs, without any third party dependencies.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds, in seconds, used for upstream request latencies.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the registry the api and cache packages register their metrics with.
+var Default = NewRegistry()
+
+// collector is implemented by every metric kind so that the registry can write it out.
+type collector interface {
+	// write writes the metric samples, without the HELP and TYPE header, in the text exposition format.
+	write(w io.Writer, name string)
+}
+
+// metric holds the descriptor shared by all metric kinds.
+type metric struct {
+	// name is the fully qualified metric name.
+	name string
+	// help describes the metric.
+	help string
+	// kind is the exposition type: counter, gauge or histogram.
+	kind string
+	// collector writes the samples.
+	collector collector
+}
+
+// Registry holds a set of metrics and writes them in the Prometheus text exposition format.
+type Registry struct {
+	// mu protects metrics.
+	mu sync.RWMutex
+	// metrics holds the registered metrics in registration order.
+	metrics []metric
+	// names records registered names to reject duplicates.
+	names map[string]bool
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]bool),
+	}
+}
+
+// register adds a metric to the registry. It panics if the name is already taken, since that is a programming error.
+func (r *Registry) register(name, help, kind string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.names[name] {
+		panic(fmt.Sprintf("metrics: duplicate metric name %s", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, metric{name: name, help: help, kind: kind, collector: c})
+}
+
+// NewCounterVec creates and registers a counter partitioned by the given label names.
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(labelNames)}
+	r.register(name, help, "counter", c)
+	return c
+}
+
+// NewGaugeVec creates and registers a gauge partitioned by the given label names.
+func (r *Registry) NewGaugeVec(name, help string, labelNames ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(labelNames)}
+	r.register(name, help, "gauge", g)
+	return g
+}
+
+// NewHistogramVec creates and registers a histogram with the given bucket upper bounds, partitioned by the given
+// label names. The buckets must be sorted in increasing order; the +Inf bucket is added automatically.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	h := &HistogramVec{vec: newVec(labelNames), buckets: buckets, series: make(map[string]*histogram)}
+	r.register(name, help, "histogram", h)
+	return h
+}
+
+// NewCounterFunc registers a counter whose value is read from fn at collection time.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, help, "counter", funcCollector(fn))
+}
+
+// NewGaugeFunc registers a gauge whose value is read from fn at collection time.
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, help, "gauge", funcCollector(fn))
+}
+
+// Write writes every registered metric to w in the Prometheus text exposition format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, m := range r.metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		m.collector.write(w, m.name)
+	}
+}
+
+// Handler returns an http.Handler that serves the registry in the Prometheus text exposition format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Write(w)
+	})
+}
+
+// vec tracks the label names of a metric and the label values of each series.
+type vec struct {
+	// labelNames are the names of the labels partitioning the metric.
+	labelNames []string
+	// mu protects the series maps of the embedding type.
+	mu sync.Mutex
+}
+
+// newVec returns a vec with the given label names.
+func newVec(labelNames []string) vec {
+	return vec{labelNames: labelNames}
+}
+
+// key joins label values into a map key. It panics if the number of values does not match the label names.
+func (v *vec) key(labelValues []string) string {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(v.labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// labels formats the label pairs for a series key, optionally followed by an extra pair such as le for buckets.
+func (v *vec) labels(key string, extra ...string) string {
+	var pairs []string
+	if len(v.labelNames) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", v.labelNames[i], escapeLabel(value)))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extra[i], escapeLabel(extra[i+1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// CounterVec is a monotonically increasing value partitioned by labels.
+type CounterVec struct {
+	vec
+	// series holds the value of each label combination.
+	series map[string]float64
+}
+
+// Inc increments the counter for the given label values by one.
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter for the given label values by delta, which must not be negative.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counters cannot decrease")
+	}
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.series == nil {
+		c.series = make(map[string]float64)
+	}
+	c.series[key] += delta
+}
+
+// Value returns the current value of the counter for the given label values.
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.series[key]
+}
+
+// write implements collector.
+func (c *CounterVec) write(w io.Writer, name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeSeries(w, name, &c.vec, c.series)
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels.
+type GaugeVec struct {
+	vec
+	// series holds the value of each label combination.
+	series map[string]float64
+}
+
+// Set sets the gauge for the given label values.
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.series == nil {
+		g.series = make(map[string]float64)
+	}
+	g.series[key] = value
+}
+
+// Add adds delta, which may be negative, to the gauge for the given label values.
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.series == nil {
+		g.series = make(map[string]float64)
+	}
+	g.series[key] += delta
+}
+
+// Inc increments the gauge for the given label values by one.
+func (g *GaugeVec) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec decrements the gauge for the given label values by one.
+func (g *GaugeVec) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Value returns the current value of the gauge for the given label values.
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	key := g.key(labelValues)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.series[key]
+}
+
+// write implements collector.
+func (g *GaugeVec) write(w io.Writer, name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeSeries(w, name, &g.vec, g.series)
+}
+
+// HistogramVec counts observations into buckets, partitioned by labels.
+type HistogramVec struct {
+	vec
+	// buckets are the upper bounds of each bucket, excluding +Inf.
+	buckets []float64
+	// series holds the histogram of each label combination.
+	series map[string]*histogram
+}
+
+// histogram holds the bucket counts, sum and count of a single series.
+type histogram struct {
+	// counts holds the non-cumulative count of each bucket, with the +Inf bucket last.
+	counts []uint64
+	// sum is the sum of all observations.
+	sum float64
+	// count is the number of observations.
+	count uint64
+}
+
+// Observe records a single observation for the given label values.
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets)+1)}
+		h.series[key] = s
+	}
+	i := sort.SearchFloat64s(h.buckets, value)
+	s.counts[i]++
+	s.sum += value
+	s.count++
+}
+
+// Count returns the number of observations recorded for the given label values.
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+// write implements collector.
+func (h *HistogramVec) write(w io.Writer, name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, h.labels(key, "le", formatFloat(upper)), cumulative)
+		}
+		cumulative += s.counts[len(h.buckets)]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, h.labels(key, "le", "+Inf"), cumulative)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, h.labels(key), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, h.labels(key), s.count)
+	}
+}
+
+// funcCollector reads an unlabelled value from a function at collection time.
+type funcCollector func() float64
+
+// write implements collector.
+func (f funcCollector) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(f()))
+}
+
+// writeSeries writes one sample per series of a counter or gauge, sorted by label values.
+func writeSeries(w io.Writer, name string, v *vec, series map[string]float64) {
+	for _, key := range sortedKeys(series) {
+		fmt.Fprintf(w, "%s%s %s\n", name, v.labels(key), formatFloat(series[key]))
+	}
+}
+
+// sortedKeys returns the keys of m in sorted order so that the output is stable.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFloat formats a sample value as expected by the text exposition format.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// escapeLabel escapes backslashes, double quotes and line feeds in a label value.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// escapeHelp escapes backslashes and line feeds in a HELP line.
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "Requests made.", "provider", "code")
+	inFlight := r.NewGaugeVec("test_in_flight", "Requests in flight.", "provider")
+	latency := r.NewHistogramVec("test_duration_seconds", "Request latency.", []float64{0.1, 1}, "provider")
+	r.NewGaugeFunc("test_size", "Entries held.", func() float64 { return 3 })
+
+	requests.Inc("geocode", "200")
+	requests.Inc("forecast", "200")
+	requests.Add(2, "forecast", "200")
+	inFlight.Inc("forecast")
+	inFlight.Inc("forecast")
+	inFlight.Dec("forecast")
+	latency.Observe(0.05, "forecast")
+	latency.Observe(0.1, "forecast")
+	latency.Observe(5, "forecast")
+
+	var b strings.Builder
+	r.Write(&b)
+
+	expected := `# HELP test_requests_total Requests made.
+# TYPE test_requests_total counter
+test_requests_total{provider="forecast",code="200"} 3
+test_requests_total{provider="geocode",code="200"} 1
+# HELP test_in_flight Requests in flight.
+# TYPE test_in_flight gauge
+test_in_flight{provider="forecast"} 1
+# HELP test_duration_seconds Request latency.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{provider="forecast",le="0.1"} 2
+test_duration_seconds_bucket{provider="forecast",le="1"} 2
+test_duration_seconds_bucket{provider="forecast",le="+Inf"} 3
+test_duration_seconds_sum{provider="forecast"} 5.15
+test_duration_seconds_count{provider="forecast"} 3
+# HELP test_size Entries held.
+# TYPE test_size gauge
+test_size 3
+`
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestRegistry_LabelEscaping(t *testing.T) {
+	r := NewRegistry()
+	errs := r.NewCounterVec("test_errors_total", "Errors.", "type")
+	errs.Inc("bad \"quote\"\n")
+
+	var b strings.Builder
+	r.Write(&b)
+	if !strings.Contains(b.String(), `test_errors_total{type="bad \"quote\"\n"} 1`) {
+		t.Errorf("Expected escaped label value, got:\n%s", b.String())
+	}
+}
+
+func TestRegistry_DuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "Test.")
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a duplicate name to panic")
+		}
+	}()
+	r.NewGaugeVec("test_total", "Test.")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "Test.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected text exposition content type, got %s", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "test_total 1\n") {
+		t.Errorf("Expected test_total 1 in body, got:\n%s", rec.Body.String())
+	}
+}
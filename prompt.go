@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+)
+
+// replCommands are the colon commands of the prompt, completed with tab.
+var replCommands = []string{":units c", ":units f", ":again", ":raw", ":cache clear", ":help"}
+
+// rawForecast is the last forecast of the prompt, as displayed by :raw.
+type rawForecast struct {
+	// Address is the full formatted address.
+	Address string `json:"address"`
+	// CurrentTemperature is the current temperature.
+	CurrentTemperature float64 `json:"current_temperature"`
+	// Units is the unit system of the forecast.
+	Units api.Units `json:"units"`
+	// IsFromCache is set if the forecast was served from the cache.
+	IsFromCache bool `json:"from_cache"`
+	// Daily is the daily forecast.
+	Daily api.WeeklyForecast `json:"daily"`
+}
+
+// promptSession holds the state of the interactive prompt that outlives a single line, such as the units selected
+// with :units and the last lookup repeated by :again.
+type promptSession struct {
+	cfg  config.Config
+	c    *cache.Cache
+	favs *favorites.Store
+	opts api.ForecastOptions
+	lc   locale.Locale
+	// out is where colon commands write to.
+	out io.Writer
+	// last is the last location looked up successfully.
+	last string
+	// lastForecast is the forecast of the last lookup, or nil before the first one.
+	lastForecast *rawForecast
+}
+
+// lookup displays the forecast for a favorite name or an address, with the air quality if enabled.
+func (s *promptSession) lookup(ctx context.Context, input string) {
+	addressFull, lat, lng, err := lookupCoordinates(ctx, input, s.favs, s.cfg)
+	if err != nil {
+		fmt.Printf("Oops! Looks like there was a mistake: %s. Please try again!\n", err)
+		return
+	}
+	currentTemp, weeklyForecast, isFromCache, err := getForecastForCoordinates(ctx, addressFull, lat, lng, s.c, s.cfg.Providers.ForecastURL, s.opts)
+	if err != nil {
+		fmt.Printf("Oops! Looks like there was a mistake: %s. Please try again!\n", err)
+		return
+	}
+
+	// Air quality is optional, so failing to retrieve it does not prevent displaying the forecast
+	var airQuality *api.AirQuality
+	if s.cfg.AirQuality {
+		aq, err := api.GetAirQualityContext(ctx, lat, lng, s.cfg.Providers.AirQualityURL)
+		if err != nil {
+			slog.WarnContext(ctx, "air quality unavailable", "error", err)
+		} else {
+			airQuality = &aq
+		}
+	}
+	displayForecast(addressFull, currentTemp, weeklyForecast, isFromCache, airQuality, s.opts.Units, s.lc, s.cfg.Chart)
+
+	s.last = input
+	s.lastForecast = &rawForecast{
+		Address:            addressFull,
+		CurrentTemperature: currentTemp,
+		Units:              s.opts.Units,
+		IsFromCache:        isFromCache,
+		Daily:              weeklyForecast,
+	}
+}
+
+// runCommand runs a colon command, such as ":units c".
+func (s *promptSession) runCommand(ctx context.Context, line string) {
+	command, arg, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	arg = strings.ToLower(strings.TrimSpace(arg))
+
+	switch strings.ToLower(command) {
+	case "units":
+		switch arg {
+		case "":
+			fmt.Fprintf(s.out, "Forecasts are displayed in %s\n", s.opts.Units)
+			return
+		case "c", "celsius":
+			s.opts.Units = api.Celsius
+		case "f", "fahrenheit":
+			s.opts.Units = api.Fahrenheit
+		default:
+			fmt.Fprintf(s.out, "Unknown units %q. Please enter :units c or :units f\n", arg)
+			return
+		}
+		fmt.Fprintf(s.out, "Forecasts are now displayed in %s\n", s.opts.Units)
+
+	case "again":
+		if s.last == "" {
+			fmt.Fprintln(s.out, "There is no lookup to repeat yet")
+			return
+		}
+		s.lookup(ctx, s.last)
+
+	case "raw":
+		if s.lastForecast == nil {
+			fmt.Fprintln(s.out, "There is no forecast to display yet")
+			return
+		}
+		data, err := json.MarshalIndent(s.lastForecast, "", "  ")
+		if err != nil {
+			fmt.Fprintf(s.out, "Oops! Looks like there was a mistake: %s. Please try again!\n", err)
+			return
+		}
+		fmt.Fprintln(s.out, string(data))
+
+	case "cache":
+		if arg != "clear" {
+			fmt.Fprintln(s.out, "To clear the cache please enter :cache clear, or cache to see its statistics")
+			return
+		}
+		fmt.Fprintf(s.out, "Removed %d cache entries\n", s.c.Clear())
+
+	case "help":
+		displayREPLHelp(s.out)
+
+	default:
+		fmt.Fprintf(s.out, "Unknown command %s. To see all commands please enter :help\n", line)
+	}
+}
+
+// completionWords returns the words the prompt completes with tab: the commands, the favorite names and the
+// locations previously looked up, taken from the prompt history.
+func (s *promptSession) completionWords(history []string) []string {
+	words := append([]string{"cache", "favs"}, replCommands...)
+	for _, place := range s.favs.List() {
+		words = append(words, place.Name)
+	}
+	for _, line := range history {
+		command, _, _ := strings.Cut(line, " ")
+		if !strings.HasPrefix(line, ":") && !promptCommands[strings.ToLower(command)] {
+			words = append(words, line)
+		}
+	}
+	return words
+}
+
+// displayREPLHelp displays the colon commands and the line editing keys.
+func displayREPLHelp(w io.Writer) {
+	fmt.Fprintln(w, "Commands: ")
+	fmt.Fprintln(w, "---------------------------")
+	fmt.Fprintln(w, ":units c|f     display forecasts in Celsius or Fahrenheit")
+	fmt.Fprintln(w, ":again         repeat the last lookup")
+	fmt.Fprintln(w, ":raw           display the last forecast as JSON")
+	fmt.Fprintln(w, ":cache clear   remove every cached forecast")
+	fmt.Fprintln(w, ":help          display this help")
+	fmt.Fprintln(w, "Use the up and down arrows to browse previous entries, and tab to complete addresses, favorites and commands")
+	fmt.Fprintln(w)
+}
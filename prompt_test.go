@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+)
+
+func TestMain_promptSession_runCommand(t *testing.T) {
+	var units []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		units = append(units, r.URL.Query().Get("temperature_unit"))
+		fmt.Fprintf(w, `{"current": {"temperature_2m": 25.9},
+			"daily": {"time": ["%s"], "temperature_2m_max": [36.4], "temperature_2m_min": [24.3]}}`, time.Now().UTC().Format("2006-01-02"))
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ForecastURL = server.URL
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	favs.Save(favorites.Place{Name: "plant", Address: "1 Plant Rd, Austin, TX 78725, USA", Latitude: 30.2445, Longitude: -97.6121})
+	lc, _ := locale.Lookup(locale.Default)
+	c := cache.GetCacheInstance()
+
+	var b strings.Builder
+	session := &promptSession{cfg: cfg, c: c, favs: favs, opts: forecastOptions(cfg), lc: lc, out: &b}
+	ctx := context.Background()
+
+	tc := []struct {
+		name     string
+		line     string
+		expected string
+	}{
+		{name: "Nothing To Repeat", line: ":again", expected: "There is no lookup to repeat yet"},
+		{name: "Nothing To Display", line: ":raw", expected: "There is no forecast to display yet"},
+		{name: "Units", line: ":units C", expected: "Forecasts are now displayed in celsius"},
+		{name: "Unknown Units", line: ":units k", expected: `Unknown units "k"`},
+		{name: "Help", line: ":help", expected: ":cache clear   remove every cached forecast"},
+		{name: "Unknown Command", line: ":forecast", expected: "Unknown command :forecast. To see all commands please enter :help"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			b.Reset()
+			session.runCommand(ctx, tc.line)
+			if !strings.Contains(b.String(), tc.expected) {
+				t.Errorf("Expected the output to contain %q, got:\n%s", tc.expected, b.String())
+			}
+		})
+	}
+
+	// The units selected apply to the next lookups, which :again repeats from the cache and :raw displays
+	session.lookup(ctx, "plant")
+	b.Reset()
+	session.runCommand(ctx, ":again")
+	session.runCommand(ctx, ":raw")
+	if len(units) != 1 || units[0] != "celsius" {
+		t.Errorf("Expected one request in celsius, got %v", units)
+	}
+	for _, expected := range []string{`"address": "1 Plant Rd, Austin, TX 78725, USA"`, `"units": "celsius"`, `"from_cache": true`, `"temperature_2m_max": [`} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("Expected the raw forecast to contain %q, got:\n%s", expected, b.String())
+		}
+	}
+
+	b.Reset()
+	session.runCommand(ctx, ":cache clear")
+	if !strings.Contains(b.String(), "Removed") || len(c.Keys()) != 0 {
+		t.Errorf("Expected the cache to be cleared, got %s and keys %v", b.String(), c.Keys())
+	}
+	if session.opts.Units != api.Celsius {
+		t.Errorf("Expected the session to stay in celsius, got %s", session.opts.Units)
+	}
+}
+
+func TestMain_promptSession_completionWords(t *testing.T) {
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	favs.Save(favorites.Place{Name: "hq", Address: "3001 Esperanza Crossing, Austin, TX 78758, USA", Latitude: 30.3985991, Longitude: -97.7220666})
+	session := &promptSession{favs: favs}
+
+	words := session.completionWords([]string{"78758", ":units c", "save home 350 5th Ave", "350 5th Ave, New York"})
+	matches := completionCandidates("", words)
+	for _, expected := range []string{"hq", "78758", "350 5th Ave, New York", ":again"} {
+		if !strings.Contains(strings.Join(matches, "|"), expected) {
+			t.Errorf("Expected %q to be a completion, got %v", expected, matches)
+		}
+	}
+	for _, unexpected := range []string{"save home 350 5th Ave"} {
+		for _, m := range matches {
+			if m == unexpected {
+				t.Errorf("Expected %q not to be a completion, got %v", unexpected, matches)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// cellStyle is the style a screen cell is drawn in.
+type cellStyle uint8
+
+const (
+	stylePlain cellStyle = iota
+	styleBold
+	styleReverse
+	styleDim
+)
+
+// styleCodes maps the cell styles to their ANSI SGR escape sequences.
+var styleCodes = map[cellStyle]string{
+	styleBold:    "\033[1m",
+	styleReverse: "\033[7m",
+	styleDim:     "\033[2m",
+}
+
+// cell is a character on the screen with its style.
+type cell struct {
+	r     rune
+	style cellStyle
+}
+
+// screen is a virtual screen of fixed size that the full-screen interface draws on. It is written to a terminal with
+// render, and read back as plain text with String, which lets tests check the layout without a terminal.
+type screen struct {
+	width, height int
+	cells         []cell
+	// cursorX and cursorY are where the terminal cursor is shown, if cursorVisible is set.
+	cursorX, cursorY int
+	cursorVisible    bool
+}
+
+// newScreen returns a blank screen of the given size.
+func newScreen(width, height int) *screen {
+	s := &screen{width: width, height: height, cells: make([]cell, width*height)}
+	for i := range s.cells {
+		s.cells[i] = cell{r: ' '}
+	}
+	return s
+}
+
+// set draws r at column x of row y. Cells outside the screen are ignored.
+func (s *screen) set(x, y int, r rune, style cellStyle) {
+	if x < 0 || y < 0 || x >= s.width || y >= s.height {
+		return
+	}
+	s.cells[y*s.width+x] = cell{r: r, style: style}
+}
+
+// text draws text from column x of row y, clipped to maxWidth columns and to the screen, and returns the number of
+// columns drawn.
+func (s *screen) text(x, y int, text string, maxWidth int, style cellStyle) int {
+	n := 0
+	for _, r := range text {
+		if n >= maxWidth {
+			break
+		}
+		s.set(x+n, y, r, style)
+		n++
+	}
+	return n
+}
+
+// fill draws r over width columns from column x of row y.
+func (s *screen) fill(x, y, width int, r rune, style cellStyle) {
+	for i := 0; i < width; i++ {
+		s.set(x+i, y, r, style)
+	}
+}
+
+// box draws a frame of the given size with its top left corner at column x of row y, and its title in the top edge.
+func (s *screen) box(x, y, width, height int, title string) {
+	if width < 2 || height < 2 {
+		return
+	}
+	right, bottom := x+width-1, y+height-1
+	s.fill(x+1, y, width-2, '─', stylePlain)
+	s.fill(x+1, bottom, width-2, '─', stylePlain)
+	for row := y + 1; row < bottom; row++ {
+		s.set(x, row, '│', stylePlain)
+		s.set(right, row, '│', stylePlain)
+	}
+	s.set(x, y, '┌', stylePlain)
+	s.set(right, y, '┐', stylePlain)
+	s.set(x, bottom, '└', stylePlain)
+	s.set(right, bottom, '┘', stylePlain)
+	if title != "" {
+		s.text(x+2, y, " "+title+" ", width-4, styleBold)
+	}
+}
+
+// String returns the screen as plain text, one line per row without trailing spaces.
+func (s *screen) String() string {
+	lines := make([]string, s.height)
+	for y := range lines {
+		var b strings.Builder
+		for _, c := range s.cells[y*s.width : (y+1)*s.width] {
+			b.WriteRune(c.r)
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// render draws the whole screen on a terminal from its top left corner, with styles, and places the cursor.
+func (s *screen) render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for y := 0; y < s.height; y++ {
+		current := stylePlain
+		for _, c := range s.cells[y*s.width : (y+1)*s.width] {
+			if c.style != current {
+				b.WriteString(ansiReset + styleCodes[c.style])
+				current = c.style
+			}
+			b.WriteRune(c.r)
+		}
+		if current != stylePlain {
+			b.WriteString(ansiReset)
+		}
+		if y < s.height-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	if s.cursorVisible {
+		fmt.Fprintf(&b, "\033[%d;%dH%s", s.cursorY+1, s.cursorX+1, ansiShowCursor)
+	} else {
+		b.WriteString(ansiHideCursor)
+	}
+	io.WriteString(w, b.String())
+}
+
+// truncate shortens text to width columns, ending it with … if it was cut.
+func truncate(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	if width <= 0 {
+		return ""
+	}
+	return string([]rune(text)[:width-1]) + "…"
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+)
+
+// serverShutdownTimeout bounds how long the server waits for requests in flight once interrupted.
+const serverShutdownTimeout = 10 * time.Second
+
+// newServeMux returns the routes of the HTTP API. Event streams end once ctx is done, so that shutting the server
+// down does not wait for them.
+func newServeMux(ctx context.Context, cfg config.Config, c *cache.Cache, favs *favorites.Store) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", graphqlHandler(cfg, c, favs))
+	mux.Handle("/v1/forecast", forecastHandler(cfg, c, favs))
+	mux.Handle("/v1/stream", streamHandler(ctx, cfg, c, favs))
+	return mux
+}
+
+// runServe runs the serve command, which serves the HTTP API, and the gRPC API if it has an address, until ctx is
+// canceled.
+func runServe(ctx context.Context, args []string, cfg config.Config, c *cache.Cache, favs *favorites.Store, stdout io.Writer) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", cfg.Server.Addr, "address to listen on")
+	grpcAddr := flags.String("grpc-addr", cfg.Server.GRPCAddr, "address to serve the gRPC API on (default: disabled)")
+	positional, err := parseInterleaved(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errors.New("usage: weather serve [--addr localhost:8080] [--grpc-addr localhost:9090]")
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %v", *addr, err)
+	}
+	server := &http.Server{Handler: newServeMux(ctx, cfg, c, favs), ReadHeaderTimeout: 10 * time.Second}
+	fmt.Fprintf(stdout, "Serving the weather API on http://%s\n", listener.Addr())
+
+	errs := make(chan error, 2)
+	go func() { errs <- server.Serve(listener) }()
+	if *grpcAddr != "" {
+		grpcListener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			server.Close()
+			return fmt.Errorf("error listening on %s: %v", *grpcAddr, err)
+		}
+		grpcServer := newGRPCServer(ctx, cfg, c, favs)
+		defer grpcServer.GracefulStop()
+		fmt.Fprintf(stdout, "Serving the weather gRPC API on %s\n", grpcListener.Addr())
+		go func() { errs <- grpcServer.Serve(grpcListener) }()
+	}
+	select {
+	case err := <-errs:
+		server.Close()
+		return fmt.Errorf("error serving the weather API: %v", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down the weather API", "addr", listener.Addr().String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("error shutting down the weather API: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/logging"
+)
+
+const (
+	// streamKeepAlive is how often an idle stream sends a comment, so that proxies do not close the connection.
+	streamKeepAlive = 30 * time.Second
+	// streamRefreshSlack is how long after the cached forecast expires the stream fetches it again, if no other
+	// request has refreshed it in the meantime.
+	streamRefreshSlack = time.Second
+)
+
+// writeEvent writes a Server-Sent Event with the given name and JSON data.
+func writeEvent(w io.Writer, event string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	return err
+}
+
+// streamHandler returns the handler of /v1/stream, which streams the forecast of the address parameter as
+// Server-Sent Events: the current forecast, then the forecast every time its cache entry is refreshed, whichever
+// request refreshed it. If nothing else refreshes the entry, the stream does once it expires. The units and days
+// parameters select the forecast, and default to the configuration. Streams end when the client disconnects or
+// done is done.
+func streamHandler(done context.Context, cfg config.Config, c *cache.Cache, favs *favorites.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		address, opts, err := parseForecastQuery(cfg, r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ctx := logging.WithRequestID(r.Context(), logging.NewRequestID())
+		addressFull, lat, lng, err := lookupCoordinates(ctx, address, favs, cfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		// Subscribe before the forecast is fetched, so that no refresh is missed in between
+		key := forecastCacheKey(locationKey(addressFull, lat, lng), opts)
+		updates, unsubscribe := c.Subscribe(key)
+		defer unsubscribe()
+		entry, _, err := getForecastEntry(ctx, addressFull, lat, lng, c, cfg.Providers.ForecastURL, opts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		if err := writeEvent(w, "forecast", newForecastRecord(addressFull, opts.Units, entry.CurrentTemp, entry.WeeklyForecast)); err != nil {
+			return
+		}
+		flusher.Flush()
+
+		// sent is when the last forecast streamed was cached, which skips the update of the fetch above
+		sent := entry.Timestamp
+		refresh := time.NewTimer(entry.TTL + streamRefreshSlack)
+		defer refresh.Stop()
+		keepAlive := time.NewTicker(streamKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done.Done():
+				return
+			case update := <-updates:
+				if !update.Timestamp.After(sent) {
+					continue
+				}
+				sent = update.Timestamp
+				refresh.Reset(time.Duration(cfg.Cache.TTL) + streamRefreshSlack)
+				if err := writeEvent(w, "forecast", newForecastRecord(addressFull, opts.Units, update.CurrentTemp, update.WeeklyForecast)); err != nil {
+					return
+				}
+			case <-refresh.C:
+				// An entry still cached is refreshed once it expires. The entry is sent here as well as through the
+				// updates, whichever comes first, since an update is dropped if the subscriber falls behind.
+				entry, _, err := getForecastEntry(ctx, addressFull, lat, lng, c, cfg.Providers.ForecastURL, opts)
+				if err != nil {
+					slog.WarnContext(ctx, "forecast refresh failed", "error", err)
+					refresh.Reset(time.Duration(cfg.Cache.TTL) + streamRefreshSlack)
+					continue
+				}
+				refresh.Reset(entry.TTL + streamRefreshSlack)
+				if !entry.Timestamp.After(sent) {
+					continue
+				}
+				sent = entry.Timestamp
+				if err := writeEvent(w, "forecast", newForecastRecord(addressFull, opts.Units, entry.CurrentTemp, entry.WeeklyForecast)); err != nil {
+					return
+				}
+			case <-keepAlive.C:
+				if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	})
+}
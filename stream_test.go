@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+)
+
+// readEvent reads the next event from a Server-Sent Events stream, skipping comments, and returns its lines.
+func readEvent(r *bufio.Reader) (string, error) {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && len(lines) > 0:
+			return strings.Join(lines, "\n"), nil
+		case line != "" && !strings.HasPrefix(line, ":"):
+			lines = append(lines, line)
+		}
+	}
+}
+
+func TestMain_streamHandler(t *testing.T) {
+	var forecastRequests atomic.Int32
+	today := time.Now().UTC().Format("2006-01-02")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forecastRequests.Add(1)
+		fmt.Fprintf(w, `{"timezone": "UTC", "current": {"temperature_2m": 77.9},
+			"daily": {"time": ["%s"], "temperature_2m_max": [95.2], "temperature_2m_min": [73.4]}}`, today)
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ForecastURL = server.URL
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	if err := favs.Save(favorites.Place{Name: "dash", Address: "7 Stream St, Austin, TX 78744, USA", Latitude: 30.2371, Longitude: -97.7261}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	c := cache.GetCacheInstance()
+	apiServer := httptest.NewServer(newServeMux(context.Background(), cfg, c, favs))
+	defer apiServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, apiServer.URL+"/v1/stream?address=dash&units=celsius", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got status %d and %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := bufio.NewReader(resp.Body)
+
+	event, err := readEvent(events)
+	expected := `event: forecast
+data: {"address":"7 Stream St, Austin, TX 78744, USA","current_temp":77.9,"units":"celsius","daily":[{"date":"` + today + `","max":95.2,"min":73.4}]}`
+	if err != nil || event != expected {
+		t.Fatalf("Expected %s, got %s (%v)", expected, event, err)
+	}
+
+	// A refresh of the entry by any request is streamed, while other entries and units are not
+	opts := forecastOptions(cfg)
+	opts.Units = api.Celsius
+	weeklyForecast := api.WeeklyForecast{Time: []string{today}, Temperature2MMax: []float64{99.5}, Temperature2MMin: []float64{74.1}}
+	c.Add(forecastCacheKey("78744", forecastOptions(cfg)), 60.0, weeklyForecast)
+	c.Add(forecastCacheKey("78744", opts), 81.2, weeklyForecast)
+	event, err = readEvent(events)
+	expected = `event: forecast
+data: {"address":"7 Stream St, Austin, TX 78744, USA","current_temp":81.2,"units":"celsius","daily":[{"date":"` + today + `","max":99.5,"min":74.1}]}`
+	if err != nil || event != expected {
+		t.Errorf("Expected %s, got %s (%v)", expected, event, err)
+	}
+	if forecastRequests.Load() != 1 {
+		t.Errorf("Expected 1 forecast request, got %d", forecastRequests.Load())
+	}
+
+	tc := []struct {
+		name   string
+		url    string
+		status int
+		body   string
+	}{
+		{name: "Missing Address", url: "/v1/stream", status: http.StatusBadRequest, body: "missing address"},
+		{name: "Invalid Units", url: "/v1/stream?address=dash&units=kelvin", status: http.StatusBadRequest, body: `invalid units "kelvin": must be fahrenheit or celsius`},
+		{name: "Invalid Days", url: "/v1/stream?address=dash&days=17", status: http.StatusBadRequest, body: "invalid forecast days 17: must be between 1 and 16"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(apiServer.URL + tc.url)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tc.status || strings.TrimSpace(string(body)) != tc.body {
+				t.Errorf("Expected status %d and %q, got %d and %q", tc.status, tc.body, resp.StatusCode, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+const (
+	// defaultTerminalWidth is the width assumed when it cannot be detected.
+	defaultTerminalWidth = 80
+	// defaultTerminalHeight is the height assumed when it cannot be detected.
+	defaultTerminalHeight = 24
+)
+
+// isTerminal reports whether f is connected to a terminal rather than a file or a pipe.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth returns the number of columns of the terminal f is connected to. It falls back to the COLUMNS
+// environment variable, then to defaultTerminalWidth.
+func terminalWidth(f *os.File) int {
+	if width, _, ok := ioctlSize(f); ok && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return defaultTerminalWidth
+}
+
+// terminalHeight returns the number of rows of the terminal f is connected to. It falls back to the LINES environment
+// variable, then to defaultTerminalHeight.
+func terminalHeight(f *os.File) int {
+	if _, height, ok := ioctlSize(f); ok && height > 0 {
+		return height
+	}
+	if height, err := strconv.Atoi(os.Getenv("LINES")); err == nil && height > 0 {
+		return height
+	}
+	return defaultTerminalHeight
+}
@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// ioctlGetTermios and ioctlSetTermios are the requests that read and write the terminal attributes on Linux.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,19 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// ioctlSize is not supported on this platform, so terminalWidth and terminalHeight fall back to COLUMNS and LINES.
+func ioctlSize(_ *os.File) (int, int, bool) {
+	return 0, 0, false
+}
+
+// makeRaw is not supported on this platform, so the prompt reads whole lines without line editing
+// and the full-screen interface is unavailable.
+func makeRaw(_ *os.File, _ bool) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
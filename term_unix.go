@@ -0,0 +1,49 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize mirrors struct winsize from sys/ioctl.h.
+type winsize struct {
+	rows, cols, xpixel, ypixel uint16
+}
+
+// ioctlSize asks the terminal driver for the number of columns and rows of the terminal f is connected to.
+func ioctlSize(f *os.File) (int, int, bool) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0, 0, false
+	}
+	return int(ws.cols), int(ws.rows), true
+}
+
+// makeRaw switches the terminal f is connected to from line mode to reading each key as it is typed, without echo,
+// so that arrows and tab can be handled. Output processing is kept, and so are signals such as Ctrl+C if signals is
+// set; otherwise Ctrl+C is read as a key. It returns a function that restores the previous mode.
+func makeRaw(f *os.File, signals bool) (func(), error) {
+	var old syscall.Termios
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlGetTermios, uintptr(unsafe.Pointer(&old))); errno != 0 {
+		return nil, errno
+	}
+
+	raw := old
+	raw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.IEXTEN
+	if !signals {
+		raw.Lflag &^= syscall.ISIG
+	}
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&raw))); errno != 0 {
+		return nil, errno
+	}
+
+	return func() {
+		syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), ioctlSetTermios, uintptr(unsafe.Pointer(&old)))
+	}, nil
+}
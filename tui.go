@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+	"github.com/mfryhover/weather/logging"
+)
+
+const (
+	// tuiMinWidth and tuiMinHeight are the smallest terminal the full-screen interface is laid out in.
+	tuiMinWidth  = 60
+	tuiMinHeight = 24
+	// tuiRecentWidth is the width of the panel listing the recent locations.
+	tuiRecentWidth = 24
+	// tuiMaxRecent is the number of recent locations kept.
+	tuiMaxRecent = 9
+	// tuiTableDays is the number of days in the forecast table.
+	tuiTableDays = 7
+
+	// ansiAltScreen and ansiMainScreen switch to the alternate screen buffer and back, so that the terminal content
+	// from before the full-screen interface is restored when it exits.
+	ansiAltScreen  = "\033[?1049h"
+	ansiMainScreen = "\033[?1049l"
+)
+
+// Keys read by the full-screen interface besides the line editor keys. Arrows are negative so that they cannot be
+// confused with characters.
+const (
+	keyCtrlR     = 18
+	keyArrowUp   = -1
+	keyArrowDown = -2
+)
+
+// tuiLocation is a location looked up in the full-screen interface, with its forecast.
+type tuiLocation struct {
+	// Input is the address or favorite name as typed.
+	Input string
+	// Address is the full formatted address.
+	Address string
+	// CurrentTemp is the current temperature.
+	CurrentTemp float64
+	// WeeklyForecast is the daily and hourly forecast.
+	WeeklyForecast api.WeeklyForecast
+	// IsFromCache is set if the forecast was served from the cache.
+	IsFromCache bool
+}
+
+// tui is the state of the full-screen interface: the address being typed, the recent locations and the one selected.
+type tui struct {
+	// lookup retrieves the forecast of an address or a favorite name.
+	lookup func(input string) (tuiLocation, error)
+	// busy is called before a lookup, so that the screen can show it is in progress. It may be nil.
+	busy  func()
+	units api.Units
+	lc    locale.Locale
+	// now returns the current time.
+	now func() time.Time
+
+	input []rune
+	// recent holds the locations looked up, most recent first.
+	recent   []tuiLocation
+	selected int
+	status   string
+	quit     bool
+}
+
+// readKey reads a key from r, translating the up and down arrow escape sequences to keyArrowUp and keyArrowDown.
+// Other escape sequences are returned as keyEscape.
+func readKey(r *bufio.Reader) (rune, error) {
+	key, _, err := r.ReadRune()
+	if err != nil || key != keyEscape {
+		return key, err
+	}
+	switch readEscape(r) {
+	case 'A':
+		return keyArrowUp, nil
+	case 'B':
+		return keyArrowDown, nil
+	default:
+		return keyEscape, nil
+	}
+}
+
+// handleKey updates the interface for a key: characters edit the address, Enter looks it up, the up and down arrows
+// select a recent location, Ctrl+R refreshes it, and Ctrl+C, or Ctrl+D with an empty address, quits.
+func (t *tui) handleKey(key rune) {
+	switch key {
+	case keyCtrlC:
+		t.quit = true
+
+	case keyCtrlD:
+		t.quit = len(t.input) == 0
+
+	case '\r', '\n':
+		input := strings.TrimSpace(string(t.input))
+		if input == "" {
+			break
+		}
+		if t.fetch(input) {
+			t.input = nil
+		}
+
+	case keyCtrlR:
+		if len(t.recent) > 0 {
+			t.fetch(t.recent[t.selected].Input)
+		}
+
+	case keyBackspace, keyDelete:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+
+	case keyCtrlU:
+		t.input = nil
+
+	case keyArrowUp:
+		if t.selected > 0 {
+			t.selected--
+		}
+
+	case keyArrowDown:
+		if t.selected < len(t.recent)-1 {
+			t.selected++
+		}
+
+	default:
+		if key >= ' ' {
+			t.input = append(t.input, key)
+		}
+	}
+}
+
+// fetch looks up a location and selects it at the top of the recent locations. It reports whether the lookup
+// succeeded; if not, the error is shown in the status line.
+func (t *tui) fetch(input string) bool {
+	t.status = fmt.Sprintf("Looking up %s...", input)
+	if t.busy != nil {
+		t.busy()
+	}
+
+	location, err := t.lookup(input)
+	if err != nil {
+		t.status = fmt.Sprintf("Oops! Looks like there was a mistake: %s", err)
+		return false
+	}
+
+	recent := []tuiLocation{location}
+	for _, l := range t.recent {
+		if l.Address != location.Address && len(recent) < tuiMaxRecent {
+			recent = append(recent, l)
+		}
+	}
+	t.recent, t.selected = recent, 0
+	t.status = "Updated " + location.Address
+	if location.IsFromCache {
+		t.status += " from the cache"
+	}
+	return true
+}
+
+// draw lays the interface out on s: a title bar, the address input, the recent locations on the left, and the
+// current conditions, the daily table and the hourly chart of the selected location on the right.
+func (t *tui) draw(s *screen) {
+	if s.width < tuiMinWidth || s.height < tuiMinHeight {
+		s.text(0, 0, fmt.Sprintf("Terminal too small, %dx%d needed", tuiMinWidth, tuiMinHeight), s.width, stylePlain)
+		return
+	}
+
+	s.fill(0, 0, s.width, ' ', styleReverse)
+	s.text(1, 0, "World's Best Weather App   Enter: look up   ↑/↓: recent   Ctrl+R: refresh   Ctrl+C: quit", s.width-2, styleReverse)
+
+	s.box(0, 1, s.width, 3, "Address or favorite")
+	s.text(2, 2, "> ", 2, styleBold)
+	input := string(t.input)
+	if visible := s.width - 6; len(t.input) > visible {
+		// Keep the end of a long address, where the cursor is, in view
+		input = string(t.input[len(t.input)-visible:])
+	}
+	s.cursorX = 4 + s.text(4, 2, input, s.width-6, stylePlain)
+	s.cursorY, s.cursorVisible = 2, true
+
+	bottom := s.height - 1
+	s.box(0, 4, tuiRecentWidth, bottom-4, "Recent")
+	for i, l := range t.recent {
+		if 5+i >= bottom-1 {
+			break
+		}
+		style := stylePlain
+		if i == t.selected {
+			style = styleReverse
+			s.fill(1, 5+i, tuiRecentWidth-2, ' ', style)
+		}
+		s.text(2, 5+i, truncate(l.Input, tuiRecentWidth-4), tuiRecentWidth-4, style)
+	}
+
+	x, width := tuiRecentWidth, s.width-tuiRecentWidth
+	if len(t.recent) == 0 {
+		s.box(x, 4, width, bottom-4, "Forecast")
+		s.text(x+2, 5, "Type an address or the name of a favorite and press Enter", width-4, styleDim)
+	} else {
+		l := t.recent[t.selected]
+		// The table starts today, leaving out the past days
+		days := min(tuiTableDays, len(l.WeeklyForecast.Time)-max(l.WeeklyForecast.DayIndex(t.now()), 0))
+		tableHeight := max(days, 1) + 3
+		t.drawCurrent(s, x, 4, width, l)
+		t.drawDaily(s, x, 8, width, tableHeight, l.WeeklyForecast)
+		t.drawHourly(s, x, 8+tableHeight, width, bottom-8-tableHeight, l.WeeklyForecast)
+	}
+
+	s.text(1, bottom, t.status, s.width-2, styleDim)
+}
+
+// drawCurrent draws the current conditions panel of a location.
+func (t *tui) drawCurrent(s *screen, x, y, width int, l tuiLocation) {
+	s.box(x, y, width, 4, "Current Conditions")
+	s.text(x+2, y+1, truncate(l.Address, width-4), width-4, styleBold)
+
+	wf := l.WeeklyForecast
+	line := fmt.Sprintf("Now %s %s", t.lc.Number(l.CurrentTemp, 1), t.units.Symbol())
+	if today := max(wf.DayIndex(t.now()), 0); today < len(wf.Temperature2MMax) && today < len(wf.Temperature2MMin) {
+		line += fmt.Sprintf("   High %s   Low %s", t.lc.Number(wf.Temperature2MMax[today], 1), t.lc.Number(wf.Temperature2MMin[today], 1))
+		if today < len(wf.WeatherCode) {
+			line += "   " + api.WeatherDescription(wf.WeatherCode[today])
+		}
+	}
+	if l.IsFromCache {
+		line += "   (cached)"
+	}
+	s.text(x+2, y+2, line, width-4, stylePlain)
+}
+
+// drawDaily draws the table of the next days of a forecast, from today.
+func (t *tui) drawDaily(s *screen, x, y, width, height int, wf api.WeeklyForecast) {
+	s.box(x, y, width, height, fmt.Sprintf("%d-Day Forecast", height-3))
+	columns := []int{x + 2, x + 16, x + 34, x + 41, x + 48}
+	// Each column ends where the next starts, and none past the frame
+	widths := make([]int, len(columns))
+	for i := range columns {
+		end := x + width - 1
+		if i+1 < len(columns) {
+			end = min(end, columns[i+1]-1)
+		}
+		widths[i] = end - columns[i]
+	}
+	for i, header := range []string{"Day", "Weather", "Max", "Min", "Chance"} {
+		s.text(columns[i], y+1, header, widths[i], styleBold)
+	}
+
+	labels := dayLabels(wf, t.now(), t.lc.Day)
+	first := max(wf.DayIndex(t.now()), 0)
+	for row := 0; row < height-3 && first+row < len(wf.Time); row++ {
+		day := first + row
+		cells := []string{labels[day], "-", "-", "-", "-"}
+		if day < len(wf.WeatherCode) {
+			cells[1] = api.WeatherDescription(wf.WeatherCode[day])
+		}
+		if day < len(wf.Temperature2MMax) {
+			cells[2] = t.lc.Number(wf.Temperature2MMax[day], 1)
+		}
+		if day < len(wf.Temperature2MMin) {
+			cells[3] = t.lc.Number(wf.Temperature2MMin[day], 1)
+		}
+		if day < len(wf.PrecipitationProbabilityMax) {
+			cells[4] = fmt.Sprintf("%.0f%%", wf.PrecipitationProbabilityMax[day])
+		}
+		for i, text := range cells {
+			s.text(columns[i], y+2+row, truncate(text, widths[i]), widths[i], stylePlain)
+		}
+	}
+}
+
+// drawHourly draws a bar chart of the temperature over the next hours, one column per hour, with the range on the
+// left and the hour every 6 hours below.
+func (t *tui) drawHourly(s *screen, x, y, width, height int, wf api.WeeklyForecast) {
+	if height < 4 {
+		return
+	}
+	s.box(x, y, width, height, "Hourly Temperature")
+
+	const axisWidth = 6
+	times, temperatures := wf.HoursFrom(t.now(), width-4-axisWidth)
+	if len(times) == 0 {
+		s.text(x+2, y+1, "Hourly forecast unavailable", width-4, styleDim)
+		return
+	}
+
+	rows := height - 3
+	low, high := temperatures[0], temperatures[0]
+	for _, v := range temperatures {
+		low, high = min(low, v), max(high, v)
+	}
+	s.text(x+2, y+1, t.lc.Number(high, 0), axisWidth-1, styleDim)
+	s.text(x+2, y+rows, t.lc.Number(low, 0), axisWidth-1, styleDim)
+
+	for row, line := range hourlyBars(temperatures, rows) {
+		s.text(x+2+axisWidth, y+1+row, line, width-4-axisWidth, stylePlain)
+	}
+	for i, hour := range times {
+		if hour.Hour()%6 == 0 {
+			s.text(x+2+axisWidth+i, y+1+rows, hour.Format("15h"), width-4-axisWidth-i, styleDim)
+		}
+	}
+}
+
+// hourlyBars draws values as vertical bars rows high, one column per value, scaled between their min and max in
+// eighths of a row. It returns the rows from the top.
+func hourlyBars(values []float64, rows int) []string {
+	low, high := values[0], values[0]
+	for _, v := range values {
+		low, high = min(low, v), max(high, v)
+	}
+
+	lines := make([][]rune, rows)
+	for row := range lines {
+		lines[row] = []rune(strings.Repeat(" ", len(values)))
+	}
+	steps := rows * len(sparkBlocks)
+	for i, v := range values {
+		// Every bar is at least one eighth high, so that the lowest value is still visible
+		level := steps
+		if high > low {
+			level = 1 + int((v-low)/(high-low)*float64(steps-1)+0.5)
+		}
+		for row := 0; row < rows; row++ {
+			fill := level - (rows-1-row)*len(sparkBlocks)
+			switch {
+			case fill >= len(sparkBlocks):
+				lines[row][i] = '█'
+			case fill > 0:
+				lines[row][i] = sparkBlocks[fill-1]
+			}
+		}
+	}
+
+	bars := make([]string, rows)
+	for row, line := range lines {
+		bars[row] = string(line)
+	}
+	return bars
+}
+
+// tuiLoop draws the interface on w and handles the keys read from r until the user quits or the input ends. size
+// returns the width and height of the terminal, read before every redraw so that resizes are followed.
+func tuiLoop(r *bufio.Reader, w io.Writer, t *tui, size func() (int, int)) error {
+	redraw := func() {
+		s := newScreen(size())
+		t.draw(s)
+		s.render(w)
+	}
+	t.busy = redraw
+
+	redraw()
+	for !t.quit {
+		key, err := readKey(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading input: %v", err)
+		}
+		t.handleKey(key)
+		redraw()
+	}
+	return nil
+}
+
+// runTUI runs the tui command, a full-screen interface to look up addresses and favorites and switch between them.
+// The locations given as arguments are looked up first.
+func runTUI(args []string, cfg config.Config, c *cache.Cache, favs *favorites.Store) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return errors.New("the tui command requires a terminal")
+	}
+	if cfg.Providers.GeocodeAPIKey == "" {
+		return errors.New("GEOCODE_API_KEY environment variable is not set")
+	}
+	lc, err := locale.Lookup(cfg.Locale)
+	if err != nil {
+		return err
+	}
+	opts := forecastOptions(cfg)
+
+	t := &tui{
+		lookup: func(input string) (tuiLocation, error) {
+			ctx := logging.WithRequestID(context.Background(), logging.NewRequestID())
+			addressFull, lat, lng, err := lookupCoordinates(ctx, input, favs, cfg)
+			if err != nil {
+				return tuiLocation{}, err
+			}
+			currentTemp, weeklyForecast, isFromCache, err := getForecastForCoordinates(ctx, addressFull, lat, lng, c, cfg.Providers.ForecastURL, opts)
+			if err != nil {
+				return tuiLocation{}, err
+			}
+			return tuiLocation{Input: input, Address: addressFull, CurrentTemp: currentTemp, WeeklyForecast: weeklyForecast, IsFromCache: isFromCache}, nil
+		},
+		units: opts.Units,
+		lc:    lc,
+		now:   time.Now,
+	}
+
+	// Ctrl+C is read as a key rather than a signal, so that the terminal is always restored
+	restore, err := makeRaw(os.Stdin, false)
+	if err != nil {
+		return fmt.Errorf("error switching the terminal to raw mode: %v", err)
+	}
+	fmt.Fprint(os.Stdout, ansiAltScreen)
+	defer func() {
+		fmt.Fprint(os.Stdout, ansiMainScreen+ansiShowCursor)
+		restore()
+	}()
+
+	// Start with the locations given as arguments, in order, the first one selected
+	for i := len(args) - 1; i >= 0; i-- {
+		t.fetch(args[i])
+	}
+	return tuiLoop(bufio.NewReader(os.Stdin), os.Stdout, t, func() (int, int) {
+		return terminalWidth(os.Stdout), terminalHeight(os.Stdout)
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/locale"
+)
+
+func TestMain_screen(t *testing.T) {
+	s := newScreen(12, 4)
+	s.box(0, 0, 12, 4, "Now")
+	s.text(2, 1, "78.6 F and sunny", 8, styleBold)
+	s.text(2, 2, "clipped at the edge", 20, stylePlain)
+
+	expected := "┌─ Now ────┐\n│ 78.6 F a │\n│ clipped at\n└──────────┘"
+	if s.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, s.String())
+	}
+
+	var b strings.Builder
+	s.render(&b)
+	if !strings.HasPrefix(b.String(), "\033[H") || !strings.Contains(b.String(), styleCodes[styleBold]+"78.6 F a"+ansiReset) {
+		t.Errorf("Expected the screen drawn from the top left with styles, got %q", b.String())
+	}
+}
+
+func TestMain_hourlyBars(t *testing.T) {
+	bars := hourlyBars([]float64{70, 75, 80}, 2)
+	if strings.Join(bars, "|") != " ▁█|▁██" {
+		t.Errorf("Expected bars rising from the lowest to the highest value, got %q", bars)
+	}
+	if bars := hourlyBars([]float64{72, 72}, 1); bars[0] != "██" {
+		t.Errorf("Expected full bars for flat values, got %q", bars)
+	}
+}
+
+// testTUI returns a full-screen interface whose lookups return a forecast for the locations in forecasts.
+func testTUI(forecasts map[string]tuiLocation, lookups *[]string) *tui {
+	lc, _ := locale.Lookup(locale.Default)
+	return &tui{
+		lookup: func(input string) (tuiLocation, error) {
+			*lookups = append(*lookups, input)
+			l, ok := forecasts[input]
+			if !ok {
+				return tuiLocation{}, errors.New("error retrieving coordinates: no results")
+			}
+			return l, nil
+		},
+		units: api.Fahrenheit,
+		lc:    lc,
+		now:   func() time.Time { return time.Date(2024, time.September, 19, 19, 30, 0, 0, time.UTC) },
+	}
+}
+
+func TestMain_tuiLoop(t *testing.T) {
+	hourly := &api.HourlyForecast{}
+	for hour := 0; hour < 48; hour++ {
+		hourly.Time = append(hourly.Time, fmt.Sprintf("2024-09-%02dT%02d:00", 19+hour/24, hour%24))
+		hourly.Temperature2M = append(hourly.Temperature2M, 75+float64(hour%24))
+	}
+	austin := tuiLocation{
+		Input:       "hq",
+		Address:     "3001 Esperanza Crossing, Austin, TX 78758, USA",
+		CurrentTemp: 78.6,
+		WeeklyForecast: api.WeeklyForecast{
+			Time:                        []string{"2024-09-18", "2024-09-19", "2024-09-20"},
+			Temperature2MMax:            []float64{95.0, 97.6, 96.1},
+			Temperature2MMin:            []float64{74.0, 75.8, 74.2},
+			PrecipitationProbabilityMax: []float64{0, 10, 70},
+			WeatherCode:                 []int{0, 1, 63},
+			Hourly:                      hourly,
+		},
+	}
+	newYork := tuiLocation{
+		Input:          "350 5th Ave, New York",
+		Address:        "350 5th Ave, New York, NY 10118, USA",
+		CurrentTemp:    68.2,
+		WeeklyForecast: api.WeeklyForecast{Time: []string{"2024-09-19"}, Temperature2MMax: []float64{72.3}, Temperature2MMin: []float64{61.0}},
+		IsFromCache:    true,
+	}
+	var lookups []string
+	tu := testTUI(map[string]tuiLocation{"hq": austin, "350 5th Ave, New York": newYork}, &lookups)
+
+	// Look up hq, mistype an address, look up New York, then go back to hq with the down arrow
+	keys := "hq\rnowhere\r\x15350 5th Ave, New Yorkx\x7f\r\x1b[B"
+	size := func() (int, int) { return 80, 24 }
+	if err := tuiLoop(bufio.NewReader(strings.NewReader(keys)), io.Discard, tu, size); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Join(lookups, "|") != "hq|nowhere|350 5th Ave, New York" {
+		t.Errorf("Expected 3 lookups, got %v", lookups)
+	}
+
+	s := newScreen(size())
+	tu.draw(s)
+	screen := s.String()
+	for _, expected := range []string{
+		"│ 350 5th Ave, New Yo… ││",
+		"3001 Esperanza Crossing, Austin, TX 78758, USA",
+		"Now 78.6 F   High 97.6   Low 75.8   Mainly clear",
+		"2-Day Forecast",
+		"Today         Mainly clear      97.6   75.8   10%",
+		"Tomorrow      Rain              96.1   74.2   70%",
+		"Hourly Temperature",
+		"Updated 350 5th Ave, New York, NY 10118, USA from the cache",
+	} {
+		if !strings.Contains(screen, expected) {
+			t.Errorf("Expected the screen to contain %q, got:\n%s", expected, screen)
+		}
+	}
+	if strings.Contains(screen, "2024-09-18") || strings.Contains(screen, "95.0") {
+		t.Errorf("Expected past days to be left out of the table, got:\n%s", screen)
+	}
+
+	// Ctrl+R refreshes the selected location and Ctrl+C quits
+	lookups = nil
+	if err := tuiLoop(bufio.NewReader(strings.NewReader("\x12\x03ignored")), io.Discard, tu, size); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !tu.quit || strings.Join(lookups, "|") != "hq" {
+		t.Errorf("Expected hq to be refreshed before quitting, got %v, quit %t", lookups, tu.quit)
+	}
+}
+
+func TestMain_tui_draw_TooSmall(t *testing.T) {
+	var lookups []string
+	s := newScreen(40, 10)
+	testTUI(nil, &lookups).draw(s)
+	if !strings.HasPrefix(s.String(), "Terminal too small, 60x24 needed") {
+		t.Errorf("Expected a resize notice, got:\n%s", s.String())
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+	"github.com/mfryhover/weather/logging"
+)
+
+const (
+	// ansiClearScreen moves the cursor to the top left corner and clears the screen, so that a frame is redrawn in
+	// place.
+	ansiClearScreen = "\033[H\033[2J"
+	// ansiHideCursor and ansiShowCursor hide the cursor while watching and restore it on exit.
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+)
+
+// watchFrame holds the forecast displayed by one refresh of the watch command.
+type watchFrame struct {
+	// Address is the full formatted address.
+	Address string
+	// CurrentTemp is the current temperature.
+	CurrentTemp float64
+	// WeeklyForecast is the daily forecast.
+	WeeklyForecast api.WeeklyForecast
+	// IsFromCache is set if the forecast was served from the cache.
+	IsFromCache bool
+	// UpdatedAt is when the forecast was refreshed.
+	UpdatedAt time.Time
+	// Err is set if the last refresh failed, in which case the forecast is the one of the previous refresh.
+	Err error
+}
+
+// watchValues returns the displayed values of the frame, keyed by the day and column they are displayed in, such as
+// "2024-09-19|max". Keys rather than positions are compared across refreshes, so that a new day scrolling in is not
+// highlighted as a change of every row.
+func watchValues(frame watchFrame, units api.Units, lc locale.Locale) (map[string]string, [][]string, [][]string) {
+	values := map[string]string{"current": fmt.Sprintf("%s %s", lc.Number(frame.CurrentTemp, 1), units.Symbol())}
+	wf := frame.WeeklyForecast
+	labels := dayLabels(wf, frame.UpdatedAt, lc.Day)
+
+	var rows, keys [][]string
+	for dayIndex := max(wf.DayIndex(frame.UpdatedAt), 0); dayIndex < len(wf.Time); dayIndex++ {
+		weather, maxTemp, minTemp, precipitation, chance := "-", "-", "-", "-", "-"
+		if dayIndex < len(wf.WeatherCode) {
+			weather = api.WeatherDescription(wf.WeatherCode[dayIndex])
+		}
+		if dayIndex < len(wf.Temperature2MMax) {
+			maxTemp = lc.Number(wf.Temperature2MMax[dayIndex], 1)
+		}
+		if dayIndex < len(wf.Temperature2MMin) {
+			minTemp = lc.Number(wf.Temperature2MMin[dayIndex], 1)
+		}
+		if dayIndex < len(wf.PrecipitationSum) {
+			precipitation = lc.Number(wf.PrecipitationSum[dayIndex], precipitationDecimals(units))
+		}
+		if dayIndex < len(wf.PrecipitationProbabilityMax) {
+			chance = fmt.Sprintf("%.0f%%", wf.PrecipitationProbabilityMax[dayIndex])
+		}
+
+		row := []string{labels[dayIndex], weather, maxTemp, minTemp, precipitation, chance}
+		rowKeys := make([]string, len(row))
+		for i, column := range []string{"day", "weather", "max", "min", "precipitation", "chance"} {
+			rowKeys[i] = wf.Time[dayIndex] + "|" + column
+			values[rowKeys[i]] = row[i]
+		}
+		rows, keys = append(rows, row), append(keys, rowKeys)
+	}
+	return values, rows, keys
+}
+
+// highlight marks a value that changed since the previous refresh: in color on a terminal, or with a trailing * in
+// plain output.
+func highlight(text string, changed bool, color bool) string {
+	switch {
+	case !changed:
+		return text
+	case color:
+		return colorize(text, "yellow", true)
+	default:
+		return text + "*"
+	}
+}
+
+// renderWatch writes a frame of the watch command, highlighting the values that differ from previous, and returns
+// the values of the frame to compare the next refresh to. On a terminal, the frame replaces the previous one.
+func renderWatch(w io.Writer, frame watchFrame, previous map[string]string, units api.Units, lc locale.Locale, interval time.Duration, interactive bool) map[string]string {
+	values, rows, keys := watchValues(frame, units, lc)
+	// Day labels move from "Tomorrow" to "Today" on their own, and new days have nothing to compare to
+	changed := func(key string) bool {
+		old, ok := previous[key]
+		return ok && old != values[key] && !strings.HasSuffix(key, "|day")
+	}
+
+	if interactive {
+		fmt.Fprint(w, ansiClearScreen)
+	}
+	if frame.IsFromCache {
+		fmt.Fprintln(w, "***Retrieved forecast from cache***")
+	}
+	fmt.Fprintf(w, "Here is the weather for address: %s\n", frame.Address)
+	fmt.Fprintf(w, "Updated %s, refreshing every %s. Press Ctrl+C to exit\n", lc.Time(frame.UpdatedAt), interval)
+	if frame.Err != nil {
+		fmt.Fprintf(w, "Refresh failed, showing the previous forecast: %v\n", frame.Err)
+	}
+	fmt.Fprintln(w, "---------------------------")
+	fmt.Fprintf(w, "The current temperature is %s\n\n", highlight(values["current"], changed("current"), interactive))
+
+	// Pad the plain text of each column before highlighting it, since escape sequences have no width on screen
+	header := []string{"Day", "Weather", "Max (" + units.Symbol() + ")", "Min (" + units.Symbol() + ")",
+		"Precipitation (" + units.PrecipitationSymbol() + ")", "Chance"}
+	cells := make([][]string, len(rows))
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for r, row := range rows {
+		cells[r] = make([]string, len(row))
+		for i, value := range row {
+			if changed(keys[r][i]) && !interactive {
+				value += "*"
+			}
+			cells[r][i] = value
+			widths[i] = max(widths[i], utf8.RuneCountInString(value))
+		}
+	}
+
+	pad := func(text string, width int) string {
+		return text + strings.Repeat(" ", width-utf8.RuneCountInString(text))
+	}
+	line := make([]string, len(header))
+	for i, h := range header {
+		line[i] = pad(h, widths[i])
+	}
+	fmt.Fprintln(w, strings.TrimRight(strings.Join(line, "   "), " "))
+	for r := range cells {
+		for i, text := range cells[r] {
+			line[i] = pad(text, widths[i])
+			if interactive {
+				line[i] = highlight(line[i], changed(keys[r][i]), true)
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(line, "   "), " "))
+	}
+	return values
+}
+
+// runWatch runs the watch command, which displays the forecast of an address or a favorite and refreshes it every
+// interval until ctx is done. Refreshes go through the cache, so the forecast API is only called once the cached
+// forecast expires. If interactive is set, each refresh redraws the screen in place.
+func runWatch(ctx context.Context, args []string, cfg config.Config, c *cache.Cache, favs *favorites.Store, stdout io.Writer, interactive bool) error {
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := flags.Duration("interval", 10*time.Minute, "how often to refresh the forecast")
+	positional, err := parseInterleaved(flags, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: weather watch <location> [--interval 10m]")
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("invalid interval %s: must be positive", *interval)
+	}
+	lc, err := locale.Lookup(cfg.Locale)
+	if err != nil {
+		return err
+	}
+	opts := forecastOptions(cfg)
+
+	addressFull, lat, lng, err := lookupCoordinates(logging.WithRequestID(ctx, logging.NewRequestID()), positional[0], favs, cfg)
+	if err != nil {
+		return err
+	}
+
+	if interactive {
+		fmt.Fprint(stdout, ansiHideCursor)
+		defer fmt.Fprint(stdout, ansiShowCursor)
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	var frame watchFrame
+	var previous map[string]string
+	for {
+		requestCtx := logging.WithRequestID(ctx, logging.NewRequestID())
+		currentTemp, weeklyForecast, isFromCache, err := getForecastForCoordinates(requestCtx, addressFull, lat, lng, c, cfg.Providers.ForecastURL, opts)
+		switch {
+		case err == nil:
+			frame = watchFrame{Address: addressFull, CurrentTemp: currentTemp, WeeklyForecast: weeklyForecast, IsFromCache: isFromCache, UpdatedAt: time.Now()}
+		case previous == nil:
+			// Nothing to fall back to yet
+			return err
+		default:
+			frame.Err = err
+		}
+
+		if !interactive && previous != nil {
+			fmt.Fprintln(stdout)
+		}
+		previous = renderWatch(stdout, frame, previous, opts.Units, lc, *interval, interactive)
+
+		select {
+		case <-ctx.Done():
+			// Interrupted, which is a clean exit
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfryhover/weather/api"
+	"github.com/mfryhover/weather/cache"
+	"github.com/mfryhover/weather/config"
+	"github.com/mfryhover/weather/favorites"
+	"github.com/mfryhover/weather/locale"
+)
+
+func TestMain_renderWatch(t *testing.T) {
+	lc, _ := locale.Lookup("en-US")
+	updatedAt := time.Date(2024, time.September, 19, 15, 0, 0, 0, time.UTC)
+	first := watchFrame{
+		Address:     "3001 Esperanza Crossing, Austin, TX 78758, USA",
+		CurrentTemp: 78.6,
+		WeeklyForecast: api.WeeklyForecast{
+			Time:             []string{"2024-09-19", "2024-09-20"},
+			Temperature2MMax: []float64{97.6, 96.1},
+			Temperature2MMin: []float64{75.8, 74.2},
+			WeatherCode:      []int{0, 3},
+		},
+		UpdatedAt: updatedAt,
+	}
+	second := first
+	second.CurrentTemp = 80.1
+	second.WeeklyForecast.Temperature2MMax = []float64{97.6, 99.0}
+	second.UpdatedAt = updatedAt.Add(10 * time.Minute)
+
+	var b strings.Builder
+	previous := renderWatch(&b, first, nil, api.Fahrenheit, lc, 10*time.Minute, false)
+	if strings.Contains(b.String(), "*") {
+		t.Errorf("Expected no highlights on the first frame, got:\n%s", b.String())
+	}
+	if !strings.Contains(b.String(), "Tomorrow   Overcast    96.1") {
+		t.Errorf("Expected a row for tomorrow, got:\n%s", b.String())
+	}
+
+	b.Reset()
+	renderWatch(&b, second, previous, api.Fahrenheit, lc, 10*time.Minute, false)
+	for _, expected := range []string{"The current temperature is 80.1 F*", "99.0*", "Updated 3:10 PM, refreshing every 10m0s"} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("Expected the frame to contain %q, got:\n%s", expected, b.String())
+		}
+	}
+	if strings.Count(b.String(), "*") != 2 {
+		t.Errorf("Expected only the 2 changed values to be highlighted, got:\n%s", b.String())
+	}
+
+	// On a terminal, the frame is redrawn in place and changes are colored
+	b.Reset()
+	second.Err = errors.New("received non-OK HTTP status: 503 Service Unavailable")
+	renderWatch(&b, second, previous, api.Fahrenheit, lc, 10*time.Minute, true)
+	if !strings.HasPrefix(b.String(), ansiClearScreen) || !strings.Contains(b.String(), ansiColors["yellow"]+"80.1 F"+ansiReset) {
+		t.Errorf("Expected a cleared screen and colored changes, got %q", b.String())
+	}
+	if !strings.Contains(b.String(), "Refresh failed, showing the previous forecast: received non-OK HTTP status") {
+		t.Errorf("Expected the refresh error, got:\n%s", b.String())
+	}
+}
+
+func TestMain_runWatch(t *testing.T) {
+	forecastRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forecastRequests++
+		fmt.Fprintf(w, `{"current": {"temperature_2m": 78.6},
+			"daily": {"time": ["%s"], "temperature_2m_max": [97.6], "temperature_2m_min": [75.8]}}`, time.Now().UTC().Format("2006-01-02"))
+	}))
+	defer server.Close()
+
+	cfg := config.Defaults()
+	cfg.Providers.ForecastURL = server.URL
+	favs, _ := favorites.Load(filepath.Join(t.TempDir(), "favorites.json"))
+	favs.Save(favorites.Place{Name: "lobby", Address: "1 Lobby Way, Austin, TX 78702, USA", Latitude: 30.2622, Longitude: -97.7205})
+
+	// Refreshes more frequent than the cache TTL are served from the cache
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var b strings.Builder
+	if err := runWatch(ctx, []string{"lobby", "--interval", "20ms"}, cfg, cache.GetCacheInstance(), favs, &b, false); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if frames := strings.Count(b.String(), "Here is the weather"); frames < 2 {
+		t.Errorf("Expected several frames, got %d:\n%s", frames, b.String())
+	}
+	if forecastRequests != 1 || !strings.Contains(b.String(), "Retrieved forecast from cache") {
+		t.Errorf("Expected refreshes to be served from the cache, got %d requests", forecastRequests)
+	}
+
+	tc := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{name: "Missing Location", args: []string{"--interval", "1m"}, err: "usage: weather watch"},
+		{name: "Invalid Interval", args: []string{"lobby", "--interval", "-1m"}, err: "invalid interval"},
+	}
+	for _, tc := range tc {
+		t.Run(tc.name, func(t *testing.T) {
+			err := runWatch(context.Background(), tc.args, cfg, cache.GetCacheInstance(), favs, &b, false)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Expected an error containing '%s', got %v", tc.err, err)
+			}
+		})
+	}
+}